@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ratingEveryone = "everyone"
+	ratingTeen     = "teen"
+	ratingMature   = "mature"
+
+	teenAge  = 13
+	adultAge = 18
+)
+
+// contentWarnings are the warnings an author can put on a book
+var contentWarnings = []string{
+	"violence",
+	"gore",
+	"sexual content",
+	"self harm",
+	"substance abuse",
+	"strong language",
+	"abuse",
+}
+
+var (
+	errInvalidContentWarning = errors.New("unknown content warning")
+	errContentRestricted     = errors.New("book is rated for older readers or mature content is turned off")
+	errBirthDateSet          = errors.New("birth date can't be changed once set")
+	errInvalidBirthDate      = errors.New("birth date should be a past date formatted as YYYY-MM-DD")
+	errMatureContentAge      = errors.New("mature content is only available to readers 18 and over")
+)
+
+// contentFilter narrows the books listed down to the ones a reader is allowed
+// and wants to see
+type contentFilter struct {
+	ratings        []string
+	hiddenWarnings []string
+}
+
+// defaultContentFilter is used for readers who aren't signed in, mature books
+// are left out
+var defaultContentFilter = contentFilter{ratings: []string{ratingEveryone, ratingTeen}, hiddenWarnings: []string{}}
+
+// normalizeContentWarnings lowercases the warnings an author gave a book and
+// makes sure they are all known, dropping empty ones and duplicates
+func normalizeContentWarnings(warnings []string) ([]string, error) {
+	normalized := []string{}
+
+	for _, warning := range warnings {
+		warning = strings.Join(strings.Fields(strings.ToLower(warning)), " ")
+		if warning == "" || slices.Contains(normalized, warning) {
+			continue
+		}
+
+		if !slices.Contains(contentWarnings, warning) {
+			return nil, errInvalidContentWarning
+		}
+
+		normalized = append(normalized, warning)
+	}
+
+	return normalized, nil
+}
+
+// age returns how old someone born on birthDate is at now
+func age(birthDate, now time.Time) int {
+	years := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		years--
+	}
+	return years
+}
+
+// allowedRatings returns the ratings the reader can read at now. Readers
+// without a birth date are treated like signed out ones, mature books need
+// an adult who turned them on.
+func (p *contentPreferences) allowedRatings(now time.Time) []string {
+	if !p.birthDate.Valid {
+		return defaultContentFilter.ratings
+	}
+
+	switch years := age(p.birthDate.Time, now); {
+	case years < teenAge:
+		return []string{ratingEveryone}
+	case years < adultAge || !p.showMature:
+		return []string{ratingEveryone, ratingTeen}
+	default:
+		return []string{ratingEveryone, ratingTeen, ratingMature}
+	}
+}
+
+// filter returns the content filter to list books for the reader with
+func (p *contentPreferences) filter(now time.Time) contentFilter {
+	return contentFilter{ratings: p.allowedRatings(now), hiddenWarnings: append([]string{}, p.hiddenWarnings...)}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAllowedRatings(t *testing.T) {
+	now := time.Date(2026, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	born := func(year int, month time.Month, day int) sql.NullTime {
+		return sql.NullTime{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+
+	tests := []struct {
+		name        string
+		preferences contentPreferences
+		expected    []string
+	}{
+		{
+			name:        "no birth date",
+			preferences: contentPreferences{showMature: true},
+			expected:    []string{ratingEveryone, ratingTeen},
+		},
+		{
+			name:        "child",
+			preferences: contentPreferences{birthDate: born(2016, time.January, 1)},
+			expected:    []string{ratingEveryone},
+		},
+		{
+			name:        "turns 13 tomorrow",
+			preferences: contentPreferences{birthDate: born(2013, time.June, 16)},
+			expected:    []string{ratingEveryone},
+		},
+		{
+			name:        "teen",
+			preferences: contentPreferences{birthDate: born(2013, time.June, 15), showMature: true},
+			expected:    []string{ratingEveryone, ratingTeen},
+		},
+		{
+			name:        "adult who didn't turn mature content on",
+			preferences: contentPreferences{birthDate: born(1990, time.March, 2)},
+			expected:    []string{ratingEveryone, ratingTeen},
+		},
+		{
+			name:        "adult who turned mature content on",
+			preferences: contentPreferences{birthDate: born(2008, time.June, 15), showMature: true},
+			expected:    []string{ratingEveryone, ratingTeen, ratingMature},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if ratings := tc.preferences.allowedRatings(now); !slices.Equal(ratings, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, ratings)
+			}
+		})
+	}
+}
+
+func TestNormalizeContentWarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		warnings    []string
+		expected    []string
+		expectedErr error
+	}{
+		{
+			name:     "case, spaces and duplicates",
+			warnings: []string{" Violence", "self  harm", "violence", ""},
+			expected: []string{"violence", "self harm"},
+		},
+		{
+			name:        "unknown warning",
+			warnings:    []string{"gore", "spiders"},
+			expectedErr: errInvalidContentWarning,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := normalizeContentWarnings(tc.warnings)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if tc.expectedErr == nil && !slices.Equal(warnings, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, warnings)
+			}
+		})
+	}
+}
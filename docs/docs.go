@@ -15,44 +15,123 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/google": {
+        "/admin/books/pending": {
             "get": {
-                "description": "Sign in with google",
+                "description": "Get the books waiting for approval, oldest first unless sort is newest",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Get pending books",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "genres",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "BCP 47 language codes",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "mine or unclaimed",
+                        "name": "claim",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "oldest or newest",
+                        "name": "sort",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Sign in with google",
                 "responses": {
-                    "302": {
-                        "description": "Found"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetPendingBooks.response"
+                        }
                     },
-                    "307": {
-                        "description": "Temporary Redirect"
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
                     }
                 }
             }
         },
-        "/auth/login": {
+        "/admin/books/{bookID}/approve": {
             "post": {
-                "description": "Login using either email, or both and password",
+                "description": "Approve a pending book. Books claimed by another admin can't be approved, and books overlapping earlier books by other authors need plagiarismReviewed.",
                 "consumes": [
-                    "appplication/json"
-                ],
-                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login",
+                "summary": "Approve book",
                 "parameters": [
                     {
-                        "description": "user",
-                        "name": "user",
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "approve book body",
+                        "name": "param",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.handleAuthLogin.request"
+                            "$ref": "#/definitions/main.approveBookRequest"
                         }
                     }
                 ],
@@ -72,12 +151,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -87,59 +178,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/logout": {
-            "get": {
-                "description": "Logout user",
-                "tags": [
-                    "auth"
-                ],
-                "summary": "Logout user",
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/auth/onboarding": {
+        "/admin/books/{bookID}/claim": {
             "post": {
-                "description": "Onboard users",
+                "description": "Claim a pending book so other admins don't review it at the same time, or assign it to another moderator. Claims expire after MODERATION_CLAIM_TTL.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Onboard users",
+                "summary": "Claim book for review",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "display name",
-                        "name": "display_name",
-                        "in": "formData",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "about",
-                        "name": "about",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "file",
-                        "description": "profile_picture",
-                        "name": "image",
-                        "in": "formData"
+                        "description": "claim book body, leave out to claim for yourself",
+                        "name": "param",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleClaimBook.request"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.handleAuthOnboarding.response"
+                            "$ref": "#/definitions/main.handleClaimBook.response"
                         }
                     },
                     "400": {
@@ -148,14 +221,26 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "413": {
-                        "description": "Request Entity Too Large",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -167,21 +252,25 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/refresh-token": {
-            "post": {
-                "description": "Get new access token",
+            },
+            "delete": {
+                "description": "Give up the claim on a pending book so other admins can review it",
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Release book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Refresh token",
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleAuthRefreshToken.response"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -189,12 +278,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -204,42 +305,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Register user using email and password",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/books/{bookID}/moderation": {
+            "get": {
+                "description": "Get every claim, release and decision made on a book and its chapters, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register user",
+                "summary": "Get moderation history",
                 "parameters": [
                     {
-                        "description": "user",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.handleAuthRegister.request"
-                        }
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Found"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetModerationHistory.response"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -253,65 +358,46 @@ const docTemplate = `{
                 }
             }
         },
-        "/books": {
+        "/admin/books/{bookID}/plagiarism": {
             "get": {
-                "description": "Get all books",
+                "description": "Get the earlier books by other authors a book overlaps with, by share of the book's fingerprints found in them, with the chapters that overlap",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get all books",
+                "summary": "Get plagiarism report",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "genre",
-                        "name": "genre",
-                        "in": "query"
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetPlagiarismReport.response"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "language",
-                        "name": "language",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "sort",
-                        "name": "sort",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "order",
-                        "name": "order",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "offset",
-                        "name": "offset",
-                        "in": "query",
-                        "required": true
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/main.handleGetBooks.response"
+                            "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -323,101 +409,39 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/books/{bookID}/reject": {
             "post": {
-                "description": "Upload book",
+                "description": "Reject a pending book. Books claimed by another admin can't be rejected.",
                 "consumes": [
-                    "multipart/form-data"
-                ],
-                "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Upload book",
+                "summary": "Reject book",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "name",
-                        "name": "name",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "description",
-                        "name": "description",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "genre",
-                        "name": "genres",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "language",
-                        "name": "language",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "draft chapter title",
-                        "name": "chapter_title",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "draft chapter content",
-                        "name": "chapter_content",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "release days (e.g. Monday, Tuesday)",
-                        "name": "release_schedule_day",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "integer"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "chapters per day (e.g. 1, 2)",
-                        "name": "release_schedule_chapter",
-                        "in": "formData",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "book cover image (max 3MB)",
-                        "name": "book_cover",
-                        "in": "formData"
+                        "description": "reject book body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.moderationRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleUploadBook.response"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -425,61 +449,26 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/main.errorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "413": {
-                        "description": "Request Entity Too Large",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/books/chapters/{chapterID}": {
-            "get": {
-                "description": "Get chapter",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "chapters"
-                ],
-                "summary": "Get chapter",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "chapter id",
-                        "name": "chapterID",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleGetChapter.response"
-                        }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -493,16 +482,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/recently-read": {
+        "/admin/chapters/flagged": {
             "get": {
-                "description": "Get recently read books",
+                "description": "Get the chapters automated moderation held back from publication, oldest first, with the verdict of every check",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get recently read books",
+                "summary": "Get flagged chapters",
                 "parameters": [
                     {
                         "type": "string",
@@ -523,7 +512,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.handleGetRecentlyReadBooks.response"
+                            "$ref": "#/definitions/main.handleGetFlaggedChapters.response"
                         }
                     },
                     "400": {
@@ -532,6 +521,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -541,35 +542,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/recently-uploaded": {
-            "get": {
-                "description": "Get recently uploaded books",
+        "/admin/chapters/{chapterID}/approve": {
+            "post": {
+                "description": "Publish a chapter automated moderation held back",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get recently uploaded books",
+                "summary": "Approve chapter",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "offset",
-                        "name": "offset",
-                        "in": "query",
+                        "description": "chapter id",
+                        "name": "chapterID",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
+                        "description": "approve chapter body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.moderationRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleGetRecentlyUploadedBooks.response"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -577,6 +580,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -586,38 +613,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/stats": {
-            "get": {
-                "description": "Get books stats",
-                "produces": [
+        "/admin/chapters/{chapterID}/reject": {
+            "post": {
+                "description": "Keep a chapter automated moderation held back from being published",
+                "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get books stats",
+                "summary": "Reject chapter",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "offset",
-                        "name": "offset",
-                        "in": "query",
+                        "description": "chapter id",
+                        "name": "chapterID",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "query",
-                        "required": true
+                        "description": "reject chapter body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.moderationRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleGetBooksStats.response"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -625,6 +651,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -634,31 +684,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{bookID}": {
-            "get": {
-                "description": "Get a single book",
+        "/admin/genres": {
+            "post": {
+                "description": "Add a genre authors can pick for their books",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Get a single book",
+                "summary": "Create genre",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
-                        "in": "path",
-                        "required": true
+                        "description": "create genre body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateGenre.request"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.handleGetBook.response"
+                            "$ref": "#/definitions/main.handleCreateGenre.response"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -670,25 +740,32 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete book",
+            }
+        },
+        "/admin/languages": {
+            "post": {
+                "description": "Add a language authors can write books in",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Delete book",
+                "summary": "Create language",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
-                        "in": "path",
-                        "required": true
+                        "description": "create language body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateLanguage.request"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "201": {
+                        "description": "Created"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -696,8 +773,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -709,79 +792,52 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Edit book",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+            }
+        },
+        "/admin/reports": {
+            "get": {
+                "description": "Get reports for triage, the most reported content first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Edit book",
+                "summary": "Get reports",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
-                        "in": "path",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "name",
-                        "name": "name",
-                        "in": "formData"
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "description",
-                        "name": "description",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Release days (e.g. Monday, Tuesday)",
-                        "name": "release_schedule_day",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "integer"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Chapters per day (e.g. 1, 2)",
-                        "name": "release_schedule_chapter",
-                        "in": "formData"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "genres",
-                        "name": "genres",
-                        "in": "formData"
+                        "description": "open (default), dismissed or upheld",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
-                        "type": "file",
-                        "description": "book cover",
-                        "name": "book_cover",
-                        "in": "formData"
+                        "type": "string",
+                        "description": "book, chapter or user",
+                        "name": "targetType",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetReports.response"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -789,14 +845,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "413": {
-                        "description": "Request Entity Too Large",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -810,34 +866,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{bookID}/approve": {
+        "/admin/reports/{reportID}": {
             "patch": {
-                "description": "Approve book",
+                "description": "Uphold or dismiss a report along with every other open report on the same content. Upheld content stays hidden, dismissed content is shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Approve book",
+                "summary": "Resolve report",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
+                        "description": "report id",
+                        "name": "reportID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "approve book body",
+                        "description": "resolve report body",
                         "name": "param",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.handleApproveBook.request"
+                            "$ref": "#/definitions/main.handleResolveReport.request"
                         }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleResolveReport.response"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -851,12 +916,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -866,9 +943,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{bookID}/chapters": {
+        "/admin/tags": {
             "post": {
-                "description": "Upload chapter",
+                "description": "Create a canonical tag, or make an existing tag canonical. Canonical tags are suggested first.",
                 "consumes": [
                     "application/json"
                 ],
@@ -876,32 +953,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "chapters"
+                    "admin"
                 ],
-                "summary": "Upload chapter",
+                "summary": "Create canonical tag",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "upload chapter body",
+                        "description": "create tag body",
                         "name": "param",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.handleUploadChapter.request"
+                            "$ref": "#/definitions/main.handleCreateTag.request"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.handleUploadChapter.response"
+                            "$ref": "#/definitions/main.handleCreateTag.response"
                         }
                     },
                     "400": {
@@ -910,8 +980,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -925,42 +1001,62 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{bookID}/chapters/{chapterID}": {
-            "delete": {
-                "description": "Delete chapter",
-                "produces": [
+        "/admin/tags/{tagID}/synonyms": {
+            "post": {
+                "description": "Make a tag a synonym of another one. Books tagged with the synonym are moved to the tag and new books get the tag instead.",
+                "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "chapters"
+                    "admin"
                 ],
-                "summary": "Delete chapter",
+                "summary": "Add tag synonym",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
+                        "description": "tag id",
+                        "name": "tagID",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "chapter id",
-                        "name": "chapterID",
-                        "in": "path",
-                        "required": true
+                        "description": "add synonym body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAddTagSynonym.request"
+                        }
                     }
                 ],
                 "responses": {
                     "204": {
                         "description": "No Content"
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -968,46 +1064,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Edit chapter",
-                "produces": [
-                    "application/json"
-                ],
+            }
+        },
+        "/admin/tags/{tagID}/synonyms/{synonym}": {
+            "delete": {
+                "description": "Make a synonym a tag of its own again. Books already moved to the tag keep it.",
                 "tags": [
-                    "chapters"
+                    "admin"
                 ],
-                "summary": "Edit chapter",
+                "summary": "Remove tag synonym",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
+                        "description": "tag id",
+                        "name": "tagID",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "chapter id",
-                        "name": "chapterID",
+                        "description": "synonym",
+                        "name": "synonym",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "edit chapter body",
-                        "name": "param",
-                        "in": "body",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleEditChapter.request"
-                        }
                     }
                 ],
                 "responses": {
                     "204": {
                         "description": "No Content"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -1027,37 +1114,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{bookID}/complete": {
-            "patch": {
-                "description": "Complete book",
+        "/admin/users/{userID}/roles": {
+            "get": {
+                "description": "Get a user's roles, what they allow and every time one was granted or revoked, newest first",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "books"
+                    "admin"
                 ],
-                "summary": "Complete book",
+                "summary": "Get user roles",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "book id",
-                        "name": "bookID",
+                        "description": "user id",
+                        "name": "userID",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "complete book body",
-                        "name": "param",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.handleCompleteBook.request"
-                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetRoles.response"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
@@ -1075,41 +1165,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users/me": {
-            "get": {
-                "description": "Get current user profile",
-                "produces": [
+            },
+            "post": {
+                "description": "Give a user the ADMIN, MODERATOR or AUTHOR role",
+                "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Get current user profile",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleGetProfile.response"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/main.errorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/users/{userID}/follow": {
-            "post": {
-                "description": "Follow user",
-                "tags": [
-                    "followers"
+                    "admin"
                 ],
-                "summary": "Follow user",
+                "summary": "Grant role",
                 "parameters": [
                     {
                         "type": "string",
@@ -1117,6 +1182,15 @@ const docTemplate = `{
                         "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "grant role body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGrantRole.request"
+                        }
                     }
                 ],
                 "responses": {
@@ -1129,43 +1203,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.errorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/users/{userID}/followers": {
-            "get": {
-                "description": "Get user followers",
-                "tags": [
-                    "followers"
-                ],
-                "summary": "Get user followers",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "user id",
-                        "name": "userID",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/main.handleGetUserFollowers.response"
-                        }
                     },
                     "404": {
                         "description": "Not Found",
@@ -1173,6 +1221,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1182,13 +1236,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userID}/following": {
-            "get": {
-                "description": "Get user following",
+        "/admin/users/{userID}/roles/{role}": {
+            "delete": {
+                "description": "Take the ADMIN, MODERATOR or AUTHOR role away from a user. Admins can't revoke their own admin role.",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "followers"
+                    "admin"
                 ],
-                "summary": "Get user following",
+                "summary": "Revoke role",
                 "parameters": [
                     {
                         "type": "string",
@@ -1196,13 +1253,43 @@ const docTemplate = `{
                         "name": "userID",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ADMIN, MODERATOR or AUTHOR",
+                        "name": "role",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "revoke role body",
+                        "name": "param",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleRevokeRole.request"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/main.handleGetUserFollowing.response"
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
                         }
                     },
                     "404": {
@@ -1211,6 +1298,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1220,20 +1313,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userID}/unfollow": {
+        "/auth/2fa": {
             "delete": {
-                "description": "Unfollow user",
+                "description": "Disable two factor authentication using a totp code or a recovery code",
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "followers"
+                    "auth"
                 ],
-                "summary": "Unfollow user",
+                "summary": "Disable two factor authentication",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "user id",
-                        "name": "userID",
-                        "in": "path",
-                        "required": true
+                        "description": "disable body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleTwoFactorDisable.request"
+                        }
                     }
                 ],
                 "responses": {
@@ -1246,6 +1344,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.errorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1254,81 +1364,4656 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.bookStats": {
+        },
+        "/auth/2fa/confirm": {
+            "post": {
+                "description": "Enable two factor authentication with a code from the authenticator app. Recovery codes are only shown once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm two factor enrollment",
+                "parameters": [
+                    {
+                        "description": "confirm body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleTwoFactorConfirm.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleTwoFactorConfirm.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/2fa/enroll": {
+            "post": {
+                "description": "Generate a totp secret and otpauth uri for an authenticator app. Two factor authentication is only enabled once confirmed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start two factor enrollment",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleTwoFactorEnroll.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/2fa/verify": {
+            "post": {
+                "description": "Second login step for users with two factor authentication, using the challenge token returned by login, or the challenge_token cookie set by a provider sign in, and either a totp code or a recovery code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify second factor",
+                "parameters": [
+                    {
+                        "description": "verify body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleTwoFactorVerify.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Login using either email, or both and password. Users with two factor authentication get a challenge token to complete the login at /auth/2fa/verify.",
+                "consumes": [
+                    "appplication/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "user",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthLogin.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthLogin.response"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "get": {
+                "description": "Logout user",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout user",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/auth/onboarding": {
+            "post": {
+                "description": "Onboard users",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Onboard users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "display name",
+                        "name": "display_name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "about",
+                        "name": "about",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "profile_picture",
+                        "name": "image",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthOnboarding.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/onboarding/display-name": {
+            "get": {
+                "description": "Check whether a display name is valid and not taken before submitting onboarding",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Check display name",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "display name",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthDisplayNameAvailable.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/onboarding/status": {
+            "get": {
+                "description": "Tells the frontend whether the current session still has to complete onboarding and which fields are needed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Onboarding status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthOnboardingStatus.response"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh-token": {
+            "post": {
+                "description": "Get new access token. Refresh tokens of deleted accounts or issued before the user's sessions were revoked are refused.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh token",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthRefreshToken.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Register user using email and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "user",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleAuthRegister.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}": {
+            "get": {
+                "description": "Sign in with an oauth provider",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in with an oauth provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider (google, github, discord)",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "frontend url to return to after signing in",
+                        "name": "returnTo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}/callback": {
+            "get": {
+                "description": "Oauth provider callback url. Users with two factor authentication are redirected to the frontend /2fa page with a short lived challenge_token cookie to verify.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Oauth provider callback url",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider (google, github, discord)",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}/link": {
+            "get": {
+                "description": "Link an oauth provider identity to the signed in account",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Link an oauth provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider (google, github, discord)",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "frontend url to return to after linking",
+                        "name": "returnTo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}/reauth": {
+            "get": {
+                "description": "Sign in again with a provider linked to the signed in account. Users without a password do this before changing their password or deleting their account, the confirmation lasts five minutes and is used up by the next of those requests.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in again with an oauth provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider (google, github, discord)",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "frontend url to return to after signing in",
+                        "name": "returnTo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books": {
+            "get": {
+                "description": "Get all books. Mature books are left out unless the signed in reader is 18 or over and turned them on, books with warnings the reader hid are left out too.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get all books",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "genre",
+                        "name": "genre",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language code",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "tags the books all have",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sort",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetBooks.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upload book",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Upload book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "name",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "description",
+                        "name": "description",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "genre",
+                        "name": "genres",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language code (e.g. en)",
+                        "name": "language",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "draft chapter title",
+                        "name": "chapter_title",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "draft chapter content",
+                        "name": "chapter_content",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "release days (e.g. Monday, Tuesday)",
+                        "name": "release_schedule_day",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "chapters per day (e.g. 1, 2)",
+                        "name": "release_schedule_chapter",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "book cover image (max 3MB)",
+                        "name": "book_cover",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "book this one translates",
+                        "name": "source_book_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "tags (at most 10)",
+                        "name": "tags",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "everyone",
+                            "teen",
+                            "mature"
+                        ],
+                        "type": "string",
+                        "description": "content rating",
+                        "name": "content_rating",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "content warnings (violence, gore, sexual content, self harm, substance abuse, strong language, abuse)",
+                        "name": "content_warnings",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUploadBook.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/chapters/{chapterID}": {
+            "get": {
+                "description": "Get chapter. Readers too young for the book's content rating, or who didn't turn mature content on, can't read it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chapters"
+                ],
+                "summary": "Get chapter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "chapter id",
+                        "name": "chapterID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetChapter.response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/recently-read": {
+            "get": {
+                "description": "Get recently read books",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get recently read books",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetRecentlyReadBooks.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/recently-uploaded": {
+            "get": {
+                "description": "Get recently uploaded books",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get recently uploaded books",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetRecentlyUploadedBooks.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/stats": {
+            "get": {
+                "description": "Get books stats",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get books stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "offset",
+                        "name": "offset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "limit",
+                        "name": "limit",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetBooksStats.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}": {
+            "get": {
+                "description": "Get a single book",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get a single book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetBook.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete book",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Delete book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Edit book",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Edit book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "name",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "description",
+                        "name": "description",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Release days (e.g. Monday, Tuesday)",
+                        "name": "release_schedule_day",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Chapters per day (e.g. 1, 2)",
+                        "name": "release_schedule_chapter",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "genres",
+                        "name": "genres",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "tags (at most 10), replaces the book's tags when sent",
+                        "name": "tags",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "everyone",
+                            "teen",
+                            "mature"
+                        ],
+                        "type": "string",
+                        "description": "content rating",
+                        "name": "content_rating",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "content warnings, replaces the book's warnings when sent",
+                        "name": "content_warnings",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "book cover",
+                        "name": "book_cover",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/chapters": {
+            "post": {
+                "description": "Upload chapter. The chapter is published once it passes automated moderation, chapters that don't are held until an admin reviews them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chapters"
+                ],
+                "summary": "Upload chapter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "upload chapter body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUploadChapter.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUploadChapter.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/chapters/{chapterID}": {
+            "delete": {
+                "description": "Delete chapter",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chapters"
+                ],
+                "summary": "Delete chapter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "chapter id",
+                        "name": "chapterID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Edit chapter. Published chapters with a new title or content are held until they pass automated moderation again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chapters"
+                ],
+                "summary": "Edit chapter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "chapter id",
+                        "name": "chapterID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "edit chapter body",
+                        "name": "param",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleEditChapter.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/collaborators": {
+            "get": {
+                "description": "Get everyone invited to work on a book. Only the author and collaborators can see them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get collaborators",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetCollaborators.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Invite a user to work on your book as a co-author, editor or translator with a share of its revenue. They can't do anything until they accept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Invite collaborator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "invite collaborator body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleInviteCollaborator.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/collaborators/{userID}": {
+            "delete": {
+                "description": "Remove a collaborator or invitation from your book, or leave a book you collaborate on",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Remove collaborator",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/complete": {
+            "patch": {
+                "description": "Complete book",
+                "tags": [
+                    "books"
+                ],
+                "summary": "Complete book",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "complete book body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCompleteBook.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{bookID}/translations": {
+            "get": {
+                "description": "Get the original of a book and its other translations you can read, with how much of the original each one has translated",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get translations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "BCP 47 language codes",
+                        "name": "language",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetTranslations.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Stream the events delivered over the websocket as server-sent events, for clients that can't use websockets",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sequence number of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sequence number of the last event received, used when Last-Event-ID is not set",
+                        "name": "lastSeenSeq",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/schema": {
+            "get": {
+                "description": "Get the JSON schema of the events sent to clients, generated from the event registry",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Get event schema",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/genres": {
+            "get": {
+                "description": "Get the genres authors can pick for their books",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "genres"
+                ],
+                "summary": "Get genres",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetGenres.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/languages": {
+            "get": {
+                "description": "Get the languages books can be written in",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "languages"
+                ],
+                "summary": "Get languages",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetLanguages.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/lists": {
+            "post": {
+                "description": "Create a collection of your own books or a reading list of any books",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Create list",
+                "parameters": [
+                    {
+                        "description": "create list body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateList.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateList.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/lists/{listID}": {
+            "get": {
+                "description": "Get a public list with its books in order, leaving out the ones rated above what you can read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Get list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "list id",
+                        "name": "listID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.listResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete your list",
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Delete list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "list id",
+                        "name": "listID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Rename your list, change its description or whether everyone can see it",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Update list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "list id",
+                        "name": "listID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "update list body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUpdateList.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/lists/{listID}/books/{bookID}": {
+            "put": {
+                "description": "Add a book to the end of your list. Collections only take books you wrote.",
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Add book to list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "list id",
+                        "name": "listID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a book from your list",
+                "tags": [
+                    "lists"
+                ],
+                "summary": "Remove book from list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "list id",
+                        "name": "listID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "post": {
+                "description": "Report a book, chapter or user. Content reported by enough people with established accounts is hidden until an admin reviews it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Report content",
+                "parameters": [
+                    {
+                        "description": "report body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateReport.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateReport.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/series": {
+            "post": {
+                "description": "Create a series out of your books. The books become its volumes in the order given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "series"
+                ],
+                "summary": "Create series",
+                "parameters": [
+                    {
+                        "description": "create series body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateSeries.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleCreateSeries.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/series/{seriesID}": {
+            "get": {
+                "description": "Get a series with its published volumes in order, leaving out the ones rated above what you can read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "series"
+                ],
+                "summary": "Get series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "series id",
+                        "name": "seriesID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetSeries.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete your series. Its books are kept.",
+                "tags": [
+                    "series"
+                ],
+                "summary": "Delete series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "series id",
+                        "name": "seriesID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Rename your series, change its description or replace its volumes with the books given, in order",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "series"
+                ],
+                "summary": "Update series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "series id",
+                        "name": "seriesID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "update series body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUpdateSeries.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Autocomplete tags. Synonyms match their canonical tag, canonical tags come first, then the most used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Search tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "start of the tag",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit (default 10, at most 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleSearchTags.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "description": "Get current user profile",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get current user profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetProfile.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the current user. Personal data is removed straight away; books are either transferred to another user or removed, and the rest of the account is cleaned up in the background. Users without a password either send their two factor code or sign in with /auth/{provider}/reauth first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete account",
+                "parameters": [
+                    {
+                        "description": "delete account body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleDeleteAccount.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleDeleteAccount.response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Edit the display name, about and profile picture of the current user",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Edit current user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "display name",
+                        "name": "display_name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "about",
+                        "name": "about",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "profile picture (max 400KB)",
+                        "name": "image",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/content-preferences": {
+            "get": {
+                "description": "Get the current user's birth date, whether they see mature books, the content warnings they hid and the content ratings they can read",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get content preferences",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetContentPreferences.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Set the birth date (once), turn mature books on or off (18 and over only) and choose the content warnings to hide when browsing. Fields left out are unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update content preferences",
+                "parameters": [
+                    {
+                        "description": "content preferences body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUpdateContentPreferences.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/exports": {
+            "get": {
+                "description": "Get the data exports of the current user and their status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get data exports",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetDataExports.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Start building a zip with all the personal data of the current user. The user is notified with a download link once it is ready.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Request data export",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleRequestDataExport.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/exports/{exportID}/download": {
+            "get": {
+                "description": "Redirects to a short lived signed link to the zip of a finished data export until the export expires",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Download data export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "export id",
+                        "name": "exportID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/identities": {
+            "get": {
+                "description": "Get the oauth provider identities linked to the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get linked identities",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetIdentities.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/identities/{provider}": {
+            "delete": {
+                "description": "Unlink an oauth provider identity from the current user",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unlink identity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/invitations": {
+            "get": {
+                "description": "Get the invitations to work on books you haven't answered yet",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetInvitations.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/invitations/{bookID}": {
+            "patch": {
+                "description": "Accept or decline an invitation to work on a book",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Respond to invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "book id",
+                        "name": "bookID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "respond to invitation body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleRespondToInvitation.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/lists": {
+            "get": {
+                "description": "Get your collections and reading lists, private ones included",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get my lists",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetMyLists.response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/password": {
+            "put": {
+                "description": "Change the password of the current user and sign out every other session. Users who signed up through an oauth provider and never set a password leave oldPassword empty and either send their two factor code or sign in with /auth/{provider}/reauth first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "description": "change password body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleChangePassword.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/privacy": {
+            "get": {
+                "description": "Get what the current user shows on their public profile",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get privacy settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetPrivacySettings.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Choose whether the library, reading history and reading stats show on the public profile. All three are private until the user opts in. Fields left out are unchanged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update privacy settings",
+                "parameters": [
+                    {
+                        "description": "privacy settings body",
+                        "name": "param",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.handleUpdatePrivacySettings.request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}": {
+            "get": {
+                "description": "Public profile of a user with their approved books. The library and reading history are left out when the user hid them, reading stats are only included when the user opted in.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetUserProfile.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/follow": {
+            "post": {
+                "description": "Follow user",
+                "tags": [
+                    "followers"
+                ],
+                "summary": "Follow user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/followers": {
+            "get": {
+                "description": "Get user followers",
+                "tags": [
+                    "followers"
+                ],
+                "summary": "Get user followers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetUserFollowers.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/following": {
+            "get": {
+                "description": "Get user following",
+                "tags": [
+                    "followers"
+                ],
+                "summary": "Get user following",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetUserFollowing.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/lists": {
+            "get": {
+                "description": "Get the public collections and reading lists of a user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user lists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetUserLists.response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/unfollow": {
+            "delete": {
+                "description": "Unfollow user",
+                "tags": [
+                    "followers"
+                ],
+                "summary": "Unfollow user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "user id",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ws/stats": {
+            "get": {
+                "description": "Get connection count and dropped message counters for this replica",
+                "tags": [
+                    "ws"
+                ],
+                "summary": "Get websocket delivery stats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.handleGetWSStats.response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.errorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.approveBookRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "plagiarismReviewed": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "main.bookStats": {
+            "type": "object",
+            "properties": {
+                "approved": {
+                    "type": "boolean"
+                },
+                "chapterCount": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "image": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "releaseSchedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.responseReleaseSchedule"
+                    }
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.collaboratorResponse": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "type": "string"
+                },
+                "bookId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "invitedBy": {
+                    "type": "string"
+                },
+                "revenueShare": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.errorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.getResponseBook": {
+            "type": "object",
+            "properties": {
+                "chapterCount": {
+                    "type": "integer"
+                },
+                "contentRating": {
+                    "type": "string"
+                },
+                "contentWarnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "image": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "releaseSchedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.responseReleaseSchedule"
+                    }
+                },
+                "series": {
+                    "$ref": "#/definitions/main.responseSeries"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleAddTagSynonym.request": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthDisplayNameAvailable.response": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthLogin.request": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthLogin.response": {
+            "type": "object",
+            "properties": {
+                "challengeToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthOnboarding.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "returnTo": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthOnboardingStatus.response": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "optionalFields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "pending": {
+                    "type": "boolean"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "requiredFields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "returnTo": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthRefreshToken.response": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleAuthRegister.request": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8
+                }
+            }
+        },
+        "main.handleChangePassword.request": {
+            "type": "object",
+            "required": [
+                "newPassword"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "type": "string",
+                    "minLength": 8
+                },
+                "oldPassword": {
+                    "type": "string"
+                },
+                "recoveryCode": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleClaimBook.request": {
+            "type": "object",
+            "properties": {
+                "assigneeId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleClaimBook.response": {
+            "type": "object",
+            "properties": {
+                "claimedBy": {
+                    "type": "string"
+                },
+                "claimedUntil": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCompleteBook.request": {
+            "type": "object",
+            "required": [
+                "complete"
+            ],
+            "properties": {
+                "complete": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.handleCreateGenre.request": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "main.handleCreateGenre.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCreateLanguage.request": {
+            "type": "object",
+            "required": [
+                "code",
+                "name",
+                "nativeName"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "nativeName": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "main.handleCreateList.request": {
+            "type": "object",
+            "required": [
+                "kind",
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "collection",
+                        "reading_list"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "public": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.handleCreateList.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCreateReport.request": {
+            "type": "object",
+            "required": [
+                "category",
+                "targetId",
+                "targetType"
+            ],
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "plagiarism",
+                        "abuse",
+                        "inappropriate",
+                        "spam",
+                        "other"
+                    ]
+                },
+                "details": {
+                    "type": "string",
+                    "maxLength": 2000
+                },
+                "targetId": {
+                    "type": "string"
+                },
+                "targetType": {
+                    "type": "string",
+                    "enum": [
+                        "book",
+                        "chapter",
+                        "user"
+                    ]
+                }
+            }
+        },
+        "main.handleCreateReport.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCreateSeries.request": {
+            "type": "object",
+            "required": [
+                "bookIds",
+                "name"
+            ],
+            "properties": {
+                "bookIds": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "main.handleCreateSeries.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCreateTag.request": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleCreateTag.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleDeleteAccount.request": {
+            "type": "object",
+            "required": [
+                "books"
+            ],
+            "properties": {
+                "books": {
+                    "type": "string",
+                    "enum": [
+                        "transfer",
+                        "remove"
+                    ]
+                },
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recoveryCode": {
+                    "type": "string"
+                },
+                "transferTo": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleDeleteAccount.response": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleEditChapter.request": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "sourceChapterId": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetBook.bookCredit": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "revenueShare": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetBook.chaptersBookPreview": {
+            "type": "object",
+            "properties": {
+                "chapterNo": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetBook.releaseSchedule": {
+            "type": "object",
+            "required": [
+                "chapters",
+                "day"
+            ],
+            "properties": {
+                "chapters": {
+                    "type": "integer"
+                },
+                "day": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetBook.response": {
+            "type": "object",
+            "properties": {
+                "chapterCount": {
+                    "type": "integer"
+                },
+                "chapters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetBook.chaptersBookPreview"
+                    }
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "contentRating": {
+                    "type": "string"
+                },
+                "contentWarnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "credits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetBook.bookCredit"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "image": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "release_schedule": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetBook.releaseSchedule"
+                    }
+                },
+                "series": {
+                    "$ref": "#/definitions/main.responseSeries"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "translations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.translationResponse"
+                    }
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleGetBooks.response": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.getResponseBook"
+                    }
+                }
+            }
+        },
+        "main.handleGetBooksStats.response": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.bookStats"
+                    }
+                }
+            }
+        },
+        "main.handleGetChapter.response": {
+            "type": "object",
+            "properties": {
+                "chapterNo": {
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetCollaborators.response": {
+            "type": "object",
+            "properties": {
+                "collaborators": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.collaboratorResponse"
+                    }
+                }
+            }
+        },
+        "main.handleGetContentPreferences.response": {
+            "type": "object",
+            "properties": {
+                "allowedRatings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "birthDate": {
+                    "type": "string"
+                },
+                "hiddenWarnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "showMatureContent": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.handleGetDataExports.response": {
+            "type": "object",
+            "properties": {
+                "exports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetDataExports.responseExport"
+                    }
+                }
+            }
+        },
+        "main.handleGetDataExports.responseExport": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "expired": {
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetFlaggedChapters.response": {
+            "type": "object",
+            "properties": {
+                "chapters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetFlaggedChapters.responseChapter"
+                    }
+                }
+            }
+        },
+        "main.handleGetFlaggedChapters.responseChapter": {
+            "type": "object",
+            "properties": {
+                "authorId": {
+                    "type": "string"
+                },
+                "book": {
+                    "type": "string"
+                },
+                "bookId": {
+                    "type": "string"
+                },
+                "chapterNo": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "verdicts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetFlaggedChapters.responseVerdict"
+                    }
+                }
+            }
+        },
+        "main.handleGetFlaggedChapters.responseVerdict": {
+            "type": "object",
+            "properties": {
+                "check": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "flagged": {
+                    "type": "boolean"
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.handleGetGenres.response": {
+            "type": "object",
+            "properties": {
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetGenres.responseGenre"
+                    }
+                }
+            }
+        },
+        "main.handleGetGenres.responseGenre": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetIdentities.response": {
+            "type": "object",
+            "properties": {
+                "identities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetIdentities.responseIdentity"
+                    }
+                }
+            }
+        },
+        "main.handleGetIdentities.responseIdentity": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetInvitations.response": {
             "type": "object",
             "properties": {
-                "approved": {
+                "invitations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.collaboratorResponse"
+                    }
+                }
+            }
+        },
+        "main.handleGetLanguages.response": {
+            "type": "object",
+            "properties": {
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetLanguages.responseLanguage"
+                    }
+                }
+            }
+        },
+        "main.handleGetLanguages.responseLanguage": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nativeName": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetModerationHistory.response": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetModerationHistory.responseAction"
+                    }
+                }
+            }
+        },
+        "main.handleGetModerationHistory.responseAction": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "assigneeId": {
+                    "type": "string"
+                },
+                "chapterId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "moderator": {
+                    "type": "string"
+                },
+                "moderatorId": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetMyLists.response": {
+            "type": "object",
+            "properties": {
+                "lists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.listResponse"
+                    }
+                }
+            }
+        },
+        "main.handleGetPendingBooks.response": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetPendingBooks.responseBook"
+                    }
+                }
+            }
+        },
+        "main.handleGetPendingBooks.responseBook": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "authorId": {
+                    "type": "string"
+                },
+                "chapterCount": {
+                    "type": "integer"
+                },
+                "claimedBy": {
+                    "type": "string"
+                },
+                "claimedByName": {
+                    "type": "string"
+                },
+                "claimedUntil": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "genres": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plagiarismOverlap": {
+                    "description": "PlagiarismOverlap is the share of the book found in the earlier\nbook by another author it overlaps with the most",
+                    "type": "number"
+                }
+            }
+        },
+        "main.handleGetPlagiarismReport.response": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetPlagiarismReport.responseMatch"
+                    }
+                }
+            }
+        },
+        "main.handleGetPlagiarismReport.responseChapter": {
+            "type": "object",
+            "properties": {
+                "chapterId": {
+                    "type": "string"
+                },
+                "chapterNo": {
+                    "type": "integer"
+                },
+                "shared": {
+                    "type": "integer"
+                },
+                "sourceChapterId": {
+                    "type": "string"
+                },
+                "sourceChapterNo": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleGetPlagiarismReport.responseMatch": {
+            "type": "object",
+            "properties": {
+                "chapters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetPlagiarismReport.responseChapter"
+                    }
+                },
+                "overlap": {
+                    "type": "number"
+                },
+                "shared": {
+                    "type": "integer"
+                },
+                "sourceAuthor": {
+                    "type": "string"
+                },
+                "sourceAuthorId": {
+                    "type": "string"
+                },
+                "sourceBook": {
+                    "type": "string"
+                },
+                "sourceBookId": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetPrivacySettings.response": {
+            "type": "object",
+            "properties": {
+                "libraryPublic": {
+                    "type": "boolean"
+                },
+                "readingHistoryPublic": {
+                    "type": "boolean"
+                },
+                "readingStatsPublic": {
                     "type": "boolean"
+                }
+            }
+        },
+        "main.handleGetProfile.response": {
+            "type": "object",
+            "properties": {
+                "about": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "main.handleGetRecentlyReadBooks.response": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetRecentlyReadBooks.responseBooks"
+                    }
+                }
+            }
+        },
+        "main.handleGetRecentlyReadBooks.responseBooks": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "type": "string"
+                },
+                "lastReadChapter": {
+                    "type": "integer"
+                },
+                "lastReadTime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetRecentlyUploadedBooks.response": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetRecentlyUploadedBooks.responseBooks"
+                    }
+                }
+            }
+        },
+        "main.handleGetRecentlyUploadedBooks.responseBooks": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetReports.response": {
+            "type": "object",
+            "properties": {
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetReports.responseReport"
+                    }
+                }
+            }
+        },
+        "main.handleGetReports.responseReport": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
                 },
-                "chapterCount": {
-                    "type": "integer"
+                "createdAt": {
+                    "type": "string"
                 },
-                "completed": {
-                    "type": "boolean"
+                "details": {
+                    "type": "string"
                 },
-                "createdAt": {
+                "id": {
                     "type": "string"
                 },
-                "description": {
+                "openReports": {
+                    "type": "integer"
+                },
+                "reporter": {
                     "type": "string"
                 },
-                "genres": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "reporterId": {
+                    "type": "string"
                 },
-                "image": {
+                "resolution": {
                     "type": "string"
                 },
-                "language": {
+                "resolvedAt": {
                     "type": "string"
                 },
-                "name": {
+                "resolvedBy": {
                     "type": "string"
                 },
-                "rating": {
-                    "type": "number"
+                "status": {
+                    "type": "string"
                 },
-                "releaseSchedule": {
+                "targetHidden": {
+                    "type": "boolean"
+                },
+                "targetId": {
+                    "type": "string"
+                },
+                "targetType": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.handleGetRoles.response": {
+            "type": "object",
+            "properties": {
+                "history": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.responseReleaseSchedule"
+                        "$ref": "#/definitions/main.handleGetRoles.responseChange"
                     }
                 },
-                "updatedAt": {
-                    "type": "string"
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "views": {
-                    "type": "integer"
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "main.errorResponse": {
+        "main.handleGetRoles.responseChange": {
             "type": "object",
             "properties": {
-                "error": {
+                "action": {
+                    "type": "string"
+                },
+                "changedBy": {
+                    "type": "string"
+                },
+                "changedByName": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "role": {
                     "type": "string"
                 }
             }
         },
-        "main.getResponseBook": {
+        "main.handleGetSeries.response": {
             "type": "object",
             "properties": {
-                "chapterCount": {
-                    "type": "integer"
+                "author": {
+                    "type": "string"
+                },
+                "authorId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
                 },
                 "description": {
                     "type": "string"
                 },
-                "genres": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "volumes": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/main.handleGetSeries.volume"
                     }
+                }
+            }
+        },
+        "main.handleGetSeries.volume": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
                 },
                 "image": {
                     "type": "string"
@@ -1339,375 +6024,605 @@ const docTemplate = `{
                 "rating": {
                     "type": "number"
                 },
-                "releaseSchedule": {
+                "views": {
+                    "type": "integer"
+                },
+                "volume": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleGetTranslations.response": {
+            "type": "object",
+            "properties": {
+                "translations": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.responseReleaseSchedule"
+                        "$ref": "#/definitions/main.translationResponse"
                     }
+                }
+            }
+        },
+        "main.handleGetUserFollowers.follower": {
+            "type": "object",
+            "properties": {
+                "about": {
+                    "type": "string"
                 },
-                "views": {
-                    "type": "integer"
+                "displayName": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleApproveBook.request": {
+        "main.handleGetUserFollowers.response": {
             "type": "object",
-            "required": [
-                "approve"
-            ],
             "properties": {
-                "approve": {
-                    "type": "boolean"
+                "followers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetUserFollowers.follower"
+                    }
                 }
             }
         },
-        "main.handleAuthLogin.request": {
+        "main.handleGetUserFollowing.following": {
             "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "about": {
                     "type": "string"
                 },
-                "password": {
+                "displayName": {
+                    "type": "string"
+                },
+                "image": {
                     "type": "string"
                 }
             }
         },
-        "main.handleAuthOnboarding.response": {
+        "main.handleGetUserFollowing.response": {
             "type": "object",
             "properties": {
-                "id": {
+                "following": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetUserFollowing.following"
+                    }
+                }
+            }
+        },
+        "main.handleGetUserLists.response": {
+            "type": "object",
+            "properties": {
+                "lists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.listResponse"
+                    }
+                }
+            }
+        },
+        "main.handleGetUserProfile.response": {
+            "type": "object",
+            "properties": {
+                "about": {
+                    "type": "string"
+                },
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.getResponseBook"
+                    }
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "followers": {
+                    "type": "integer"
+                },
+                "following": {
+                    "type": "integer"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "joined": {
                     "type": "string"
+                },
+                "library": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.getResponseBook"
+                    }
+                },
+                "online": {
+                    "type": "boolean"
+                },
+                "readingStats": {
+                    "$ref": "#/definitions/main.handleGetUserProfile.responseReadingStats"
+                },
+                "recentlyRead": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.handleGetUserProfile.responseRecentlyRead"
+                    }
                 }
             }
         },
-        "main.handleAuthRefreshToken.response": {
+        "main.handleGetUserProfile.responseReadingStats": {
             "type": "object",
             "properties": {
-                "message": {
+                "booksRead": {
+                    "type": "integer"
+                },
+                "chaptersRead": {
+                    "type": "integer"
+                },
+                "libraryBooks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleGetUserProfile.responseRecentlyRead": {
+            "type": "object",
+            "properties": {
+                "image": {
+                    "type": "string"
+                },
+                "lastReadAt": {
+                    "type": "string"
+                },
+                "lastReadChapter": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 }
             }
         },
-        "main.handleAuthRegister.request": {
+        "main.handleGetWSStats.response": {
+            "type": "object",
+            "properties": {
+                "connections": {
+                    "type": "integer"
+                },
+                "droppedEvents": {
+                    "type": "integer"
+                },
+                "droppedMessages": {
+                    "type": "integer"
+                },
+                "slowDisconnects": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.handleGrantRole.request": {
             "type": "object",
             "required": [
-                "email",
-                "password"
+                "role"
             ],
             "properties": {
-                "email": {
-                    "type": "string"
+                "reason": {
+                    "type": "string",
+                    "maxLength": 1000
                 },
-                "password": {
+                "role": {
                     "type": "string",
-                    "minLength": 8
+                    "enum": [
+                        "ADMIN",
+                        "MODERATOR",
+                        "AUTHOR"
+                    ]
                 }
             }
         },
-        "main.handleCompleteBook.request": {
+        "main.handleInviteCollaborator.request": {
             "type": "object",
             "required": [
-                "complete"
+                "role",
+                "userId"
             ],
             "properties": {
-                "complete": {
-                    "type": "boolean"
+                "revenueShare": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "co_author",
+                        "editor",
+                        "translator"
+                    ]
+                },
+                "userId": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleEditChapter.request": {
+        "main.handleRequestDataExport.response": {
             "type": "object",
             "properties": {
-                "content": {
+                "id": {
                     "type": "string"
+                }
+            }
+        },
+        "main.handleResolveReport.request": {
+            "type": "object",
+            "required": [
+                "resolution",
+                "status"
+            ],
+            "properties": {
+                "resolution": {
+                    "type": "string",
+                    "maxLength": 1000
                 },
-                "title": {
-                    "type": "string"
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "dismissed",
+                        "upheld"
+                    ]
                 }
             }
         },
-        "main.handleGetBook.chaptersBookPreview": {
+        "main.handleResolveReport.response": {
             "type": "object",
             "properties": {
-                "chapterNo": {
+                "resolved": {
                     "type": "integer"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "title": {
-                    "type": "string"
                 }
             }
         },
-        "main.handleGetBook.releaseSchedule": {
+        "main.handleRespondToInvitation.request": {
             "type": "object",
             "required": [
-                "chapters",
-                "day"
+                "accept"
             ],
             "properties": {
-                "chapters": {
-                    "type": "integer"
-                },
-                "day": {
-                    "type": "string"
+                "accept": {
+                    "type": "boolean"
                 }
             }
         },
-        "main.handleGetBook.response": {
+        "main.handleRevokeRole.request": {
             "type": "object",
             "properties": {
-                "chapterCount": {
-                    "type": "integer"
-                },
-                "chapters": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "main.handleSearchTags.response": {
+            "type": "object",
+            "properties": {
+                "tags": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.handleGetBook.chaptersBookPreview"
+                        "$ref": "#/definitions/main.handleSearchTags.responseTag"
                     }
+                }
+            }
+        },
+        "main.handleSearchTags.responseTag": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "integer"
                 },
-                "completed": {
+                "canonical": {
                     "type": "boolean"
                 },
-                "description": {
-                    "type": "string"
-                },
-                "genres": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "image": {
+                "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "rating": {
-                    "type": "number"
-                },
-                "release_schedule": {
+                "synonyms": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.handleGetBook.releaseSchedule"
+                        "type": "string"
                     }
-                },
-                "views": {
-                    "type": "integer"
                 }
             }
         },
-        "main.handleGetBooks.response": {
+        "main.handleTwoFactorConfirm.request": {
             "type": "object",
+            "required": [
+                "code"
+            ],
             "properties": {
-                "books": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.getResponseBook"
-                    }
+                "code": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleGetBooksStats.response": {
+        "main.handleTwoFactorConfirm.response": {
             "type": "object",
             "properties": {
-                "books": {
+                "recoveryCodes": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.bookStats"
+                        "type": "string"
                     }
                 }
             }
         },
-        "main.handleGetChapter.response": {
+        "main.handleTwoFactorDisable.request": {
             "type": "object",
             "properties": {
-                "chapterNo": {
-                    "type": "integer"
-                },
-                "content": {
+                "code": {
                     "type": "string"
                 },
-                "title": {
+                "recoveryCode": {
                     "type": "string"
                 }
             }
         },
-        "main.handleGetProfile.response": {
+        "main.handleTwoFactorEnroll.response": {
             "type": "object",
             "properties": {
-                "about": {
+                "secret": {
                     "type": "string"
                 },
-                "displayName": {
+                "uri": {
                     "type": "string"
-                },
-                "email": {
+                }
+            }
+        },
+        "main.handleTwoFactorVerify.request": {
+            "type": "object",
+            "properties": {
+                "challengeToken": {
                     "type": "string"
                 },
-                "image": {
+                "code": {
                     "type": "string"
                 },
-                "roles": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "recoveryCode": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleGetRecentlyReadBooks.response": {
+        "main.handleUpdateContentPreferences.request": {
             "type": "object",
             "properties": {
-                "books": {
+                "birthDate": {
+                    "type": "string"
+                },
+                "hiddenWarnings": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.handleGetRecentlyReadBooks.responseBooks"
+                        "type": "string"
                     }
+                },
+                "showMatureContent": {
+                    "type": "boolean"
                 }
             }
         },
-        "main.handleGetRecentlyReadBooks.responseBooks": {
+        "main.handleUpdateList.request": {
             "type": "object",
             "properties": {
-                "image": {
-                    "type": "string"
-                },
-                "lastReadChapter": {
-                    "type": "integer"
-                },
-                "lastReadTime": {
+                "description": {
                     "type": "string"
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
+                },
+                "public": {
+                    "type": "boolean"
                 }
             }
         },
-        "main.handleGetRecentlyUploadedBooks.response": {
+        "main.handleUpdatePrivacySettings.request": {
             "type": "object",
             "properties": {
-                "books": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.handleGetRecentlyUploadedBooks.responseBooks"
-                    }
+                "libraryPublic": {
+                    "type": "boolean"
+                },
+                "readingHistoryPublic": {
+                    "type": "boolean"
+                },
+                "readingStatsPublic": {
+                    "type": "boolean"
                 }
             }
         },
-        "main.handleGetRecentlyUploadedBooks.responseBooks": {
+        "main.handleUpdateSeries.request": {
             "type": "object",
             "properties": {
-                "author": {
-                    "type": "string"
+                "bookIds": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "image": {
+                "description": {
                     "type": "string"
                 },
                 "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
+                }
+            }
+        },
+        "main.handleUploadBook.response": {
+            "type": "object",
+            "properties": {
+                "id": {
                     "type": "string"
                 }
             }
         },
-        "main.handleGetUserFollowers.follower": {
+        "main.handleUploadChapter.request": {
             "type": "object",
+            "required": [
+                "chapterNo",
+                "content",
+                "title"
+            ],
             "properties": {
-                "about": {
+                "chapterNo": {
+                    "type": "integer"
+                },
+                "content": {
                     "type": "string"
                 },
-                "displayName": {
+                "sourceChapterId": {
                     "type": "string"
                 },
-                "image": {
+                "title": {
                     "type": "string"
                 }
             }
         },
-        "main.handleGetUserFollowers.response": {
+        "main.handleUploadChapter.response": {
             "type": "object",
             "properties": {
-                "followers": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.handleGetUserFollowers.follower"
-                    }
+                "id": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleGetUserFollowing.following": {
+        "main.listBookResponse": {
             "type": "object",
             "properties": {
-                "about": {
+                "description": {
                     "type": "string"
                 },
-                "displayName": {
+                "id": {
                     "type": "string"
                 },
                 "image": {
                     "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "views": {
+                    "type": "integer"
                 }
             }
         },
-        "main.handleGetUserFollowing.response": {
+        "main.listResponse": {
             "type": "object",
             "properties": {
-                "following": {
+                "books": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.handleGetUserFollowing.following"
+                        "$ref": "#/definitions/main.listBookResponse"
                     }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "updatedAt": {
+                    "type": "string"
                 }
             }
         },
-        "main.handleUploadBook.response": {
+        "main.moderationRequest": {
             "type": "object",
+            "required": [
+                "reason"
+            ],
             "properties": {
-                "id": {
-                    "type": "string"
+                "reason": {
+                    "type": "string",
+                    "maxLength": 1000
                 }
             }
         },
-        "main.handleUploadChapter.request": {
+        "main.responseReleaseSchedule": {
             "type": "object",
-            "required": [
-                "chapterNo",
-                "content",
-                "title"
-            ],
             "properties": {
-                "chapterNo": {
+                "chapters": {
                     "type": "integer"
                 },
-                "content": {
-                    "type": "string"
-                },
-                "title": {
+                "day": {
                     "type": "string"
                 }
             }
         },
-        "main.handleUploadChapter.response": {
+        "main.responseSeries": {
             "type": "object",
             "properties": {
                 "id": {
                     "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "volume": {
+                    "type": "integer"
+                },
+                "volumes": {
+                    "type": "integer"
                 }
             }
         },
-        "main.responseReleaseSchedule": {
+        "main.translationResponse": {
             "type": "object",
             "properties": {
-                "chapters": {
-                    "type": "integer"
+                "id": {
+                    "type": "string"
                 },
-                "day": {
+                "language": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string"
+                },
+                "original": {
+                    "type": "boolean"
+                },
+                "originalChapters": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "description": "Progress is the percentage of the original's chapters translated",
+                    "type": "integer"
+                },
+                "translatedChapters": {
+                    "type": "integer"
                 }
             }
         }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// jsonSchemaOf describes a payload type by reflecting over its json tags.
+// Fields tagged omitempty are optional, every other field is required.
+func jsonSchemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Pointer:
+		return jsonSchemaOf(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = jsonSchemaOf(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+
+	return map[string]any{}
+}
+
+// eventSchema is the JSON schema of the event envelope, with one branch per
+// registered event type pinning its version and payload
+func eventSchema() map[string]any {
+	defs := map[string]any{}
+	branches := []any{}
+	names := []string{}
+
+	for t := range len(eventRegistry) {
+		def := eventRegistry[eventType(t)]
+
+		payload := jsonSchemaOf(reflect.TypeOf(def.payload))
+		payload["description"] = def.description
+		defs[def.name] = payload
+
+		names = append(names, def.name)
+		branches = append(branches, map[string]any{
+			"properties": map[string]any{
+				"type":    map[string]any{"const": def.name},
+				"version": map[string]any{"maximum": def.version},
+				"payload": map[string]any{"$ref": "#/$defs/" + def.name},
+			},
+		})
+	}
+
+	return map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "Pagesy event",
+		"description": "Envelope of the events sent over /api/v1/ws, /api/v1/events and between replicas",
+		"type":        "object",
+		"properties": map[string]any{
+			"id":         map[string]any{"type": "string"},
+			"type":       map[string]any{"enum": names},
+			"version":    map[string]any{"type": "integer", "minimum": 1},
+			"occurredAt": map[string]any{"type": "string", "format": "date-time"},
+			"seq":        map[string]any{"type": "integer", "minimum": 1},
+			"payload":    map[string]any{"type": "object"},
+		},
+		"required": []string{"id", "type", "version", "occurredAt", "payload"},
+		"oneOf":    branches,
+		"$defs":    defs,
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// every replica binds its own queue to this exchange, so events published by
+// one replica reach the websocket clients connected to all of them
+const exchangeEvents = "pagesy.events"
+
+// eventsPrefetch is how many events a replica takes from the broker before
+// acking them
+const eventsPrefetch = 100
+
+type eventType int
+
+const (
+	NEW_BOOK eventType = iota
+	CHAPTER_UPLOADED
+	NEW_FOLLOWER
+	TYPING
+	BOOK_MODERATED
+	MODERATION_UPDATED
+	NEW_REPORT
+)
+
+type eventDefinition struct {
+	name        string
+	version     int
+	description string
+	// payload is the zero value of the event's payload type
+	payload eventPayload
+	// ephemeral events are only useful while they are fresh. They are not
+	// recorded for replay and are never worth disconnecting a slow client over.
+	ephemeral bool
+	// admin events are only ever delivered to admins
+	admin bool
+}
+
+// eventRegistry lists every event that can be sent. The JSON schema served to
+// consumers is generated from it, and a payload change older consumers can't
+// read has to bump the event's version.
+var eventRegistry = map[eventType]eventDefinition{
+	NEW_BOOK: {
+		name:        "book.created",
+		version:     1,
+		description: "A book was uploaded and is waiting for approval. Sent to admins.",
+		payload:     newBookEvent{},
+		admin:       true,
+	},
+	CHAPTER_UPLOADED: {
+		name:        "chapter.uploaded",
+		version:     1,
+		description: "A chapter was uploaded to a book the reader has in their library or subscribed to.",
+		payload:     chapterUploadEvent{},
+	},
+	NEW_FOLLOWER: {
+		name:        "user.followed",
+		version:     1,
+		description: "Someone followed the user.",
+		payload:     followerEvent{},
+	},
+	TYPING: {
+		name:        "book.typing",
+		version:     1,
+		description: "Someone subscribed to the book is typing a comment.",
+		payload:     typingEvent{},
+		ephemeral:   true,
+	},
+	BOOK_MODERATED: {
+		name:        "book.moderated",
+		version:     1,
+		description: "An admin approved or rejected the author's book.",
+		payload:     bookModeratedEvent{},
+	},
+	MODERATION_UPDATED: {
+		name:        "moderation.updated",
+		version:     1,
+		description: "A book in the moderation queue was claimed, released or decided on. Sent to admins.",
+		payload:     moderationUpdatedEvent{},
+		ephemeral:   true,
+		admin:       true,
+	},
+	NEW_REPORT: {
+		name:        "report.created",
+		version:     1,
+		description: "Someone reported a book, chapter or user. Sent to admins.",
+		payload:     reportEvent{},
+		admin:       true,
+	},
+}
+
+func (e eventType) String() string {
+	if def, ok := eventRegistry[e]; ok {
+		return def.name
+	}
+	return "Unknown event"
+}
+
+func (e eventType) ephemeral() bool {
+	return eventRegistry[e].ephemeral
+}
+
+// adminEventNames lists the events only admins may have replayed
+func adminEventNames() []string {
+	var names []string
+	for _, def := range eventRegistry {
+		if def.admin {
+			names = append(names, def.name)
+		}
+	}
+	return names
+}
+
+func parseEventType(name string) (eventType, bool) {
+	for t, def := range eventRegistry {
+		if def.name == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// eventPayload ties every payload type to its event, so an event can't be
+// built with the wrong payload
+type eventPayload interface {
+	eventType() eventType
+}
+
+type newBookEvent struct {
+	BookId  string `json:"bookId"`
+	Message string `json:"message"`
+}
+
+type chapterUploadEvent struct {
+	BookId  string `json:"bookId"`
+	Message string `json:"message"`
+}
+
+type followerEvent struct {
+	UserId  string `json:"userId"`
+	Message string `json:"message"`
+}
+
+type typingEvent struct {
+	BookId      string `json:"bookId"`
+	UserId      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+}
+
+type bookModeratedEvent struct {
+	BookId   string `json:"bookId"`
+	AuthorId string `json:"authorId"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason"`
+	Message  string `json:"message"`
+}
+
+type moderationUpdatedEvent struct {
+	BookId       string     `json:"bookId"`
+	Status       string     `json:"status"`
+	ClaimedBy    string     `json:"claimedBy,omitempty"`
+	ClaimedUntil *time.Time `json:"claimedUntil,omitempty"`
+}
+
+type reportEvent struct {
+	ReportId   string `json:"reportId"`
+	TargetType string `json:"targetType"`
+	TargetId   string `json:"targetId"`
+	Category   string `json:"category"`
+	// OpenReports counts the open reports on the target, this one included
+	OpenReports int    `json:"openReports"`
+	Hidden      bool   `json:"hidden"`
+	Message     string `json:"message"`
+}
+
+func (newBookEvent) eventType() eventType           { return NEW_BOOK }
+func (chapterUploadEvent) eventType() eventType     { return CHAPTER_UPLOADED }
+func (followerEvent) eventType() eventType          { return NEW_FOLLOWER }
+func (typingEvent) eventType() eventType            { return TYPING }
+func (bookModeratedEvent) eventType() eventType     { return BOOK_MODERATED }
+func (moderationUpdatedEvent) eventType() eventType { return MODERATION_UPDATED }
+func (reportEvent) eventType() eventType            { return NEW_REPORT }
+
+type event struct {
+	ID         string
+	Type       eventType
+	OccurredAt time.Time
+	Payload    eventPayload
+	// Seqs holds the sequence number the event was recorded with for every
+	// recipient. Ephemeral events have none.
+	Seqs map[string]int64
+}
+
+func newEvent(payload eventPayload) *event {
+	return &event{ID: newEventID(), Type: payload.eventType(), OccurredAt: time.Now().UTC(), Payload: payload}
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// envelope is how an event reaches its consumers. Seq is the event's number in
+// the recipient's sequence and is left out for ephemeral events.
+type envelope struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Seq        int64           `json:"seq,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// wireEvent is how events travel between replicas: the envelope along with
+// the sequence number of every recipient
+type wireEvent struct {
+	envelope
+	Seqs map[string]int64 `json:"seqs,omitempty"`
+}
+
+// wrap puts the event in an envelope for the recipient with the given sequence
+// number
+func (e *event) wrap(seq int64) (*envelope, error) {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling event payload, %v", err)
+	}
+
+	return &envelope{
+		ID:         e.ID,
+		Type:       e.Type.String(),
+		Version:    eventRegistry[e.Type].version,
+		OccurredAt: e.OccurredAt,
+		Seq:        seq,
+		Payload:    payload,
+	}, nil
+}
+
+func encodeEvent(e *event) ([]byte, error) {
+	env, err := e.wrap(0)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&wireEvent{envelope: *env, Seqs: e.Seqs})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling event, %v", err)
+	}
+
+	return body, nil
+}
+
+// decodeEvent turns a wire event back into the payload type registered for it
+func decodeEvent(body []byte) (*event, error) {
+	var w wireEvent
+	if err := json.Unmarshal(body, &w); err != nil {
+		return nil, fmt.Errorf("error unmarshalling event, %v", err)
+	}
+
+	t, ok := parseEventType(w.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", w.Type)
+	}
+
+	if w.Version > eventRegistry[t].version {
+		return nil, fmt.Errorf("unsupported %s version %d", w.Type, w.Version)
+	}
+
+	payload := reflect.New(reflect.TypeOf(eventRegistry[t].payload))
+	if err := json.Unmarshal(w.Payload, payload.Interface()); err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s payload, %v", w.Type, err)
+	}
+
+	return &event{
+		ID:         w.ID,
+		Type:       t,
+		OccurredAt: w.OccurredAt,
+		Payload:    payload.Elem().Interface().(eventPayload),
+		Seqs:       w.Seqs,
+	}, nil
+}
+
+// publishEvent records the event for its recipients, so clients that are offline
+// can have it replayed, and sends it to every replica.
+func (s *server) publishEvent(ctx context.Context, e *event) error {
+	if !e.Type.ephemeral() {
+		seqs, err := s.recordEvent(ctx, e, s.hub.replayMaxAge)
+		if err != nil {
+			return err
+		}
+		e.Seqs = seqs
+	}
+
+	return s.fanOutEvent(ctx, e)
+}
+
+// fanOutEvent sends an event to every replica, including this one. Without a
+// broker the event is delivered to the local hub only.
+func (s *server) fanOutEvent(ctx context.Context, e *event) error {
+	if s.ch == nil {
+		s.hub.dispatch(e)
+		return nil
+	}
+
+	body, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ch.PublishWithContext(ctx, exchangeEvents, "", false, false, amqp.Publishing{ContentType: "application/json", Body: body}); err != nil {
+		return fmt.Errorf("error publishing event, %v", err)
+	}
+
+	return nil
+}
+
+// consumeEvents hands events coming from the exchange to the local hub until
+// the deliveries channel is closed. Events are only acked once the hub took
+// them, so with a prefetch limit on the consumer the events the hub hasn't
+// got to yet stay with the broker.
+func (s *server) consumeEvents(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		e, err := decodeEvent(d.Body)
+		if err != nil {
+			s.logger.Error(err.Error())
+			d.Nack(false, false)
+			continue
+		}
+		s.hub.broadcast <- e
+		d.Ack(false)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker is an in-process fanout exchange. Every bound queue gets a copy
+// of each message published to the events exchange.
+type fakeBroker struct {
+	mu     sync.Mutex
+	queues []chan amqp.Delivery
+}
+
+func (b *fakeBroker) bind() <-chan amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := make(chan amqp.Delivery, 16)
+	b.queues = append(b.queues, q)
+	return q
+}
+
+func (b *fakeBroker) PublishWithContext(_ context.Context, exchange, _ string, _, _ bool, msg amqp.Publishing) error {
+	if exchange != exchangeEvents {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, q := range b.queues {
+		q <- amqp.Delivery{Exchange: exchange, ContentType: msg.ContentType, Body: msg.Body}
+	}
+	return nil
+}
+
+func TestEncodeDecodeEvent(t *testing.T) {
+	uploaded := newEvent(chapterUploadEvent{BookId: "1", Message: "book chapter 2"})
+	uploaded.Seqs = map[string]int64{"2": 3, "3": 7}
+
+	tests := []*event{
+		newEvent(newBookEvent{BookId: "1", Message: "book waiting for approval"}),
+		uploaded,
+		newEvent(followerEvent{UserId: "2", Message: "user followed you"}),
+		newEvent(typingEvent{BookId: "1", UserId: "2", DisplayName: "user"}),
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Type.String(), func(t *testing.T) {
+			body, err := encodeEvent(tc)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			got, err := decodeEvent(body)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if !reflect.DeepEqual(got, tc) {
+				t.Fatalf("expected %+v, got %+v", tc, got)
+			}
+		})
+	}
+}
+
+func TestDecodeEventRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{`},
+		{name: "unknown type", body: `{"version": 1, "type": "UNKNOWN", "payload": {}}`},
+		{name: "legacy type name", body: `{"version": 1, "type": "TYPING", "payload": {}}`},
+		{name: "newer version", body: `{"version": 2, "type": "book.typing", "payload": {}}`},
+		{name: "invalid payload", body: `{"version": 1, "type": "book.typing", "payload": "typing"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeEvent([]byte(tc.body)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestFanOutEvent(t *testing.T) {
+	broker := &fakeBroker{}
+
+	uploader := newServer(nil, nil, nil, broker)
+	reader := newServer(nil, nil, nil, broker)
+	go uploader.consumeEvents(broker.bind())
+	go reader.consumeEvents(broker.bind())
+
+	local := &client{id: "local", connID: newConnectionID(), send: make(chan []byte, 1)}
+	remote := &client{id: "remote", connID: newConnectionID(), send: make(chan []byte, 1)}
+
+	connectClient(t, uploader, local)
+	uploader.hub.joinRoom <- &roomUser{roomID: "book", client: local}
+	connectClient(t, reader, remote)
+	reader.hub.joinRoom <- &roomUser{roomID: "book", client: remote}
+
+	e := newEvent(chapterUploadEvent{BookId: "book", Message: "book chapter 1"})
+	e.Seqs = map[string]int64{"local": 1, "remote": 2}
+
+	if err := uploader.fanOutEvent(context.Background(), e); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, c := range []*client{local, remote} {
+		select {
+		case body := <-c.send:
+			var msg serverMessage
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Fatal(err.Error())
+			}
+			if msg.Event == nil || msg.Event.Type != CHAPTER_UPLOADED.String() || msg.Event.ID != e.ID {
+				t.Fatalf("%s: expected %s %s, got %+v", c.id, CHAPTER_UPLOADED, e.ID, msg.Event)
+			}
+			if expected := e.Seqs[c.id]; msg.Event.Seq != expected {
+				t.Fatalf("%s: expected seq %d, got %d", c.id, expected, msg.Event.Seq)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: expected event to be delivered", c.id)
+		}
+	}
+
+	// the uploading replica only delivers what comes back from the exchange
+	select {
+	case <-local.send:
+		t.Fatal("expected event to be delivered once")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventRegistry(t *testing.T) {
+	names := map[string]bool{}
+
+	for typ, def := range eventRegistry {
+		if def.payload.eventType() != typ {
+			t.Fatalf("%s: payload belongs to %s", def.name, def.payload.eventType())
+		}
+		if def.version < 1 {
+			t.Fatalf("%s: expected version to start at 1", def.name)
+		}
+		if names[def.name] {
+			t.Fatalf("%s: registered twice", def.name)
+		}
+		names[def.name] = true
+	}
+
+	// the schema walks the registry by index
+	for typ := range len(eventRegistry) {
+		if _, ok := eventRegistry[eventType(typ)]; !ok {
+			t.Fatalf("expected event type %d to be registered", typ)
+		}
+	}
+}
+
+// The schema checked in for consumers has to match the registry. Regenerate it
+// with UPDATE_EVENT_SCHEMA=1 go test -run TestEventSchemaDocument.
+func TestEventSchemaDocument(t *testing.T) {
+	const path = "docs/events.schema.json"
+
+	schema, err := json.MarshalIndent(eventSchema(), "", "    ")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	schema = append(schema, '\n')
+
+	if os.Getenv("UPDATE_EVENT_SCHEMA") != "" {
+		if err := os.WriteFile(path, schema, 0o644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(existing, schema) {
+		t.Fatalf("%s is out of date, regenerate it with UPDATE_EVENT_SCHEMA=1", path)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -10,35 +11,197 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"database/sql"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// handleAuthGoogle godoc
+// githubEmailsURL lists the emails of the github account an access token
+// belongs to
+var githubEmailsURL = "https://api.github.com/user/emails"
+
+var githubClient = &http.Client{Timeout: 10 * time.Second}
+
+// providerEmailVerified reports whether the provider vouches for the email it
+// returned. Unverified emails are never used to match or create accounts,
+// otherwise anyone could claim an account by adding its email to a provider.
+func providerEmailVerified(ctx context.Context, u goth.User) (bool, error) {
+	switch u.Provider {
+	case "google":
+		for _, key := range []string{"verified_email", "email_verified"} {
+			if verified, ok := u.RawData[key].(bool); ok {
+				return verified, nil
+			}
+		}
+		return false, nil
+	case "github":
+		if u.Email == "" {
+			return false, nil
+		}
+		return githubEmailVerified(ctx, u.AccessToken, u.Email)
+	case "discord":
+		verified, _ := u.RawData["verified"].(bool)
+		return verified, nil
+	}
+	return false, nil
+}
+
+// githubEmailVerified checks email is the primary verified email of the
+// github account. The email on the profile is whatever public email the user
+// typed in, github never checks it.
+func githubEmailVerified(ctx context.Context, accessToken string, email string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubEmailsURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating github emails request, %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("error getting github emails, %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("error getting github emails, status %d", resp.StatusCode)
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return false, fmt.Errorf("error decoding github emails, %v", err)
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified && strings.EqualFold(e.Email, email) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// handleAuthProvider godoc
 //
-//	@Summary		Sign in with google
-//	@Description	Sign in with google
+//	@Summary		Sign in with an oauth provider
+//	@Description	Sign in with an oauth provider
 //	@Tags			auth
+//	@Param			provider	path		string	true	"provider (google, github, discord)"
+//	@Param			returnTo	query		string	false	"frontend url to return to after signing in"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
 //	@Success		302
 //	@Success		307
-//	@Router			/auth/google [get]
-func (s *server) handleAuthGoogle(w http.ResponseWriter, r *http.Request) {
-	r = r.WithContext(context.WithValue(r.Context(), "provider", "google"))
+//	@Router			/auth/{provider} [get]
+func (s *server) handleAuthProvider(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	if _, err := goth.GetProvider(provider); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: "provider not found"})
+		return
+	}
+
+	if !s.saveReturnTo(w, r) {
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
 	gothic.BeginAuthHandler(w, r)
 }
 
-// handleAuthGoogleCallback godoc
+// handleAuthLinkProvider godoc
 //
-//	@Summary		Google auth callback url
-//	@Description	Google auth callback url
+//	@Summary		Link an oauth provider
+//	@Description	Link an oauth provider identity to the signed in account
 //	@Tags			auth
-//	@Failure		404	{object}	errorResponse
-//	@Failure		404	{object}	errorResponse
-func (s *server) handleAuthGoogleCallback(w http.ResponseWriter, r *http.Request) {
-	r = r.WithContext(context.WithValue(r.Context(), "provider", "google"))
+//	@Param			provider	path		string	true	"provider (google, github, discord)"
+//	@Param			returnTo	query		string	false	"frontend url to return to after linking"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Success		302
+//	@Success		307
+//	@Router			/auth/{provider}/link [get]
+func (s *server) handleAuthLinkProvider(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	if _, err := goth.GetProvider(provider); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: "provider not found"})
+		return
+	}
+
+	if !s.saveReturnTo(w, r) {
+		return
+	}
+
+	session, _ := gothic.Store.Get(r, "app_session")
+	session.Values["link_user_id"] = r.Context().Value("user").(string)
+	if err := session.Save(r, w); err != nil {
+		s.logger.Error(fmt.Sprintf("error saving session, %v", err))
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
+	gothic.BeginAuthHandler(w, r)
+}
+
+// handleAuthReauthProvider godoc
+//
+//	@Summary		Sign in again with an oauth provider
+//	@Description	Sign in again with a provider linked to the signed in account. Users without a password do this before changing their password or deleting their account, the confirmation lasts five minutes and is used up by the next of those requests.
+//	@Tags			auth
+//	@Param			provider	path		string	true	"provider (google, github, discord)"
+//	@Param			returnTo	query		string	false	"frontend url to return to after signing in"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Success		302
+//	@Success		307
+//	@Router			/auth/{provider}/reauth [get]
+func (s *server) handleAuthReauthProvider(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	if _, err := goth.GetProvider(provider); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: "provider not found"})
+		return
+	}
+
+	if !s.saveReturnTo(w, r) {
+		return
+	}
+
+	session, _ := gothic.Store.Get(r, "app_session")
+	session.Values["reauth_user_id"] = r.Context().Value("user").(string)
+	if err := session.Save(r, w); err != nil {
+		s.logger.Error(fmt.Sprintf("error saving session, %v", err))
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
+	gothic.BeginAuthHandler(w, r)
+}
+
+// handleAuthProviderCallback godoc
+//
+//	@Summary		Oauth provider callback url
+//	@Description	Oauth provider callback url. Users with two factor authentication are redirected to the frontend /2fa page with a short lived challenge_token cookie to verify.
+//	@Tags			auth
+//	@Param			provider	path		string	true	"provider (google, github, discord)"
+//	@Failure		403			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Router			/auth/{provider}/callback [get]
+func (s *server) handleAuthProviderCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
 
 	user, err := gothic.CompleteUserAuth(w, r)
 
@@ -47,27 +210,183 @@ func (s *server) handleAuthGoogleCallback(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	id, err := s.checkIfUserExists(r.Context(), user.Email)
+	identity := &identity{provider: provider, providerUserID: user.UserID, email: user.Email}
+	session, _ := gothic.Store.Get(r, "app_session")
 
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	if reauthUserID, ok := session.Values["reauth_user_id"].(string); ok && reauthUserID != "" {
+		delete(session.Values, "reauth_user_id")
+		session.Save(r, w)
+
+		id, err := s.getUserByIdentity(r.Context(), provider, user.UserID)
+		if err != nil && !errors.Is(err, errIdentityNotFound) {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if id != reauthUserID {
+			encode(w, http.StatusForbidden, &errorResponse{Error: "provider account isn't linked to you"})
+			return
+		}
+
+		if err := s.markReauthenticated(r.Context(), id); err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		s.redirectAfterAuth(w, r)
+		return
+	}
+
+	if linkUserID, ok := session.Values["link_user_id"].(string); ok && linkUserID != "" {
+		delete(session.Values, "link_user_id")
+		session.Save(r, w)
+
+		if err := s.linkIdentity(r.Context(), linkUserID, identity); err != nil {
+			if errors.Is(err, errIdentityAlreadyLinked) {
+				encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+				return
+			}
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		s.redirectAfterAuth(w, r)
+		return
+	}
+
+	s.providerSignIn(w, r, provider, user)
+}
+
+// providerSignIn signs in the account the provider identity belongs to, or the
+// account with the same verified email. Users with two factor authentication
+// are sent to the frontend with a challenge token instead of being signed in,
+// and users without an account go through onboarding.
+func (s *server) providerSignIn(w http.ResponseWriter, r *http.Request, provider string, user goth.User) {
+	identity := &identity{provider: provider, providerUserID: user.UserID, email: user.Email}
+
+	id, err := s.getUserByIdentity(r.Context(), provider, user.UserID)
+	if err != nil && !errors.Is(err, errIdentityNotFound) {
 		s.logger.Error(err.Error())
-		encode(w, http.StatusInternalServerError, "internal server error")
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
 
+	if id == "" {
+		verified, err := providerEmailVerified(r.Context(), user)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if !verified {
+			encode(w, http.StatusForbidden, &errorResponse{Error: "email not verified by provider, sign in another way and link this provider from your account"})
+			return
+		}
+
+		id, err = s.checkIfUserExists(r.Context(), user.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if id != "" {
+			if err := s.linkIdentity(r.Context(), id, identity); err != nil {
+				if errors.Is(err, errIdentityAlreadyLinked) {
+					encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+					return
+				}
+				s.logger.Error(err.Error())
+				encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+				return
+			}
+		}
+	}
+
 	if id != "" {
+		tf, err := s.getTwoFactor(r.Context(), id)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if tf.enabled {
+			challengeToken, err := createChallengeToken(id)
+			if err != nil {
+				s.logger.Error(err.Error())
+				encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+				return
+			}
+
+			setChallengeCookie(w, challengeToken)
+			frontendRedirect(w, r, "/2fa")
+			return
+		}
+
 		if err := createAccessAndRefreshTokens(w, id); err != nil {
 			s.logger.Error(err.Error())
 			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 			return
 		}
+		s.redirectAfterAuth(w, r)
 		return
 	}
 
 	session, _ := gothic.Store.Get(r, "app_session")
 	session.Values["user_email"] = user.Email
+	session.Values["provider"] = provider
+	session.Values["provider_user_id"] = user.UserID
 	session.Save(r, w)
-	http.Redirect(w, r, "/healthz", http.StatusFound) //TODO: put a proper redirect link here when there's a frontend
+	frontendRedirect(w, r, "/onboarding")
+}
+
+// saveReturnTo keeps a validated returnTo query param in the session for
+// when the oauth flow comes back. It writes the error response itself.
+func (s *server) saveReturnTo(w http.ResponseWriter, r *http.Request) bool {
+	raw := r.URL.Query().Get("returnTo")
+	if raw == "" {
+		return true
+	}
+
+	returnTo, ok := validateReturnTo(raw)
+	if !ok {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "returnTo is not an allowed redirect url"})
+		return false
+	}
+
+	session, _ := gothic.Store.Get(r, "app_session")
+	session.Values["return_to"] = returnTo
+	if err := session.Save(r, w); err != nil {
+		s.logger.Error(fmt.Sprintf("error saving session, %v", err))
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return false
+	}
+
+	return true
+}
+
+// popReturnTo returns where the user should end up once signed in, falling
+// back to the frontend home page
+func popReturnTo(w http.ResponseWriter, r *http.Request) string {
+	session, _ := gothic.Store.Get(r, "app_session")
+
+	returnTo, ok := session.Values["return_to"].(string)
+	if !ok || returnTo == "" {
+		return frontendURL()
+	}
+
+	delete(session.Values, "return_to")
+	session.Save(r, w)
+	return returnTo
+}
+
+func (s *server) redirectAfterAuth(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, popReturnTo(w, r), http.StatusFound)
 }
 
 // handleAuthOnboarding godoc
@@ -82,6 +401,7 @@ func (s *server) handleAuthGoogleCallback(w http.ResponseWriter, r *http.Request
 //	@Param			image			formData	file	false	"profile_picture"
 //	@Failure		400				{object}	errorResponse
 //	@Failure		404				{object}	errorResponse
+//	@Failure		409				{object}	errorResponse
 //	@Failure		413				{object}	errorResponse
 //	@Failure		500				{object}	errorResponse
 //	@Success		201				{object}	main.handleAuthOnboarding.response
@@ -94,7 +414,8 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type response struct {
-		Id string `json:"id"`
+		Id       string `json:"id"`
+		ReturnTo string `json:"returnTo"`
 	}
 
 	session, _ := gothic.Store.Get(r, "app_session")
@@ -115,7 +436,7 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 	defer r.MultipartForm.RemoveAll()
 
 	params := request{
-		displayName: r.FormValue("display_name"),
+		displayName: strings.TrimSpace(r.FormValue("display_name")),
 		about:       r.FormValue("about"),
 	}
 
@@ -124,6 +445,11 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateDisplayName(params.displayName); err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
 	file, header, err := r.FormFile("image")
 
 	if err != nil && err != http.ErrMissingFile {
@@ -186,8 +512,9 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 		image:       image,
 	})
 
-	if errors.Is(err, errUserExists) {
+	if errors.Is(err, errUserExists) || errors.Is(err, errDisplayNameTaken) {
 		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+		return
 	}
 
 	if err != nil {
@@ -196,8 +523,24 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if provider, ok := session.Values["provider"].(string); ok {
+		if err := s.linkIdentity(r.Context(), id, &identity{provider: provider, providerUserID: session.Values["provider_user_id"].(string), email: email}); err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+	}
+
+	returnTo, ok := session.Values["return_to"].(string)
+	if !ok || returnTo == "" {
+		returnTo = frontendURL()
+	}
+
 	delete(session.Values, "user_email")
 	delete(session.Values, "user_password")
+	delete(session.Values, "provider")
+	delete(session.Values, "provider_user_id")
+	delete(session.Values, "return_to")
 
 	session.Options.MaxAge = -1
 
@@ -213,7 +556,104 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	encode(w, http.StatusCreated, response{Id: id})
+	encode(w, http.StatusCreated, response{Id: id, ReturnTo: returnTo})
+}
+
+var errInvalidDisplayName = errors.New("display name must be between 3 and 30 characters and only contain letters, numbers, spaces, '.', '_' or '-'")
+
+func validateDisplayName(name string) error {
+	if n := utf8.RuneCountInString(name); n < 3 || n > 30 {
+		return errInvalidDisplayName
+	}
+
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" ._-", c) {
+			return errInvalidDisplayName
+		}
+	}
+
+	return nil
+}
+
+// handleAuthOnboardingStatus godoc
+//
+//	@Summary		Onboarding status
+//	@Description	Tells the frontend whether the current session still has to complete onboarding and which fields are needed
+//	@Tags			auth
+//	@Produce		json
+//	@Success		200	{object}	main.handleAuthOnboardingStatus.response
+//	@Router			/auth/onboarding/status [get]
+func (s *server) handleAuthOnboardingStatus(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Pending        bool     `json:"pending"`
+		Email          string   `json:"email,omitempty"`
+		Provider       string   `json:"provider,omitempty"`
+		RequiredFields []string `json:"requiredFields"`
+		OptionalFields []string `json:"optionalFields"`
+		ReturnTo       string   `json:"returnTo"`
+	}
+
+	session, _ := gothic.Store.Get(r, "app_session")
+
+	returnTo, ok := session.Values["return_to"].(string)
+	if !ok || returnTo == "" {
+		returnTo = frontendURL()
+	}
+
+	email, ok := session.Values["user_email"].(string)
+	if !ok || email == "" {
+		encode(w, http.StatusOK, &response{Pending: false, RequiredFields: []string{}, OptionalFields: []string{}, ReturnTo: returnTo})
+		return
+	}
+
+	provider, _ := session.Values["provider"].(string)
+
+	encode(w, http.StatusOK, &response{
+		Pending:        true,
+		Email:          email,
+		Provider:       provider,
+		RequiredFields: []string{"display_name"},
+		OptionalFields: []string{"about", "image"},
+		ReturnTo:       returnTo,
+	})
+}
+
+// handleAuthDisplayNameAvailable godoc
+//
+//	@Summary		Check display name
+//	@Description	Check whether a display name is valid and not taken before submitting onboarding
+//	@Tags			auth
+//	@Produce		json
+//	@Param			name	query		string	true	"display name"
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleAuthDisplayNameAvailable.response
+//	@Router			/auth/onboarding/display-name [get]
+func (s *server) handleAuthDisplayNameAvailable(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Available bool   `json:"available"`
+		Reason    string `json:"reason,omitempty"`
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+
+	if err := validateDisplayName(name); err != nil {
+		encode(w, http.StatusOK, &response{Available: false, Reason: err.Error()})
+		return
+	}
+
+	taken, err := s.checkIfDisplayNameTaken(r.Context(), name)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if taken {
+		encode(w, http.StatusOK, &response{Available: false, Reason: errDisplayNameTaken.Error()})
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Available: true})
 }
 
 // handleRegister godoc
@@ -226,6 +666,7 @@ func (s *server) handleAuthOnboarding(w http.ResponseWriter, r *http.Request) {
 //	@Param			user	body		main.handleAuthRegister.request	true	"user"
 //	@Failure		400		{object}	errorResponse
 //	@Failure		409		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
 //	@Failure		500		{object}	errorResponse
 //	@Success		302
 //	@Router			/auth/register [post]
@@ -270,21 +711,26 @@ func (s *server) handleAuthRegister(w http.ResponseWriter, r *http.Request) {
 	session.Values["user_email"] = user.Email
 	session.Values["user_password"] = string(hash)
 	session.Save(r, w)
-	http.Redirect(w, r, "/healthz", http.StatusFound) //TODO: put a proper redirect link here when there's a frontend
+	frontendRedirect(w, r, "/onboarding")
 }
 
+// dummyPasswordHash is compared against when the email is unknown so that
+// response times don't reveal which accounts exist
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("pagesy dummy password"), bcrypt.DefaultCost)
+
 // handleAuthLogin godoc
 //
 //	@Summary		Login
-//	@Description	Login using either email, or both and password
+//	@Description	Login using either email, or both and password. Users with two factor authentication get a challenge token to complete the login at /auth/2fa/verify.
 //	@Tags			auth
 //	@Accept			appplication/json
 //	@Produce		json
 //	@Param			user	body		main.handleAuthLogin.request	true	"user"
 //	@Failure		400		{object}	errorResponse
 //	@Failure		401		{object}	errorResponse
-//	@Failure		404		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
 //	@Failure		500		{object}	errorResponse
+//	@Success		202		{object}	main.handleAuthLogin.response
 //	@Success		204
 //	@Router			/auth/login [post]
 func (s *server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
@@ -293,6 +739,10 @@ func (s *server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password" validate:"required"`
 	}
 
+	type response struct {
+		ChallengeToken string `json:"challengeToken"`
+	}
+
 	var user request
 
 	if err := decode(r, &user); err != nil {
@@ -304,26 +754,77 @@ func (s *server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := s.checkIfUserExists(r.Context(), user.Email)
-	if errors.Is(err, sql.ErrNoRows) {
-		encode(w, http.StatusNotFound, &errorResponse{Error: "user not found"})
+	allowed, retryAfter, err := s.limiter.allow(r.Context(), fmt.Sprintf("login_account:%s", strings.ToLower(user.Email)), s.limits["login_account"])
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+	if !allowed {
+		writeTooManyRequests(w, retryAfter)
 		return
 	}
+
+	lockout, err := s.getLoginLockout(r.Context(), user.Email)
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
+	if lockout > 0 {
+		writeTooManyRequests(w, lockout)
+		return
+	}
 
-	password, err := s.getUserPassword(r.Context(), id)
+	id, err := s.checkIfUserExists(r.Context(), user.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	password := string(dummyPasswordHash)
+	if id != "" {
+		password, err = s.getUserPassword(r.Context(), id)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(password), []byte(user.Password)); err != nil || id == "" {
+		if err := s.recordLoginFailure(r.Context(), user.Email); err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+		encode(w, http.StatusUnauthorized, &errorResponse{Error: "invalid email or password"})
+		return
+	}
+
+	if err := s.clearLoginFailures(r.Context(), user.Email); err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	tf, err := s.getTwoFactor(r.Context(), id)
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(password), []byte(user.Password)); err != nil {
-		encode(w, http.StatusUnauthorized, &errorResponse{Error: "incorrect password"})
+	if tf.enabled {
+		challengeToken, err := createChallengeToken(id)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		encode(w, http.StatusAccepted, &response{ChallengeToken: challengeToken})
 		return
 	}
 
@@ -344,6 +845,11 @@ func (s *server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 //	@Success		200
 //	@Router			/auth/logout [get]
 func (s *server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
+	clearAuthCookies(w)
+	encode(w, http.StatusNoContent, nil)
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:   "access_token",
 		Value:  "",
@@ -357,15 +863,14 @@ func (s *server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
 		Path:   "/",
 		MaxAge: -1,
 	})
-
-	encode(w, http.StatusNoContent, nil)
 }
 
 // handleAuthRefreshToken godoc
 //
 //	@Summary		Refresh token
-//	@Description	Get new access token
+//	@Description	Get new access token. Refresh tokens of deleted accounts or issued before the user's sessions were revoked are refused.
 //	@Tags			auth
+//	@Failure		400	{object}	errorResponse
 //	@Failure		401	{object}	errorResponse
 //	@Failure		404	{object}	errorResponse
 //	@Failure		500	{object}	errorResponse
@@ -382,12 +887,25 @@ func (s *server) handleAuthRefreshToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	id, err := decodeJWTToken(token.Value)
+	id, issuedAt, err := decodeSessionToken(token.Value)
 	if err != nil {
 		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
 		return
 	}
 
+	revoked, err := s.sessionRevoked(r.Context(), id, issuedAt)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if revoked {
+		clearAuthCookies(w)
+		encode(w, http.StatusUnauthorized, &errorResponse{Error: "session revoked, sign in again"})
+		return
+	}
+
 	access_token, err := createJWTToken(id, 24*time.Hour)
 	if err != nil {
 		s.logger.Error(err.Error())
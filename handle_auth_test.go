@@ -2,11 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/markbates/goth"
 )
 
 func TestHandleAuthRegister(t *testing.T) {
@@ -89,7 +95,7 @@ func TestHandleAuthLogin(t *testing.T) {
 		{
 			name:         "user not found",
 			body:         request{Email: "notfound@notfound.com", Password: "123"},
-			expectedCode: http.StatusNotFound,
+			expectedCode: http.StatusUnauthorized,
 		},
 		{
 			name:         "incorrect password",
@@ -119,6 +125,152 @@ func TestHandleAuthLogin(t *testing.T) {
 	}
 }
 
+func TestHandleAuthLoginRateLimit(t *testing.T) {
+	type request struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	db := connectTestDb(t)
+	createAndCleanUpUser(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	svr.limits["login"] = rateLimit{requests: 2, per: time.Minute}
+
+	expectedCodes := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+
+	for i, expectedCode := range expectedCodes {
+		payload, _ := json.Marshal(request{Email: "notfound@notfound.com", Password: "incorrect"})
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(payload))
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != expectedCode {
+			t.Fatalf("attempt %d: expected %d, got %d", i+1, expectedCode, rr.Code)
+		}
+	}
+}
+
+func TestLoginLockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 1, expected: 0},
+		{failures: maxLoginFailures - 1, expected: 0},
+		{failures: maxLoginFailures, expected: time.Minute},
+		{failures: maxLoginFailures + 1, expected: 2 * time.Minute},
+		{failures: maxLoginFailures + 3, expected: 8 * time.Minute},
+		{failures: maxLoginFailures + 100, expected: 24 * time.Hour},
+	}
+
+	for _, tc := range tests {
+		if got := loginLockoutDuration(tc.failures); got != tc.expected {
+			t.Fatalf("%d failures: expected %v, got %v", tc.failures, tc.expected, got)
+		}
+	}
+}
+
+func TestRecordLoginFailureDecay(t *testing.T) {
+	db := connectTestDb(t)
+	svr := newServer(nil, db, nil, nil)
+	email := "decay@test.com"
+
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), `DELETE FROM login_attempts WHERE email = $1;`, email); err != nil {
+			t.Errorf("error deleting login attempts, %v", err)
+		}
+	})
+
+	query :=
+		`
+			INSERT INTO login_attempts (email, failures, locked_until, updated_at)
+			VALUES ($1, $2, NOW() - make_interval(secs => $3), NOW() - make_interval(secs => $3));
+		`
+	if _, err := db.ExecContext(context.Background(), query, email, maxLoginFailures+2, (loginFailureWindow + time.Hour).Seconds()); err != nil {
+		t.Fatalf("error inserting login attempts, %v", err)
+	}
+
+	if err := svr.recordLoginFailure(context.Background(), email); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var failures int
+	if err := db.QueryRowContext(context.Background(), `SELECT failures FROM login_attempts WHERE email = $1;`, email).Scan(&failures); err != nil {
+		t.Fatalf("error getting login attempts, %v", err)
+	}
+
+	if failures != 1 {
+		t.Fatalf("expected failures to start over, got %d", failures)
+	}
+}
+
+func TestProviderEmailVerified(t *testing.T) {
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer github_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"email":"test@test.com","primary":true,"verified":true},{"email":"public@test.com","primary":false,"verified":false}]`))
+	}))
+	defer github.Close()
+
+	emailsURL := githubEmailsURL
+	githubEmailsURL = github.URL
+	defer func() { githubEmailsURL = emailsURL }()
+
+	tests := []struct {
+		name   string
+		user   goth.User
+		expect bool
+	}{
+		{
+			name:   "google verified",
+			user:   goth.User{Provider: "google", Email: "test@test.com", RawData: map[string]any{"verified_email": true}},
+			expect: true,
+		},
+		{
+			name:   "google unverified",
+			user:   goth.User{Provider: "google", Email: "test@test.com", RawData: map[string]any{"verified_email": false}},
+			expect: false,
+		},
+		{
+			name:   "github primary verified",
+			user:   goth.User{Provider: "github", Email: "test@test.com", AccessToken: "github_token"},
+			expect: true,
+		},
+		{
+			name:   "github public profile email",
+			user:   goth.User{Provider: "github", Email: "public@test.com", AccessToken: "github_token"},
+			expect: false,
+		},
+		{
+			name:   "discord unverified",
+			user:   goth.User{Provider: "discord", Email: "test@test.com", RawData: map[string]any{"verified": false}},
+			expect: false,
+		},
+		{
+			name:   "unknown provider",
+			user:   goth.User{Provider: "unknown", Email: "test@test.com"},
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := providerEmailVerified(context.Background(), tc.user)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if got != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
 func TestHandleAuthLogout(t *testing.T) {
 	r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
 	rr := httptest.NewRecorder()
@@ -182,3 +334,172 @@ func TestHandleAuthRefreshToken(t *testing.T) {
 		t.Fatal("expected access token")
 	}
 }
+
+func TestValidateDisplayName(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect bool
+	}{
+		{name: "test_user", expect: true},
+		{name: "Jane Doe", expect: true},
+		{name: "ab", expect: false},
+		{name: "a very long display name that goes on", expect: false},
+		{name: "bad\nname", expect: false},
+		{name: "<script>", expect: false},
+	}
+
+	for _, tc := range tests {
+		if got := validateDisplayName(tc.name) == nil; got != tc.expect {
+			t.Fatalf("%q: expected %v, got %v", tc.name, tc.expect, got)
+		}
+	}
+}
+
+func TestHandleAuthOnboardingStatus(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/onboarding/status", nil)
+	rr := httptest.NewRecorder()
+
+	svr := newServer(nil, nil, nil, nil)
+	svr.router.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var resp struct {
+		Pending bool `json:"pending"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if resp.Pending {
+		t.Fatal("expected no pending onboarding without a session")
+	}
+}
+
+func TestHandleAuthDisplayNameAvailable(t *testing.T) {
+	db := connectTestDb(t)
+	createAndCleanUpUser(t, db)
+
+	tests := []struct {
+		name      string
+		query     string
+		available bool
+	}{
+		{
+			name:      "invalid name",
+			query:     "ab",
+			available: false,
+		},
+		{
+			name:      "taken name",
+			query:     "TEST_DISPLAY",
+			available: false,
+		},
+		{
+			name:      "available name",
+			query:     "available_name",
+			available: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/onboarding/display-name?name="+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+			}
+
+			var resp struct {
+				Available bool `json:"available"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if resp.Available != tc.available {
+				t.Fatalf("expected %v, got %v", tc.available, resp.Available)
+			}
+		})
+	}
+}
+
+func TestCreateUserDisplayNameCase(t *testing.T) {
+	db := connectTestDb(t)
+	createAndCleanUpUser(t, db)
+
+	t.Cleanup(func() {
+		db.ExecContext(context.Background(), `DELETE FROM users WHERE email = 'case@test.com';`)
+	})
+
+	svr := newServer(nil, db, nil, nil)
+	_, err := svr.createUser(context.Background(), &user{displayName: "TEST_DISPLAY", email: "case@test.com"})
+	if !errors.Is(err, errDisplayNameTaken) {
+		t.Fatalf("expected %v, got %v", errDisplayNameTaken, err)
+	}
+}
+
+func TestProviderSignInTwoFactor(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	if err := svr.linkIdentity(context.Background(), id, &identity{provider: "github", providerUserID: "12345", email: "test@test.com"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	secret, _ := generateTOTPSecret()
+	if err := svr.setTOTPSecret(context.Background(), id, secret); err != nil {
+		t.Fatal(err.Error())
+	}
+	recoveryCodes, _ := generateRecoveryCodes()
+	if err := svr.enableTwoFactor(context.Background(), id, recoveryCodes); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/auth/github/callback", nil)
+	rr := httptest.NewRecorder()
+
+	svr.providerSignIn(rr, r, "github", goth.User{Provider: "github", UserID: "12345", Email: "test@test.com"})
+
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected %d, got %d", http.StatusFound, rr.Code)
+	}
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "access_token" || c.Name == "refresh_token" {
+			t.Fatalf("expected no %s cookie before the second factor", c.Name)
+		}
+	}
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasSuffix(location.Path, "/2fa") {
+		t.Fatalf("expected a redirect to /2fa, got %s", location)
+	}
+
+	if location.RawQuery != "" {
+		t.Fatalf("expected no query on the redirect, got %s", location.RawQuery)
+	}
+
+	var challengeToken string
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "challenge_token" && c.HttpOnly {
+			challengeToken = c.Value
+		}
+	}
+
+	challengedID, err := decodeChallengeToken(challengeToken)
+	if err != nil || challengedID != id {
+		t.Fatalf("expected a challenge token for %s, got %s, %v", id, challengedID, err)
+	}
+}
@@ -25,16 +25,21 @@ import (
 //	@Param			name						formData	string		true	"name"
 //	@Param			description					formData	string		true	"description"
 //	@Param			genres						formData	[]string	true	"genre"
-//	@Param			language					formData	string		true	"language"
+//	@Param			language					formData	string		true	"BCP 47 language code (e.g. en)"
 //	@Param			chapter_title				formData	string		true	"draft chapter title"
 //	@Param			chapter_content				formData	string		true	"draft chapter content"
 //	@Param			release_schedule_day		formData	[]string	true	"release days (e.g. Monday, Tuesday)"
 //	@Param			release_schedule_chapter	formData	[]int		true	"chapters per day (e.g. 1, 2)"
 //	@Param			book_cover					formData	file		false	"book cover image (max 3MB)"
+//	@Param			source_book_id				formData	string		false	"book this one translates"
+//	@Param			tags						formData	[]string	false	"tags (at most 10)"
+//	@Param			content_rating				formData	string		true	"content rating"	Enums(everyone, teen, mature)
+//	@Param			content_warnings			formData	[]string	false	"content warnings (violence, gore, sexual content, self harm, substance abuse, strong language, abuse)"
 //	@Failure		400							{object}	errorResponse
 //	@Failure		409							{object}	errorResponse
 //	@Failure		413							{object}	errorResponse
 //	@Failure		404							{object}	errorResponse
+//	@Failure		429							{object}	errorResponse
 //	@Failure		500							{object}	errorResponse
 //	@Success		201							{object}	main.handleUploadBook.response
 //	@Router			/books [post]
@@ -48,7 +53,9 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 		Name            string `validate:"required"`
 		Description     string `validate:"required"`
 		Genres          string `validate:"required"`
-		Language        string `validate:"required"`
+		Language        string `validate:"required,bcp47_language_tag"`
+		SourceBookId    string `validate:"omitempty,uuid"`
+		ContentRating   string `validate:"required,oneof=everyone teen mature"`
 		ReleaseSchedule []requestReleaseSchedule
 		DraftChapter    draftChapter
 	}
@@ -68,10 +75,12 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 	defer r.MultipartForm.RemoveAll()
 
 	params := request{
-		Name:        r.FormValue("name"),
-		Description: r.FormValue("description"),
-		Genres:      r.FormValue("genres"),
-		Language:    r.FormValue("language"),
+		Name:          r.FormValue("name"),
+		Description:   r.FormValue("description"),
+		Genres:        r.FormValue("genres"),
+		Language:      r.FormValue("language"),
+		SourceBookId:  r.FormValue("source_book_id"),
+		ContentRating: r.FormValue("content_rating"),
 		DraftChapter: draftChapter{
 			Title:   r.FormValue("chapter_title"),
 			Content: r.FormValue("chapter_content"),
@@ -108,6 +117,18 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags, err := normalizeTags(strings.Split(r.FormValue("tags"), ","))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	warnings, err := normalizeContentWarnings(strings.Split(r.FormValue("content_warnings"), ","))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
 	var schedule []releaseSchedule
 
 	for _, rs := range params.ReleaseSchedule {
@@ -119,12 +140,16 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 		description: params.Description,
 		authorID:    userID,
 		genres:      strings.Split(params.Genres, ","),
+		tags:        tags,
 		draftChapter: draftChapter{
 			Title:   params.DraftChapter.Title,
 			Content: params.DraftChapter.Content,
 		},
 		language:        params.Language,
 		releaseSchedule: schedule,
+		sourceBookID:    params.SourceBookId,
+		contentRating:   params.ContentRating,
+		contentWarnings: warnings,
 	})
 
 	if errors.Is(err, errBookNameAlreadyTaken) {
@@ -132,17 +157,24 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if errors.Is(err, errGenresNotFound) {
+	if errors.Is(err, errGenresNotFound) || errors.Is(err, errLanguageNotFound) || errors.Is(err, errSourceBookNotFound) {
 		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 		return
 	}
 
+	if errors.Is(err, errTranslationLanguage) {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
 
+	s.queueFingerprint(r.Context(), bookID)
+
 	file, header, err := r.FormFile("book_cover")
 
 	if err != nil && !errors.Is(err, http.ErrMissingFile) {
@@ -184,7 +216,9 @@ func (s *server) handleUploadBook(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	s.hub.broadcast <- &event{Type: NEW_BOOK, Payload: fmt.Sprintf("%v waiting for approval", params.Name)}
+	if err := s.publishEvent(r.Context(), newEvent(newBookEvent{BookId: bookID, Message: fmt.Sprintf("%v waiting for approval", params.Name)})); err != nil {
+		s.logger.Error(err.Error())
+	}
 
 	encode(w, http.StatusCreated, &response{Id: bookID})
 }
@@ -194,6 +228,20 @@ type responseReleaseSchedule struct {
 	Chapters int    `json:"chapters"`
 }
 
+type responseSeries struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Volume  int    `json:"volume"`
+	Volumes int    `json:"volumes"`
+}
+
+func seriesResponse(sv *seriesVolume) *responseSeries {
+	if sv == nil {
+		return nil
+	}
+	return &responseSeries{Id: sv.seriesID, Name: sv.seriesName, Volume: sv.volume, Volumes: sv.volumes}
+}
+
 type getResponseBook struct {
 	Name            string                    `json:"name"`
 	Description     string                    `json:"description"`
@@ -202,7 +250,11 @@ type getResponseBook struct {
 	Rating          float32                   `json:"rating"`
 	ChapterCount    int                       `json:"chapterCount"`
 	Genres          []string                  `json:"genres"`
+	Tags            []string                  `json:"tags"`
+	ContentRating   string                    `json:"contentRating"`
+	ContentWarnings []string                  `json:"contentWarnings"`
 	ReleaseSchedule []responseReleaseSchedule `json:"releaseSchedule"`
+	Series          *responseSeries           `json:"series"`
 }
 
 func mapToGetBooks(books []book) []getResponseBook {
@@ -214,7 +266,7 @@ func mapToGetBooks(books []book) []getResponseBook {
 			image = &book.image.String
 		}
 
-		newBook := getResponseBook{Name: book.name, Description: book.description, Image: image, Views: book.views, Rating: book.rating, ChapterCount: book.chapterCount, Genres: book.genres}
+		newBook := getResponseBook{Name: book.name, Description: book.description, Image: image, Views: book.views, Rating: book.rating, ChapterCount: book.chapterCount, Genres: book.genres, Tags: book.tags, ContentRating: book.contentRating, ContentWarnings: append([]string{}, book.contentWarnings...), Series: seriesResponse(book.series)}
 
 		for _, rs := range book.releaseSchedule {
 			newBook.ReleaseSchedule = append(newBook.ReleaseSchedule, responseReleaseSchedule{Day: rs.Day, Chapters: rs.Chapters})
@@ -229,11 +281,12 @@ func mapToGetBooks(books []book) []getResponseBook {
 // handleGetbooks godoc
 //
 //	@Summary		Get all books
-//	@Description	Get all books
+//	@Description	Get all books. Mature books are left out unless the signed in reader is 18 or over and turned them on, books with warnings the reader hid are left out too.
 //	@Tags			books
 //	@Produce		json
 //	@Param			genre		query		string	false	"genre"
-//	@Param			language	query		string	false	"language"
+//	@Param			language	query		string	false	"BCP 47 language code"
+//	@Param			tag			query		[]string	false	"tags the books all have"
 //	@Param			sort		query		string	false	"sort"
 //	@Param			order		query		string	false	"order"
 //	@Param			offset		query		string	true	"offset"
@@ -272,8 +325,33 @@ func (s *server) handleGetBooks(w http.ResponseWriter, r *http.Request) {
 		order = "desc"
 	}
 
+	userID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		for i := range tags {
+			tags[i] = normalizeTag(tags[i])
+		}
+
+		books, err := s.getBooksByTags(r.Context(), tags, genre, language, filter, offset, limit, sort, order)
+		if err != nil && !errors.Is(err, errNoBooksUnderTags) {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		encode(w, http.StatusOK, &response{Books: mapToGetBooks(books)})
+		return
+	}
+
 	if len(genre) > 0 && len(language) < 1 {
-		books, err := s.getBooksByGenre(r.Context(), genre, offset, limit, sort, order)
+		books, err := s.getBooksByGenre(r.Context(), genre, filter, offset, limit, sort, order)
 		if err != nil && !errors.Is(err, errNoBooksUnderGenre) {
 			s.logger.Error(err.Error())
 			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -285,7 +363,7 @@ func (s *server) handleGetBooks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(genre) < 1 && len(language) > 0 {
-		books, err := s.getBooksByLanguage(r.Context(), language, offset, limit, sort, order)
+		books, err := s.getBooksByLanguage(r.Context(), language, filter, offset, limit, sort, order)
 		if err != nil && !errors.Is(err, errNoBooksUnderLanguage) {
 			s.logger.Error(err.Error())
 			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -297,7 +375,7 @@ func (s *server) handleGetBooks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(genre) > 0 && len(language) > 0 {
-		books, err := s.getBooksByGenreAndLanguage(r.Context(), genre, language, offset, limit, sort, order)
+		books, err := s.getBooksByGenreAndLanguage(r.Context(), genre, language, filter, offset, limit, sort, order)
 		if err != nil && !errors.Is(err, errNoBooksUnderGenreAndLanguage) {
 			s.logger.Error(err.Error())
 			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -308,7 +386,7 @@ func (s *server) handleGetBooks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	books, err := s.getAllBooks(r.Context(), offset, limit, sort, order)
+	books, err := s.getAllBooks(r.Context(), filter, offset, limit, sort, order)
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -472,6 +550,7 @@ func (s *server) handleGetRecentlyReadBooks(w http.ResponseWriter, r *http.Reque
 //	@Param			offset	query		string	true	"offset"
 //	@Param			limit	query		string	true	"limit"
 //	@Failure		400		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
 //	@Failure		500		{object}	errorResponse
 //	@Success		200		{object}	main.handleGetRecentlyUploadedBooks.response
 //	@Router			/books/recently-uploaded [get]
@@ -486,26 +565,6 @@ func (s *server) handleGetRecentlyUploadedBooks(w http.ResponseWriter, r *http.R
 		Books []responseBooks `json:"books"`
 	}
 
-	user, err := s.getUser(r.Context(), r.Context().Value("user").(string))
-	if err != nil {
-		s.logger.Error(err.Error())
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
-		return
-	}
-
-	var isAdmin bool
-	for _, role := range user.roles {
-		if role == "ADMIN" {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		encode(w, http.StatusUnauthorized, &errorResponse{Error: "role is not admin"})
-		return
-	}
-
 	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
 	if err != nil {
 		encode(w, http.StatusBadRequest, &errorResponse{Error: "offset should be a valid number"})
@@ -560,6 +619,13 @@ func (s *server) handleGetBook(w http.ResponseWriter, r *http.Request) {
 		Chapters int    `validate:"required"`
 	}
 
+	type bookCredit struct {
+		UserId       string `json:"userId"`
+		DisplayName  string `json:"displayName"`
+		Role         string `json:"role"`
+		RevenueShare int    `json:"revenueShare"`
+	}
+
 	type response struct {
 		Name             string                `json:"name"`
 		Description      string                `json:"description"`
@@ -567,10 +633,16 @@ func (s *server) handleGetBook(w http.ResponseWriter, r *http.Request) {
 		Views            int                   `json:"views"`
 		Rating           float32               `json:"rating"`
 		Genres           []string              `json:"genres"`
+		Tags             []string              `json:"tags"`
+		ContentRating    string                `json:"contentRating"`
+		ContentWarnings  []string              `json:"contentWarnings"`
 		Completed        bool                  `json:"completed"`
 		ChapterCount     int                   `json:"chapterCount"`
 		Chapters         []chaptersBookPreview `json:"chapters"`
 		Release_schedule []releaseSchedule     `json:"release_schedule"`
+		Credits          []bookCredit          `json:"credits"`
+		Translations     []translationResponse `json:"translations"`
+		Series           *responseSeries       `json:"series"`
 	}
 
 	book, err := s.getBook(r.Context(), chi.URLParam(r, "bookID"))
@@ -599,7 +671,28 @@ func (s *server) handleGetBook(w http.ResponseWriter, r *http.Request) {
 		schedule = append(schedule, releaseSchedule{Day: rs.Day, Chapters: rs.Chapters})
 	}
 
-	encode(w, http.StatusOK, &response{Name: book.name, Description: book.description, Image: image, Views: book.views, Rating: book.rating, Genres: book.genres, Completed: book.completed, ChapterCount: book.chapterCount, Chapters: chaptersPreviews, Release_schedule: schedule})
+	userID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	translations, err := s.getTranslations(r.Context(), book.id, nil, filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	credits := []bookCredit{}
+	for _, c := range book.credits {
+		credits = append(credits, bookCredit{UserId: c.userID, DisplayName: c.displayName, Role: c.role, RevenueShare: c.revenueShare})
+	}
+
+	encode(w, http.StatusOK, &response{Name: book.name, Description: book.description, Image: image, Views: book.views, Rating: book.rating, Genres: book.genres, Tags: book.tags, ContentRating: book.contentRating, ContentWarnings: append([]string{}, book.contentWarnings...), Completed: book.completed, ChapterCount: book.chapterCount, Chapters: chaptersPreviews, Release_schedule: schedule, Credits: credits, Translations: translationsResponse(translations), Series: seriesResponse(book.series)})
 }
 
 // handleDeleteBook
@@ -639,10 +732,14 @@ func (s *server) handleDeleteBook(w http.ResponseWriter, r *http.Request) {
 //	@Param			release_schedule_day		formData	[]string	false	"Release days (e.g. Monday, Tuesday)"
 //	@Param			release_schedule_chapter	formData	[]int		false	"Chapters per day (e.g. 1, 2)"
 //	@Param			genres						formData	[]string	false	"genres"
+//	@Param			tags						formData	[]string	false	"tags (at most 10), replaces the book's tags when sent"
+//	@Param			content_rating				formData	string		false	"content rating"	Enums(everyone, teen, mature)
+//	@Param			content_warnings			formData	[]string	false	"content warnings, replaces the book's warnings when sent"
 //	@Param			book_cover					formData	file		false	"book cover"
 //	@Failure		400							{object}	errorResponse
 //	@Failure		404							{object}	errorResponse
 //	@Failure		413							{object}	errorResponse
+//	@Failure		429							{object}	errorResponse
 //	@Failure		500							{object}	errorResponse
 //	@Success		204
 //	@Router			/books/{bookID} [patch]
@@ -678,6 +775,32 @@ func (s *server) handleEditBook(w http.ResponseWriter, r *http.Request) {
 		genres = []string{}
 	}
 
+	var tags []string
+	if _, ok := r.MultipartForm.Value["tags"]; ok {
+		var err error
+		tags, err = normalizeTags(strings.Split(r.FormValue("tags"), ","))
+		if err != nil {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	rating := r.FormValue("content_rating")
+	if err := validate.Var(rating, "omitempty,oneof=everyone teen mature"); err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "content_rating should be one of everyone, teen or mature"})
+		return
+	}
+
+	var warnings []string
+	if _, ok := r.MultipartForm.Value["content_warnings"]; ok {
+		var err error
+		warnings, err = normalizeContentWarnings(strings.Split(r.FormValue("content_warnings"), ","))
+		if err != nil {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+			return
+		}
+	}
+
 	file, header, err := r.FormFile("book_cover")
 
 	var url string
@@ -722,12 +845,15 @@ func (s *server) handleEditBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	book := &book{
-		id:          bookID,
-		name:        r.FormValue("name"),
-		description: r.FormValue("description"),
-		genres:      genres,
-		authorID:    r.Context().Value("user").(string),
-		image:       image,
+		id:              bookID,
+		name:            r.FormValue("name"),
+		description:     r.FormValue("description"),
+		genres:          genres,
+		tags:            tags,
+		contentRating:   rating,
+		contentWarnings: warnings,
+		authorID:        r.Context().Value("user").(string),
+		image:           image,
 	}
 
 	if len(days) > 0 && len(chapters) > 0 {
@@ -750,69 +876,7 @@ func (s *server) handleEditBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.editBook(r.Context(), book); err != nil {
-		if errors.Is(err, errBookNotFound) {
-			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
-			return
-		}
-
-		s.logger.Error(err.Error())
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
-		return
-	}
-
-	encode(w, http.StatusNoContent, nil)
-}
-
-// handleApproveBook godoc
-//
-//	@Summary		Approve book
-//	@Description	Approve book
-//	@Tags			books
-//	@Param			bookID	path		string							true	"book id"
-//	@Param			param	body		main.handleApproveBook.request	true	"approve book body"
-//	@Failure		400		{object}	errorResponse
-//	@Failure		401		{object}	errorResponse
-//	@Failure		404		{object}	errorResponse
-//	@Failure		500		{object}	errorResponse
-//	@Success		204
-//	@Router			/books/{bookID}/approve [patch]
-func (s *server) handleApproveBook(w http.ResponseWriter, r *http.Request) {
-	type request struct {
-		Approve bool `json:"approve" validate:"required"`
-	}
-
-	user, err := s.getUser(r.Context(), r.Context().Value("user").(string))
-	if err != nil {
-		s.logger.Error(err.Error())
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
-		return
-	}
-
-	var isAdmin bool
-	for _, role := range user.roles {
-		if role == "ADMIN" {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		encode(w, http.StatusUnauthorized, &errorResponse{Error: "role is not admin"})
-		return
-	}
-
-	var params request
-	if err := decode(r, &params); err != nil {
-		if errors.Is(err, errValidation) {
-			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
-			return
-		}
-		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
-		return
-	}
-
-	if err := s.approveBook(r.Context(), chi.URLParam(r, "bookID"), params.Approve); err != nil {
-		if errors.Is(err, errBookNotFound) {
+		if errors.Is(err, errBookNotFound) || errors.Is(err, errGenresNotFound) {
 			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 			return
 		}
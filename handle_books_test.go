@@ -58,7 +58,8 @@ func TestHandleUploadBook(t *testing.T) {
 				"name":                     "test book",
 				"description":              "test book description",
 				"genres":                   "Fantasy",
-				"language":                 "English",
+				"language":                 "en",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday, Monday",
 				"release_schedule_chapter": "2",
 				"chapter_title":            "test chapter title",
@@ -74,7 +75,8 @@ func TestHandleUploadBook(t *testing.T) {
 				"name":                     "test book",
 				"description":              "test book description",
 				"genres":                   "Fantasy",
-				"language":                 "English",
+				"language":                 "en",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday",
 				"release_schedule_chapter": "two",
 				"chapter_title":            "test chapter title",
@@ -89,7 +91,8 @@ func TestHandleUploadBook(t *testing.T) {
 			req: map[string]string{
 				"name":                     "test book",
 				"description":              "test book description",
-				"language":                 "English",
+				"language":                 "en",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday",
 				"release_schedule_chapter": "2",
 				"chapter_title":            "test chapter title",
@@ -105,7 +108,8 @@ func TestHandleUploadBook(t *testing.T) {
 				"name":                     "test book taken",
 				"description":              "test book description",
 				"genres":                   "Fantasy",
-				"language":                 "English",
+				"language":                 "en",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday",
 				"release_schedule_chapter": "2",
 				"chapter_title":            "test chapter title",
@@ -121,7 +125,42 @@ func TestHandleUploadBook(t *testing.T) {
 				"name":                     "test book",
 				"description":              "test book description",
 				"genres":                   "non-existent genre",
+				"language":                 "en",
+				"content_rating":           "teen",
+				"release_schedule_day":     "Sunday",
+				"release_schedule_chapter": "2",
+				"chapter_title":            "test chapter title",
+				"chapter_content":          "test chapter content",
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:        "invalid language code",
+			cookieName:  "access_token",
+			cookieValue: token,
+			req: map[string]string{
+				"name":                     "test book",
+				"description":              "test book description",
+				"genres":                   "Action",
 				"language":                 "English",
+				"content_rating":           "teen",
+				"release_schedule_day":     "Sunday",
+				"release_schedule_chapter": "2",
+				"chapter_title":            "test chapter title",
+				"chapter_content":          "test chapter content",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:        "language not found",
+			cookieName:  "access_token",
+			cookieValue: token,
+			req: map[string]string{
+				"name":                     "test book",
+				"description":              "test book description",
+				"genres":                   "Action",
+				"language":                 "tlh",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday",
 				"release_schedule_chapter": "2",
 				"chapter_title":            "test chapter title",
@@ -129,6 +168,41 @@ func TestHandleUploadBook(t *testing.T) {
 			},
 			expectedCode: http.StatusNotFound,
 		},
+		{
+			name:        "invalid content rating",
+			cookieName:  "access_token",
+			cookieValue: token,
+			req: map[string]string{
+				"name":                     "test book",
+				"description":              "test book description",
+				"genres":                   "Action",
+				"language":                 "en",
+				"content_rating":           "adults only",
+				"release_schedule_day":     "Sunday",
+				"release_schedule_chapter": "2",
+				"chapter_title":            "test chapter title",
+				"chapter_content":          "test chapter content",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:        "unknown content warning",
+			cookieName:  "access_token",
+			cookieValue: token,
+			req: map[string]string{
+				"name":                     "test book",
+				"description":              "test book description",
+				"genres":                   "Action",
+				"language":                 "en",
+				"content_rating":           "mature",
+				"content_warnings":         "violence, spiders",
+				"release_schedule_day":     "Sunday",
+				"release_schedule_chapter": "2",
+				"chapter_title":            "test chapter title",
+				"chapter_content":          "test chapter content",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			name:        "upload book",
 			cookieName:  "access_token",
@@ -137,7 +211,8 @@ func TestHandleUploadBook(t *testing.T) {
 				"name":                     "test book",
 				"description":              "test book description",
 				"genres":                   "Action",
-				"language":                 "English",
+				"language":                 "en",
+				"content_rating":           "teen",
 				"release_schedule_day":     "Sunday",
 				"release_schedule_chapter": "2",
 				"chapter_title":            "test chapter title",
@@ -198,12 +273,12 @@ func TestHandleGetBooks(t *testing.T) {
 		},
 		{
 			name:         "books under language",
-			path:         "/api/v1/books?language=English&offset=1&limit=1",
+			path:         "/api/v1/books?language=en&offset=1&limit=1",
 			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "books under genre and language",
-			path:         "/api/v1/books?genre=Action&language=English&offset=1&limit=1",
+			path:         "/api/v1/books?genre=Action&language=en&offset=1&limit=1",
 			expectedCode: http.StatusOK,
 		},
 		{
@@ -477,12 +552,12 @@ func TestHandleGetBook(t *testing.T) {
 			svr := newServer(nil, db, nil, nil)
 
 			if tc.bookID == "" {
-				bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book", description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "English", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}})
+				bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book", description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
 				if err != nil {
 					t.Fatal(err)
 				}
 
-				if err := svr.approveBook(context.Background(), bookID, true); err != nil {
+				if _, err := svr.moderateBook(context.Background(), userID, bookID, true, "looks good"); err != nil {
 					t.Fatal(err)
 				}
 				tc.bookID = bookID
@@ -500,6 +575,34 @@ func TestHandleGetBook(t *testing.T) {
 	}
 }
 
+func TestGetBookChapterCount(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book", description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svr.moderateBook(context.Background(), userID, bookID, true, "looks good"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "pending chapter", chapterNo: 2, content: "pending chapter content", bookID: bookID}); err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := svr.getBook(context.Background(), bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if book.chapterCount != 1 || len(book.chapters) != 1 {
+		t.Fatalf("expected only the approved chapter to be counted, got %d counted and %d listed", book.chapterCount, len(book.chapters))
+	}
+}
+
 func TestHandleDeleteBook(t *testing.T) {
 	db := connectTestDb(t)
 	userID := createAndCleanUpUser(t, db)
@@ -643,7 +746,7 @@ func TestHandleEditBook(t *testing.T) {
 			svr := newServer(nil, db, nil, nil)
 
 			if tc.bookID == "" {
-				bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book", description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "English", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}})
+				bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book", description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -664,102 +767,6 @@ func TestHandleEditBook(t *testing.T) {
 	}
 }
 
-func TestHandleApproveBook(t *testing.T) {
-	db := connectTestDb(t)
-	userID := createAndCleanUpUser(t, db)
-	token, err := createJWTToken(userID, 5*time.Second)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
-
-	tests := []struct {
-		name         string
-		cookieName   string
-		cookieValue  string
-		bookID       string
-		admin        bool
-		body         any
-		expectedCode int
-	}{
-		{
-			name:         "no access token cookie",
-			bookID:       uuid.NewString(),
-			expectedCode: http.StatusNotFound,
-		},
-		{
-			name:         "invalid/malformed token",
-			cookieName:   "access_token",
-			cookieValue:  "invalid token",
-			bookID:       uuid.NewString(),
-			expectedCode: http.StatusBadRequest,
-		},
-		{
-			name:         "role is not admin",
-			cookieName:   "access_token",
-			cookieValue:  token,
-			bookID:       uuid.NewString(),
-			expectedCode: http.StatusUnauthorized,
-		},
-		{
-			name:         "validation error",
-			cookieName:   "access_token",
-			cookieValue:  token,
-			bookID:       uuid.NewString(),
-			admin:        true,
-			body:         struct{ name string }{name: "invalid structure"},
-			expectedCode: http.StatusBadRequest,
-		},
-		{
-			name:        "book not found",
-			cookieName:  "access_token",
-			cookieValue: token,
-			bookID:      uuid.NewString(),
-			admin:       true,
-			body: struct {
-				Approve bool `json:"approve"`
-			}{Approve: true},
-			expectedCode: http.StatusNotFound,
-		},
-		{
-			name:        "approve book",
-			cookieName:  "access_token",
-			cookieValue: token,
-			bookID:      createBook(t, userID, db),
-			admin:       true,
-			body: struct {
-				Approve bool `json:"approve"`
-			}{Approve: true},
-			expectedCode: http.StatusNoContent,
-		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			if tc.admin == true {
-				query :=
-					`
-						UPDATE users SET roles = ARRAY['ADMIN']::role_type[] WHERE display_name = 'test_display';
-					`
-				if _, err := db.ExecContext(context.Background(), query); err != nil {
-					t.Fatalf("error updating users, %v", err)
-				}
-			}
-
-			body, _ := json.Marshal(tc.body)
-			r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%v/approve", tc.bookID), bytes.NewReader(body))
-			r.AddCookie(&http.Cookie{Name: tc.cookieName, Value: tc.cookieValue})
-			rr := httptest.NewRecorder()
-
-			svr := newServer(nil, db, nil, nil)
-			svr.router.ServeHTTP(rr, r)
-
-			if rr.Code != tc.expectedCode {
-				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
-			}
-		})
-	}
-}
-
 func TestHandleCompleteBook(t *testing.T) {
 	db := connectTestDb(t)
 	userID := createAndCleanUpUser(t, db)
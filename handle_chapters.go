@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -13,7 +15,7 @@ import (
 // handleUploadChapter godoc
 //
 //	@Summary		Upload chapter
-//	@Description	Upload chapter
+//	@Description	Upload chapter. The chapter is published once it passes automated moderation, chapters that don't are held until an admin reviews them.
 //	@Tags			chapters
 //	@Accept			json
 //	@Produce		json
@@ -21,14 +23,17 @@ import (
 //	@Param			param	body		main.handleUploadChapter.request	true	"upload chapter body"
 //	@Failure		400		{object}	errorResponse
 //	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
 //	@Failure		500		{object}	errorResponse
 //	@Success		200		{object}	main.handleUploadChapter.response
 //	@Router			/books/{bookID}/chapters [post]
 func (s *server) handleUploadChapter(w http.ResponseWriter, r *http.Request) {
 	type request struct {
-		Title     string `json:"title" validate:"required"`
-		ChapterNo int    `json:"chapterNo" validate:"required"`
-		Content   string `json:"content" validate:"required"`
+		Title           string `json:"title" validate:"required"`
+		ChapterNo       int    `json:"chapterNo" validate:"required"`
+		Content         string `json:"content" validate:"required"`
+		SourceChapterId string `json:"sourceChapterId" validate:"omitempty,uuid"`
 	}
 
 	type response struct {
@@ -49,56 +54,162 @@ func (s *server) handleUploadChapter(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user").(string)
 	bookID := chi.URLParam(r, "bookID")
 	id, err := s.uploadChapter(r.Context(), userID, &chapter{
-		title:     params.Title,
-		chapterNo: params.ChapterNo,
-		content:   params.Content,
-		bookID:    bookID,
+		title:           params.Title,
+		chapterNo:       params.ChapterNo,
+		content:         params.Content,
+		bookID:          bookID,
+		sourceChapterID: params.SourceChapterId,
 	})
-	if errors.Is(err, errBookNotFound) {
+	if errors.Is(err, errBookNotFound) || errors.Is(err, errSourceChapterNotFound) {
 		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 		return
 	}
+	if errors.Is(err, errChapterAlreadyTranslated) {
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
 
-	book, err := s.getBook(r.Context(), bookID)
+	s.queueModeration(r.Context(), id)
+	s.queueFingerprint(r.Context(), bookID)
+
+	encode(w, http.StatusCreated, &response{Id: id})
+}
+
+// queueModeration sends a pending chapter to the moderation worker. Failures
+// are only logged, the chapter is already saved as pending and the worker
+// queues pending chapters again on its own, so the client isn't asked to
+// upload it twice.
+func (s *server) queueModeration(ctx context.Context, chapterID string) {
+	if s.ch == nil {
+		return
+	}
+
+	messageBody, err := json.Marshal(struct {
+		ChapterID string
+	}{
+		ChapterID: chapterID,
+	})
+
 	if err != nil {
-		s.logger.Error(err.Error())
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		s.logger.Error(fmt.Sprintf("error marshalling message, %v", err))
 		return
 	}
 
-	message := fmt.Sprintf("%v chapter %v", book.name, params.ChapterNo)
+	if err := s.ch.PublishWithContext(ctx, "", queueChapterModeration, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
+		s.logger.Error(fmt.Sprintf("error publishing message to queue, %v", err))
+	}
+}
+
+// queueFingerprint sends the book to the moderation worker to fingerprint its
+// new or edited chapters and match it against earlier books again. Failures
+// are only logged, the worker's backfill fingerprints the chapters later.
+func (s *server) queueFingerprint(ctx context.Context, bookID string) {
+	if s.ch == nil {
+		return
+	}
+
+	messageBody, err := json.Marshal(struct {
+		BookID string
+	}{
+		BookID: bookID,
+	})
+
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error marshalling message, %v", err))
+		return
+	}
+
+	if err := s.ch.PublishWithContext(ctx, "", queueChapterFingerprint, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
+		s.logger.Error(fmt.Sprintf("error publishing message to queue, %v", err))
+	}
+}
+
+// publishChapter tells readers about a chapter that passed moderation. The
+// event and the notification are each sent once per chapter, so neither a
+// delivery requeued after a failure nor an edited chapter approved again
+// announces it twice.
+func (s *server) publishChapter(ctx context.Context, chapterID string) error {
+	ch, bookName, err := s.getPublishedChapter(ctx, chapterID)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%v chapter %v", bookName, ch.chapterNo)
+
+	if !ch.announced {
+		if err := s.publishEvent(ctx, newEvent(chapterUploadEvent{BookId: ch.bookID, Message: message})); err != nil {
+			return err
+		}
+
+		if err := s.markChapterAnnounced(ctx, chapterID); err != nil {
+			return err
+		}
+	}
+
+	if ch.notified {
+		return nil
+	}
 
 	messageBody, err := json.Marshal(struct {
 		BookID  string
 		Message string
 	}{
-		BookID:  bookID,
+		BookID:  ch.bookID,
 		Message: message,
 	})
 
-	if err := s.ch.PublishWithContext(r.Context(), "", queueChapterUploaded, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
-		s.logger.Error(fmt.Sprintf("error publishing message to queue, %v", err))
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
-		return
+	if err != nil {
+		return fmt.Errorf("error marshalling message, %v", err)
 	}
 
-	s.hub.broadcast <- &event{Type: CHAPTER_UPLOADED, Payload: chapterUploadEvent{BookId: bookID, Message: message}}
+	if err := s.ch.PublishWithContext(ctx, "", queueChapterUploaded, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
+		return fmt.Errorf("error publishing message to queue, %v", err)
+	}
 
-	encode(w, http.StatusCreated, &response{Id: id})
+	return s.markChapterNotified(ctx, chapterID)
+}
+
+// consumePublishedChapters publishes the chapters the moderation worker
+// approved until the deliveries channel is closed
+func (s *server) consumePublishedChapters(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		var msg struct {
+			ChapterID string
+		}
+		if err := json.Unmarshal(d.Body, &msg); err != nil {
+			d.Nack(false, false)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		err := s.publishChapter(ctx, msg.ChapterID)
+		cancel()
+
+		switch {
+		case errors.Is(err, errChapterNotFound):
+			d.Nack(false, false)
+		case err != nil:
+			s.logger.Error(err.Error())
+			d.Nack(false, true)
+		default:
+			d.Ack(false)
+		}
+	}
 }
 
 // handleGetChapter godoc
 //
 //	@Summary		Get chapter
-//	@Description	Get chapter
+//	@Description	Get chapter. Readers too young for the book's content rating, or who didn't turn mature content on, can't read it.
 //	@Tags			chapters
 //	@Produce		json
 //	@Param			chapterID	path		string	true	"chapter id"
+//	@Failure		403			{object}	errorResponse
 //	@Failure		404			{object}	errorResponse
 //	@Failure		500			{object}	errorResponse
 //	@Success		200			{object}	main.handleGetChapter.response
@@ -111,10 +222,14 @@ func (s *server) handleGetChapter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ch, err := s.getChapter(r.Context(), r.Context().Value("user").(string), chi.URLParam(r, "chapterID"))
-	if errors.Is(err, errChapterNotFound) {
+	if errors.Is(err, errChapterNotFound) || errors.Is(err, errUserNotFound) {
 		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 		return
 	}
+	if errors.Is(err, errContentRestricted) {
+		encode(w, http.StatusForbidden, &errorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -137,7 +252,9 @@ func (s *server) handleGetChapter(w http.ResponseWriter, r *http.Request) {
 //	@Success		204
 //	@Router			/books/{bookID}/chapters/{chapterID} [delete]
 func (s *server) handleDeleteChapter(w http.ResponseWriter, r *http.Request) {
-	if err := s.deleteChapter(r.Context(), r.Context().Value("user").(string), chi.URLParam(r, "bookID"), chi.URLParam(r, "chapterID")); err != nil {
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := s.deleteChapter(r.Context(), r.Context().Value("user").(string), bookID, chi.URLParam(r, "chapterID")); err != nil {
 		if errors.Is(err, errBookNotFound) || errors.Is(err, errChapterNotFound) {
 			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 			return
@@ -147,13 +264,17 @@ func (s *server) handleDeleteChapter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the deleted chapter's fingerprints are gone, the matches they were part
+	// of have to be worked out again
+	s.queueFingerprint(r.Context(), bookID)
+
 	encode(w, http.StatusNoContent, nil)
 }
 
 // handleEditChapter godoc
 //
 //	@Summary		Edit chapter
-//	@Description	Edit chapter
+//	@Description	Edit chapter. Published chapters with a new title or content are held until they pass automated moderation again.
 //	@Tags			chapters
 //	@Produce		json
 //	@Param			bookID		path		string							true	"book id"
@@ -161,13 +282,15 @@ func (s *server) handleDeleteChapter(w http.ResponseWriter, r *http.Request) {
 //	@Param			param		body		main.handleEditChapter.request	false	"edit chapter body"
 //	@Failure		400			{object}	errorResponse
 //	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
 //	@Failure		500			{object}	errorResponse
 //	@Success		204
 //	@Router			/books/{bookID}/chapters/{chapterID} [patch]
 func (s *server) handleEditChapter(w http.ResponseWriter, r *http.Request) {
 	type request struct {
-		Title   string `json:"title"`
-		Content string `json:"content"`
+		Title           string `json:"title"`
+		Content         string `json:"content"`
+		SourceChapterId string `json:"sourceChapterId"`
 	}
 
 	var params request
@@ -176,20 +299,39 @@ func (s *server) handleEditChapter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if params.Title == "" && params.Content == "" {
+	if params.SourceChapterId != "" {
+		if err := validate.Var(params.SourceChapterId, "uuid"); err != nil {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: "sourceChapterId should be a valid uuid"})
+			return
+		}
+	}
+
+	if params.Title == "" && params.Content == "" && params.SourceChapterId == "" {
 		encode(w, http.StatusBadRequest, &errorResponse{Error: "should at least pass one field to update"})
 		return
 	}
 
-	if err := s.editChapter(r.Context(), r.Context().Value("user").(string), &chapter{id: chi.URLParam(r, "chapterID"), title: params.Title, content: params.Content, bookID: chi.URLParam(r, "bookID")}); err != nil {
-		if errors.Is(err, errBookNotFound) || errors.Is(err, errChapterNotFound) {
+	if err := s.editChapter(r.Context(), r.Context().Value("user").(string), &chapter{id: chi.URLParam(r, "chapterID"), title: params.Title, content: params.Content, bookID: chi.URLParam(r, "bookID"), sourceChapterID: params.SourceChapterId}); err != nil {
+		if errors.Is(err, errBookNotFound) || errors.Is(err, errChapterNotFound) || errors.Is(err, errSourceChapterNotFound) {
 			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
 			return
 		}
+		if errors.Is(err, errChapterAlreadyTranslated) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
 
+	if params.Content != "" || params.Title != "" {
+		s.queueModeration(r.Context(), chi.URLParam(r, "chapterID"))
+	}
+
+	if params.Content != "" {
+		s.queueFingerprint(r.Context(), chi.URLParam(r, "bookID"))
+	}
+
 	encode(w, http.StatusNoContent, nil)
 }
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
@@ -20,6 +22,18 @@ func (*mc) PublishWithContext(_ context.Context, _, _ string, _, _ bool, _ amqp.
 	return nil
 }
 
+// failingQueue is a channel that can't publish to one queue
+type failingQueue struct {
+	queue string
+}
+
+func (c *failingQueue) PublishWithContext(_ context.Context, _, key string, _, _ bool, _ amqp.Publishing) error {
+	if key == c.queue {
+		return errors.New("queue unavailable")
+	}
+	return nil
+}
+
 func TestHandleUploadChapter(t *testing.T) {
 	db := connectTestDb(t)
 	userID := createAndCleanUpUser(t, db)
@@ -90,6 +104,23 @@ func TestHandleUploadChapter(t *testing.T) {
 			mockChannel:  &mc{},
 			expectedCode: http.StatusCreated,
 		},
+		{
+			name:        "moderation queue unavailable",
+			cookieName:  "access_token",
+			cookieValue: token,
+			bookID:      createNamedBook(t, userID, db),
+			body: struct {
+				Title     string `json:"title"`
+				ChapterNo int    `json:"chapterNo"`
+				Content   string `json:"content"`
+			}{
+				Title:     "test chapter",
+				ChapterNo: 1,
+				Content:   "test chapter content",
+			},
+			mockChannel:  &failingQueue{queue: queueChapterModeration},
+			expectedCode: http.StatusCreated,
+		},
 	}
 
 	for _, tc := range tests {
@@ -118,11 +149,25 @@ func TestHandleGetChapter(t *testing.T) {
 	}
 
 	svr := newServer(nil, db, nil, nil)
-	chapterID, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: createBook(t, userID, db)})
+	bookID := createBook(t, userID, db)
+	chapterID, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: bookID})
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
+	pendingChapterID, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 2, content: "test chapter content", bookID: bookID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	query :=
+		`
+			UPDATE chapters SET moderation_status = 'approved' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+		t.Fatalf("error approving chapter, %v", err)
+	}
+
 	tests := []struct {
 		name         string
 		cookieName   string
@@ -150,6 +195,13 @@ func TestHandleGetChapter(t *testing.T) {
 			chapterID:    uuid.NewString(),
 			expectedCode: http.StatusNotFound,
 		},
+		{
+			name:         "chapter waiting for moderation",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			chapterID:    pendingChapterID,
+			expectedCode: http.StatusNotFound,
+		},
 		{
 			name:         "get chapter",
 			cookieName:   "access_token",
@@ -174,6 +226,134 @@ func TestHandleGetChapter(t *testing.T) {
 	}
 }
 
+func TestHandleGetChapterContentRating(t *testing.T) {
+	db := connectTestDb(t)
+	authorID := createAndCleanUpUser(t, db)
+	readerID := createAndCleanUpFollowed(t, db)
+	token, err := createJWTToken(readerID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	bookID := createBook(t, authorID, db)
+	chapterID, err := svr.uploadChapter(context.Background(), authorID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: bookID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	query :=
+		`
+			UPDATE chapters SET moderation_status = 'approved' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+		t.Fatalf("error approving chapter, %v", err)
+	}
+
+	query =
+		`
+			UPDATE books SET content_rating = 'mature' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, bookID); err != nil {
+		t.Fatalf("error rating book, %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		preferences  string
+		expectedCode int
+	}{
+		{
+			name:         "reader without birth date",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "adult who didn't turn mature content on",
+			preferences:  `{"birthDate": "1990-06-15"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "adult who turned mature content on",
+			preferences:  `{"showMatureContent": true}`,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.preferences != "" {
+				r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me/content-preferences", bytes.NewReader([]byte(tc.preferences)))
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+				rr := httptest.NewRecorder()
+
+				svr.router.ServeHTTP(rr, r)
+
+				if rr.Code != http.StatusNoContent {
+					t.Fatalf("expected %d, got %d", http.StatusNoContent, rr.Code)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/chapters/%v", chapterID), nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("mature books are left out for signed out readers", func(t *testing.T) {
+		books, err := svr.getAllBooks(context.Background(), defaultContentFilter, 0, 100, "views", "desc")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if slices.ContainsFunc(books, func(b book) bool { return b.id == bookID }) {
+			t.Fatalf("expected mature book %s to be left out", bookID)
+		}
+	})
+}
+
+func TestPublishChapter(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	svr := newServer(nil, db, nil, &failingQueue{queue: queueChapterUploaded})
+	bookID := createBook(t, userID, db)
+	chapterID, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: bookID})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	query :=
+		`
+			UPDATE chapters SET moderation_status = 'approved' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+		t.Fatalf("error approving chapter, %v", err)
+	}
+
+	if err := svr.publishChapter(context.Background(), chapterID); err == nil {
+		t.Fatal("expected publishing to the queue to fail")
+	}
+
+	ch, _, err := svr.getPublishedChapter(context.Background(), chapterID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !ch.announced {
+		t.Fatal("expected the chapter event to be published before the queue")
+	}
+
+	svr = newServer(nil, db, nil, &mc{})
+	if err := svr.publishChapter(context.Background(), chapterID); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
 func TestHandleDeleteChapter(t *testing.T) {
 	db := connectTestDb(t)
 	userID := createAndCleanUpUser(t, db)
@@ -341,4 +521,42 @@ func TestHandleEditChapter(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("edited chapter is held until it is approved again", func(t *testing.T) {
+		query :=
+			`
+				UPDATE chapters SET moderation_status = 'approved' WHERE id = $1;
+			`
+		if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+			t.Fatalf("error approving chapter, %v", err)
+		}
+
+		body, _ := json.Marshal(map[string]string{"content": "edited content with spam"})
+		r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/books/%v/chapters/%v", bookID, chapterID), bytes.NewReader(body))
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d", http.StatusNoContent, rr.Code)
+		}
+
+		if _, err := svr.getChapter(context.Background(), userID, chapterID); !errors.Is(err, errChapterNotFound) {
+			t.Fatalf("expected the edited chapter to be held, got %v", err)
+		}
+
+		if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+			t.Fatalf("error approving chapter, %v", err)
+		}
+
+		ch, err := svr.getChapter(context.Background(), userID, chapterID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if ch.content != "edited content with spam" {
+			t.Fatalf("expected the edited content once approved, got %q", ch.content)
+		}
+	})
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// collaboratorError answers with the status matching an error returned while
+// managing a book's collaborators
+func (s *server) collaboratorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBookNotFound), errors.Is(err, errUserNotFound), errors.Is(err, errCollaboratorNotFound), errors.Is(err, errInvitationNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errCollaboratorExists):
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errInviteAuthor), errors.Is(err, errRevenueShareTooHigh):
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+type collaboratorResponse struct {
+	BookId       string  `json:"bookId"`
+	Book         string  `json:"book"`
+	UserId       string  `json:"userId"`
+	DisplayName  string  `json:"displayName"`
+	Role         string  `json:"role"`
+	RevenueShare int     `json:"revenueShare"`
+	Status       string  `json:"status"`
+	InvitedBy    *string `json:"invitedBy"`
+	CreatedAt    string  `json:"createdAt"`
+}
+
+func collaboratorsResponse(collaborators []collaborator) []collaboratorResponse {
+	resp := []collaboratorResponse{}
+	for _, c := range collaborators {
+		item := collaboratorResponse{BookId: c.bookID, Book: c.bookName, UserId: c.userID, DisplayName: c.displayName, Role: c.role, RevenueShare: c.revenueShare, Status: c.status, CreatedAt: c.createdAt.Format(time.RFC3339)}
+
+		if c.invitedBy.Valid {
+			item.InvitedBy = &c.invitedBy.String
+		}
+
+		resp = append(resp, item)
+	}
+	return resp
+}
+
+// handleInviteCollaborator godoc
+//
+//	@Summary		Invite collaborator
+//	@Description	Invite a user to work on your book as a co-author, editor or translator with a share of its revenue. They can't do anything until they accept.
+//	@Tags			books
+//	@Accept			json
+//	@Param			bookID	path		string								true	"book id"
+//	@Param			param	body		main.handleInviteCollaborator.request	true	"invite collaborator body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/books/{bookID}/collaborators [post]
+func (s *server) handleInviteCollaborator(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		UserId       string `json:"userId" validate:"required,uuid"`
+		Role         string `json:"role" validate:"required,oneof=co_author editor translator"`
+		RevenueShare int    `json:"revenueShare" validate:"min=0,max=100"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.inviteCollaborator(r.Context(), r.Context().Value("user").(string), &collaborator{bookID: bookID, userID: params.UserId, role: params.Role, revenueShare: params.RevenueShare}); err != nil {
+		s.collaboratorError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleGetCollaborators godoc
+//
+//	@Summary		Get collaborators
+//	@Description	Get everyone invited to work on a book. Only the author and collaborators can see them.
+//	@Tags			books
+//	@Produce		json
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetCollaborators.response
+//	@Router			/books/{bookID}/collaborators [get]
+func (s *server) handleGetCollaborators(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Collaborators []collaboratorResponse `json:"collaborators"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	collaborators, err := s.getCollaborators(r.Context(), r.Context().Value("user").(string), bookID)
+	if err != nil {
+		s.collaboratorError(w, err)
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Collaborators: collaboratorsResponse(collaborators)})
+}
+
+// handleRemoveCollaborator godoc
+//
+//	@Summary		Remove collaborator
+//	@Description	Remove a collaborator or invitation from your book, or leave a book you collaborate on
+//	@Tags			books
+//	@Param			bookID	path		string	true	"book id"
+//	@Param			userID	path		string	true	"user id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/books/{bookID}/collaborators/{userID} [delete]
+func (s *server) handleRemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	bookID := chi.URLParam(r, "bookID")
+	userID := chi.URLParam(r, "userID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errCollaboratorNotFound.Error()})
+		return
+	}
+
+	if err := s.removeCollaborator(r.Context(), r.Context().Value("user").(string), bookID, userID); err != nil {
+		s.collaboratorError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleGetInvitations godoc
+//
+//	@Summary		Get invitations
+//	@Description	Get the invitations to work on books you haven't answered yet
+//	@Tags			users
+//	@Produce		json
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetInvitations.response
+//	@Router			/users/me/invitations [get]
+func (s *server) handleGetInvitations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Invitations []collaboratorResponse `json:"invitations"`
+	}
+
+	invitations, err := s.getInvitations(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		s.collaboratorError(w, err)
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Invitations: collaboratorsResponse(invitations)})
+}
+
+// handleRespondToInvitation godoc
+//
+//	@Summary		Respond to invitation
+//	@Description	Accept or decline an invitation to work on a book
+//	@Tags			users
+//	@Accept			json
+//	@Param			bookID	path		string								true	"book id"
+//	@Param			param	body		main.handleRespondToInvitation.request	true	"respond to invitation body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me/invitations/{bookID} [patch]
+func (s *server) handleRespondToInvitation(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Accept *bool `json:"accept" validate:"required"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errInvitationNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.respondToInvitation(r.Context(), r.Context().Value("user").(string), bookID, *params.Accept); err != nil {
+		s.collaboratorError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
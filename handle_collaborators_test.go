@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleCollaborators(t *testing.T) {
+	db := connectTestDb(t)
+	authorID := createAndCleanUpUser(t, db)
+	collaboratorID := createAndCleanUpFollowed(t, db)
+
+	authorToken, err := createJWTToken(authorID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	collaboratorToken, err := createJWTToken(collaboratorID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+	bookID := createBook(t, authorID, db)
+
+	chapter := map[string]any{"title": "test chapter", "chapterNo": 1, "content": "test chapter content"}
+
+	tests := []struct {
+		name         string
+		token        string
+		method       string
+		path         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "only the author can invite",
+			token:        collaboratorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": authorID, "role": "editor"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid role",
+			token:        authorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": collaboratorID, "role": "illustrator"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "user not found",
+			token:        authorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": uuid.NewString(), "role": "editor"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invite the author",
+			token:        authorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": authorID, "role": "editor"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invite collaborator",
+			token:        authorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": collaboratorID, "role": "co_author", "revenueShare": 40},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "already invited",
+			token:        authorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			body:         map[string]any{"userId": collaboratorID, "role": "editor"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "pending collaborators can't upload chapters",
+			token:        collaboratorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/chapters", bookID),
+			body:         chapter,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "accept invitation",
+			token:        collaboratorToken,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/api/v1/users/me/invitations/%v", bookID),
+			body:         map[string]any{"accept": true},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "invitation already answered",
+			token:        collaboratorToken,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/api/v1/users/me/invitations/%v", bookID),
+			body:         map[string]any{"accept": false},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "co-author uploads chapter",
+			token:        collaboratorToken,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/books/%v/chapters", bookID),
+			body:         chapter,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "collaborators can see each other",
+			token:        collaboratorToken,
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/api/v1/books/%v/collaborators", bookID),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+			r := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: tc.token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("credits", func(t *testing.T) {
+		book, err := svr.getBook(context.Background(), bookID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(book.credits) != 2 {
+			t.Fatalf("expected 2 credits, got %d", len(book.credits))
+		}
+		if book.credits[0].role != "author" || book.credits[0].revenueShare != 60 {
+			t.Fatalf("expected the author to keep 60%%, got %s with %d%%", book.credits[0].role, book.credits[0].revenueShare)
+		}
+		if book.credits[1].role != "co_author" || book.credits[1].revenueShare != 40 {
+			t.Fatalf("expected the co-author to get 40%%, got %s with %d%%", book.credits[1].role, book.credits[1].revenueShare)
+		}
+	})
+
+	t.Run("leave book", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/books/%v/collaborators/%v", bookID, collaboratorID), nil)
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: collaboratorToken})
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d", http.StatusNoContent, rr.Code)
+		}
+	})
+}
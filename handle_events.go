@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sseFrame formats an event as a server-sent event. Events without a sequence
+// number carry no id, so they don't move the client's Last-Event-ID.
+func sseFrame(name string, seq int64, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling event, %v", err)
+	}
+
+	var b bytes.Buffer
+	if seq > 0 {
+		fmt.Fprintf(&b, "id: %d\n", seq)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", name, data)
+
+	return b.Bytes(), nil
+}
+
+// lastEventID reads where the client left off. Browsers send Last-Event-ID
+// themselves when they reconnect; lastSeenSeq lets a client resume on its
+// first connection.
+func lastEventID(r *http.Request) (int64, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastSeenSeq")
+	}
+
+	if value == "" {
+		return 0, false, nil
+	}
+
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, false, errors.New("last event id should be a valid number")
+	}
+
+	return seq, true, nil
+}
+
+// handleEvents godoc
+//
+//	@Summary		Stream events
+//	@Description	Stream the events delivered over the websocket as server-sent events, for clients that can't use websockets
+//	@Tags			events
+//	@Produce		text/event-stream
+//	@Param			Last-Event-ID	header	string	false	"sequence number of the last event received"
+//	@Param			lastSeenSeq		query	string	false	"sequence number of the last event received, used when Last-Event-ID is not set"
+//	@Failure		400				{object}	errorResponse
+//	@Failure		429				{object}	errorResponse
+//	@Failure		500				{object}	errorResponse
+//	@Success		200
+//	@Router			/events [get]
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user").(string)
+
+	lastSeenSeq, replay, err := lastEventID(r)
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	c, bookIDs, err := s.newUserClient(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+	c.sse = true
+
+	if err := s.attachClient(c, bookIDs); err != nil {
+		encode(w, http.StatusTooManyRequests, &errorResponse{Error: err.Error()})
+		return
+	}
+
+	defer func() {
+		s.hub.disconnect <- c
+	}()
+
+	var frames [][]byte
+	if replay {
+		frames, err = s.missedEventFrames(r.Context(), c, lastSeenSeq)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(frame []byte) error {
+		rc.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, frame := range frames {
+		if err := write(frame); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	// comments keep proxies from closing an idle stream
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case frame := <-c.send:
+			if err := write(frame); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case <-c.closed:
+			// the client reconnects and resumes from its Last-Event-ID
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleGetEventSchema godoc
+//
+//	@Summary		Get event schema
+//	@Description	Get the JSON schema of the events sent to clients, generated from the event registry
+//	@Tags			events
+//	@Produce		json
+//	@Success		200	{object}	map[string]any
+//	@Router			/events/schema [get]
+func (s *server) handleGetEventSchema(w http.ResponseWriter, r *http.Request) {
+	encode(w, http.StatusOK, eventSchema())
+}
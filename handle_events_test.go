@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		seq      int64
+		expected string
+	}{
+		{
+			name:     "recorded event",
+			seq:      42,
+			expected: "id: 42\nevent: user.followed\ndata: {\"userId\":\"1\",\"message\":\"user followed you\"}\n\n",
+		},
+		{
+			name:     "ephemeral event",
+			seq:      0,
+			expected: "event: user.followed\ndata: {\"userId\":\"1\",\"message\":\"user followed you\"}\n\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			frame, err := sseFrame(NEW_FOLLOWER.String(), tc.seq, followerEvent{UserId: "1", Message: "user followed you"})
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if string(frame) != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, frame)
+			}
+		})
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		query       string
+		expectedSeq int64
+		replay      bool
+		expectErr   bool
+	}{
+		{name: "no last event id"},
+		{name: "header", header: "7", expectedSeq: 7, replay: true},
+		{name: "query", query: "3", expectedSeq: 3, replay: true},
+		{name: "header wins over query", header: "7", query: "3", expectedSeq: 7, replay: true},
+		{name: "invalid", header: "abc", expectErr: true},
+		{name: "negative", query: "-1", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/events?lastSeenSeq="+tc.query, nil)
+			if tc.header != "" {
+				r.Header.Set("Last-Event-ID", tc.header)
+			}
+
+			seq, replay, err := lastEventID(r)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if seq != tc.expectedSeq || replay != tc.replay {
+				t.Fatalf("expected %d/%v, got %d/%v", tc.expectedSeq, tc.replay, seq, replay)
+			}
+		})
+	}
+}
+
+func TestHandleEvents(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	ts := httptest.NewServer(svr.router)
+	defer ts.Close()
+
+	publish := func() int64 {
+		e := newEvent(followerEvent{UserId: id, Message: "user followed you"})
+		if err := svr.publishEvent(context.Background(), e); err != nil {
+			t.Fatal(err.Error())
+		}
+		return e.Seqs[id]
+	}
+
+	missed := publish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/events", nil)
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	r.Header.Set("Last-Event-ID", strconv.FormatInt(missed-1, 10))
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readFrame := func() string {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if line == "\n" {
+				return frame.String()
+			}
+			frame.WriteString(line)
+		}
+	}
+
+	if frame := readFrame(); !strings.HasPrefix(frame, "id: "+strconv.FormatInt(missed, 10)+"\nevent: user.followed\n") {
+		t.Fatalf("expected missed event, got %q", frame)
+	}
+	if frame := readFrame(); !strings.HasPrefix(frame, "event: replayed\n") {
+		t.Fatalf("expected end of replay, got %q", frame)
+	}
+
+	live := publish()
+
+	if frame := readFrame(); !strings.HasPrefix(frame, "id: "+strconv.FormatInt(live, 10)+"\nevent: user.followed\n") {
+		t.Fatalf("expected live event, got %q", frame)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// exportLinkTTL is how long a signed link to an export zip stays valid
+const exportLinkTTL = 5 * time.Minute
+
+// handleRequestDataExport godoc
+//
+//	@Summary		Request data export
+//	@Description	Start building a zip with all the personal data of the current user. The user is notified with a download link once it is ready.
+//	@Tags			users
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		409	{object}	errorResponse
+//	@Failure		429	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		202	{object}	main.handleRequestDataExport.response
+//	@Router			/users/me/exports [post]
+func (s *server) handleRequestDataExport(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	id, err := s.createDataExport(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		if errors.Is(err, errExportPending) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	messageBody, err := json.Marshal(struct {
+		ExportID string
+	}{
+		ExportID: id,
+	})
+
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error marshalling message, %v", err))
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := s.ch.PublishWithContext(r.Context(), "", queueDataExport, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
+		s.logger.Error(fmt.Sprintf("error publishing message to queue, %v", err))
+		if err := s.failDataExport(r.Context(), id); err != nil {
+			s.logger.Error(err.Error())
+		}
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusAccepted, &response{Id: id})
+}
+
+// handleGetDataExports godoc
+//
+//	@Summary		Get data exports
+//	@Description	Get the data exports of the current user and their status
+//	@Tags			users
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetDataExports.response
+//	@Router			/users/me/exports [get]
+func (s *server) handleGetDataExports(w http.ResponseWriter, r *http.Request) {
+	type responseExport struct {
+		Id        string  `json:"id"`
+		Status    string  `json:"status"`
+		Expired   bool    `json:"expired"`
+		ExpiresAt *string `json:"expiresAt"`
+		CreatedAt string  `json:"createdAt"`
+	}
+
+	type response struct {
+		Exports []responseExport `json:"exports"`
+	}
+
+	exports, err := s.getDataExports(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	var resp []responseExport
+	for _, e := range exports {
+		var expiresAt *string
+		if e.expiresAt.Valid {
+			formatted := e.expiresAt.Time.Format(time.RFC3339)
+			expiresAt = &formatted
+		}
+		resp = append(resp, responseExport{Id: e.id, Status: e.status, Expired: e.expiresAt.Valid && time.Now().After(e.expiresAt.Time), ExpiresAt: expiresAt, CreatedAt: e.createdAt.Format(time.RFC3339)})
+	}
+
+	encode(w, http.StatusOK, &response{Exports: resp})
+}
+
+// handleDownloadDataExport godoc
+//
+//	@Summary		Download data export
+//	@Description	Redirects to a short lived signed link to the zip of a finished data export until the export expires
+//	@Tags			users
+//	@Param			exportID	path		string	true	"export id"
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		410			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		302
+//	@Router			/users/me/exports/{exportID}/download [get]
+func (s *server) handleDownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID := chi.URLParam(r, "exportID")
+
+	if err := validate.Var(exportID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errExportNotFound.Error()})
+		return
+	}
+
+	e, err := s.getDataExport(r.Context(), r.Context().Value("user").(string), exportID)
+	if err != nil {
+		if errors.Is(err, errExportNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if e.status == "expired" || (e.expiresAt.Valid && time.Now().After(e.expiresAt.Time)) {
+		encode(w, http.StatusGone, &errorResponse{Error: "export link expired, request a new export"})
+		return
+	}
+
+	if e.status != "ready" || !e.objectKey.Valid {
+		encode(w, http.StatusConflict, &errorResponse{Error: fmt.Sprintf("export is %s", e.status)})
+		return
+	}
+
+	url, err := s.objectStore.signedURL(r.Context(), e.objectKey.String, exportLinkTTL)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
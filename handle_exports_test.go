@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleRequestDataExport(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+	svr.limits["data_export"] = rateLimit{requests: 100, per: time.Minute}
+
+	tests := []struct {
+		name         string
+		cookieName   string
+		cookieValue  string
+		expectedCode int
+	}{
+		{
+			name:         "no access token cookie",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "request export",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "export already pending",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/exports", nil)
+			r.AddCookie(&http.Cookie{Name: tc.cookieName, Value: tc.cookieValue})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("queue unavailable", func(t *testing.T) {
+		if _, err := db.Exec(`UPDATE data_exports SET status = 'ready' WHERE user_id = $1;`, id); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		failing := newServer(nil, db, nil, &failingQueue{queue: queueDataExport})
+		failing.limits["data_export"] = rateLimit{requests: 100, per: time.Minute}
+
+		request := func(s *server) int {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/exports", nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, r)
+			return rr.Code
+		}
+
+		if code := request(failing); code != http.StatusInternalServerError {
+			t.Fatalf("expected %d, got %d", http.StatusInternalServerError, code)
+		}
+
+		// the export that never got queued doesn't block the next request
+		if code := request(svr); code != http.StatusAccepted {
+			t.Fatalf("expected %d, got %d", http.StatusAccepted, code)
+		}
+	})
+}
+
+func TestHandleDownloadDataExport(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/exports", nil)
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rr := httptest.NewRecorder()
+	svr.router.ServeHTTP(rr, r)
+
+	var created struct {
+		Id string `json:"id"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		exportID     string
+		expectedCode int
+	}{
+		{
+			name:         "export not found",
+			exportID:     uuid.NewString(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "export not ready",
+			exportID:     created.Id,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/exports/"+tc.exportID+"/download", nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
@@ -39,8 +39,8 @@ func (s *server) handleFollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if client, ok := s.hub.regular[userID]; ok {
-		client.send <- fmt.Appendf([]byte{}, "%v followed you", displayName)
+	if err := s.publishEvent(r.Context(), newEvent(followerEvent{UserId: userID, Message: fmt.Sprintf("%v followed you", displayName)})); err != nil {
+		s.logger.Error(err.Error())
 	}
 
 	encode(w, http.StatusNoContent, nil)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// handleGetGenres godoc
+//
+//	@Summary		Get genres
+//	@Description	Get the genres authors can pick for their books
+//	@Tags			genres
+//	@Produce		json
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetGenres.response
+//	@Router			/genres [get]
+func (s *server) handleGetGenres(w http.ResponseWriter, r *http.Request) {
+	type responseGenre struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	type response struct {
+		Genres []responseGenre `json:"genres"`
+	}
+
+	genres, err := s.getGenres(r.Context())
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseGenre{}
+	for _, g := range genres {
+		resp = append(resp, responseGenre{Id: g.id, Name: g.name})
+	}
+
+	encode(w, http.StatusOK, &response{Genres: resp})
+}
+
+// handleCreateGenre godoc
+//
+//	@Summary		Create genre
+//	@Description	Add a genre authors can pick for their books
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleCreateGenre.request	true	"create genre body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201		{object}	main.handleCreateGenre.response
+//	@Router			/admin/genres [post]
+func (s *server) handleCreateGenre(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name string `json:"name" validate:"required,max=64,excludesall=0x2C"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	name := strings.Join(strings.Fields(params.Name), " ")
+	if name == "" {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "genre name can't be empty"})
+		return
+	}
+
+	id, err := s.createGenre(r.Context(), name)
+	if err != nil {
+		if errors.Is(err, errGenreExists) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusCreated, &response{Id: id})
+}
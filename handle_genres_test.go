@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleCreateGenre(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+
+	genre := "Cultivation " + uuid.NewString()[:8]
+
+	t.Cleanup(func() {
+		query :=
+			`
+				DELETE FROM genres WHERE genre = $1;
+			`
+		if _, err := db.ExecContext(context.Background(), query, genre); err != nil {
+			t.Errorf("error deleting genre, %v", err)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		genre        string
+		expectedCode int
+	}{
+		{
+			name:         "comma in name",
+			genre:        "Cultivation, Xianxia",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "create genre",
+			genre:        genre,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "genre exists",
+			genre:        "  " + genre + " ",
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"name": tc.genre})
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/admin/genres", bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("get genres", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/genres", nil)
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Genres []struct {
+				Name string `json:"name"`
+			} `json:"genres"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if !slices.ContainsFunc(resp.Genres, func(g struct {
+			Name string `json:"name"`
+		}) bool {
+			return g.Name == genre
+		}) {
+			t.Fatalf("expected %s in %v", genre, resp.Genres)
+		}
+	})
+
+	t.Run("books can use the new genre", func(t *testing.T) {
+		if _, err := svr.uploadBook(context.Background(), &book{name: "test-book " + uuid.NewString(), description: "test-book description", authorID: userID, genres: []string{genre}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen}); err != nil {
+			t.Fatal(err.Error())
+		}
+	})
+}
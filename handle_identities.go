@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// handleGetIdentities godoc
+//
+//	@Summary		Get linked identities
+//	@Description	Get the oauth provider identities linked to the current user
+//	@Tags			users
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetIdentities.response
+//	@Router			/users/me/identities [get]
+func (s *server) handleGetIdentities(w http.ResponseWriter, r *http.Request) {
+	type responseIdentity struct {
+		Provider  string  `json:"provider"`
+		Email     *string `json:"email"`
+		CreatedAt string  `json:"createdAt"`
+	}
+
+	type response struct {
+		Identities []responseIdentity `json:"identities"`
+	}
+
+	identities, err := s.getUserIdentities(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	var resp []responseIdentity
+	for _, i := range identities {
+		var email *string
+		if i.email != "" {
+			email = &i.email
+		}
+		resp = append(resp, responseIdentity{Provider: i.provider, Email: email, CreatedAt: i.createdAt.Format("Jan 2, 2006")})
+	}
+
+	encode(w, http.StatusOK, &response{Identities: resp})
+}
+
+// handleUnlinkIdentity godoc
+//
+//	@Summary		Unlink identity
+//	@Description	Unlink an oauth provider identity from the current user
+//	@Tags			users
+//	@Param			provider	path		string	true	"provider"
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me/identities/{provider} [delete]
+func (s *server) handleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if err := s.unlinkIdentity(r.Context(), r.Context().Value("user").(string), chi.URLParam(r, "provider")); err != nil {
+		if errors.Is(err, errIdentityNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, errLastLoginMethod) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
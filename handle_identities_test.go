@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleGetIdentities(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	if err := svr.linkIdentity(context.Background(), id, &identity{provider: "github", providerUserID: "123", email: "test@test.com"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		cookieName   string
+		cookieValue  string
+		expectedCode int
+	}{
+		{
+			name:         "no access token cookie",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "get identities",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/identities", nil)
+			r.AddCookie(&http.Cookie{Name: tc.cookieName, Value: tc.cookieValue})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleUnlinkIdentity(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	if err := svr.linkIdentity(context.Background(), id, &identity{provider: "github", providerUserID: "123", email: "test@test.com"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		provider     string
+		expectedCode int
+	}{
+		{
+			name:         "identity not found",
+			provider:     "discord",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "unlink identity",
+			provider:     "github",
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/users/me/identities/%v", tc.provider), nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
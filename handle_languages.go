@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// handleGetLanguages godoc
+//
+//	@Summary		Get languages
+//	@Description	Get the languages books can be written in
+//	@Tags			languages
+//	@Produce		json
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetLanguages.response
+//	@Router			/languages [get]
+func (s *server) handleGetLanguages(w http.ResponseWriter, r *http.Request) {
+	type responseLanguage struct {
+		Code       string `json:"code"`
+		Name       string `json:"name"`
+		NativeName string `json:"nativeName"`
+	}
+
+	type response struct {
+		Languages []responseLanguage `json:"languages"`
+	}
+
+	languages, err := s.getLanguages(r.Context())
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseLanguage{}
+	for _, l := range languages {
+		resp = append(resp, responseLanguage{Code: l.code, Name: l.name, NativeName: l.nativeName})
+	}
+
+	encode(w, http.StatusOK, &response{Languages: resp})
+}
+
+// handleCreateLanguage godoc
+//
+//	@Summary		Create language
+//	@Description	Add a language authors can write books in
+//	@Tags			admin
+//	@Accept			json
+//	@Param			param	body		main.handleCreateLanguage.request	true	"create language body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201
+//	@Router			/admin/languages [post]
+func (s *server) handleCreateLanguage(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Code       string `json:"code" validate:"required,bcp47_language_tag"`
+		Name       string `json:"name" validate:"required,max=64"`
+		NativeName string `json:"nativeName" validate:"required,max=64"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.createLanguage(r.Context(), &language{code: params.Code, name: params.Name, nativeName: params.NativeName}); err != nil {
+		if errors.Is(err, errLanguageExists) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusCreated, nil)
+}
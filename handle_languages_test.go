@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleLanguages(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+
+	t.Cleanup(func() {
+		query :=
+			`
+				DELETE FROM languages WHERE code = 'pt-BR';
+			`
+		if _, err := db.ExecContext(context.Background(), query); err != nil {
+			t.Errorf("error deleting language, %v", err)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		body         map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "invalid code",
+			body:         map[string]string{"code": "Brazilian", "name": "Brazilian Portuguese", "nativeName": "Português brasileiro"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "language exists",
+			body:         map[string]string{"code": "en", "name": "English", "nativeName": "English"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "create language",
+			body:         map[string]string{"code": "pt-BR", "name": "Brazilian Portuguese", "nativeName": "Português brasileiro"},
+			expectedCode: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/admin/languages", bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("get languages", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/languages", nil)
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Languages []struct {
+				Code string `json:"code"`
+				Name string `json:"name"`
+			} `json:"languages"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		codes := make(map[string]string)
+		for _, l := range resp.Languages {
+			codes[l.Code] = l.Name
+		}
+
+		if codes["pt"] != "Portuguese" || codes["pt-BR"] != "Brazilian Portuguese" {
+			t.Fatalf("expected pt and pt-BR, got %v", resp.Languages)
+		}
+	})
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listError answers with the status matching an error returned while managing
+// a list
+func (s *server) listError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errListNotFound), errors.Is(err, errListBookNotFound), errors.Is(err, errBookNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errCollectionBook):
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+type listBookResponse struct {
+	Id          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Image       *string `json:"image"`
+	Views       int     `json:"views"`
+	Rating      float32 `json:"rating"`
+}
+
+type listResponse struct {
+	Id          string             `json:"id"`
+	Kind        string             `json:"kind"`
+	Name        string             `json:"name"`
+	Description *string            `json:"description"`
+	Public      bool               `json:"public"`
+	OwnerId     string             `json:"ownerId"`
+	Owner       string             `json:"owner"`
+	Books       []listBookResponse `json:"books"`
+	CreatedAt   string             `json:"createdAt"`
+	UpdatedAt   string             `json:"updatedAt"`
+}
+
+func listsResponse(lists []bookList) []listResponse {
+	resp := []listResponse{}
+	for _, l := range lists {
+		item := listResponse{Id: l.id, Kind: l.kind, Name: l.name, Public: l.public, OwnerId: l.ownerID, Owner: l.ownerName, Books: []listBookResponse{}, CreatedAt: l.createdAt.Format(time.RFC3339), UpdatedAt: l.updatedAt.Format(time.RFC3339)}
+
+		if l.description.Valid {
+			item.Description = &l.description.String
+		}
+
+		for _, b := range l.books {
+			book := listBookResponse{Id: b.id, Name: b.name, Description: b.description, Views: b.views, Rating: b.rating}
+			if b.image.Valid {
+				book.Image = &b.image.String
+			}
+			item.Books = append(item.Books, book)
+		}
+
+		resp = append(resp, item)
+	}
+	return resp
+}
+
+// handleCreateList godoc
+//
+//	@Summary		Create list
+//	@Description	Create a collection of your own books or a reading list of any books
+//	@Tags			lists
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleCreateList.request	true	"create list body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201		{object}	main.handleCreateList.response
+//	@Router			/lists [post]
+func (s *server) handleCreateList(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Kind        string  `json:"kind" validate:"required,oneof=collection reading_list"`
+		Name        string  `json:"name" validate:"required,max=255"`
+		Description *string `json:"description"`
+		Public      *bool   `json:"public"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	l := &bookList{ownerID: r.Context().Value("user").(string), kind: params.Kind, name: params.Name, public: true}
+	if params.Description != nil {
+		l.description = sql.NullString{String: *params.Description, Valid: true}
+	}
+	if params.Public != nil {
+		l.public = *params.Public
+	}
+
+	id, err := s.createList(r.Context(), l)
+	if err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusCreated, &response{Id: id})
+}
+
+// handleGetList godoc
+//
+//	@Summary		Get list
+//	@Description	Get a public list with its books in order, leaving out the ones rated above what you can read
+//	@Tags			lists
+//	@Produce		json
+//	@Param			listID	path		string	true	"list id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	listResponse
+//	@Router			/lists/{listID} [get]
+func (s *server) handleGetList(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listID")
+
+	if err := validate.Var(listID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListNotFound.Error()})
+		return
+	}
+
+	userID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	l, err := s.getList(r.Context(), listID, filter)
+	if err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusOK, &listsResponse([]bookList{*l})[0])
+}
+
+// handleUpdateList godoc
+//
+//	@Summary		Update list
+//	@Description	Rename your list, change its description or whether everyone can see it
+//	@Tags			lists
+//	@Accept			json
+//	@Param			listID	path		string							true	"list id"
+//	@Param			param	body		main.handleUpdateList.request	true	"update list body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/lists/{listID} [patch]
+func (s *server) handleUpdateList(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+		Description *string `json:"description"`
+		Public      *bool   `json:"public"`
+	}
+
+	listID := chi.URLParam(r, "listID")
+
+	if err := validate.Var(listID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.updateList(r.Context(), r.Context().Value("user").(string), listID, params.Name, params.Description, params.Public); err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleDeleteList godoc
+//
+//	@Summary		Delete list
+//	@Description	Delete your list
+//	@Tags			lists
+//	@Param			listID	path		string	true	"list id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/lists/{listID} [delete]
+func (s *server) handleDeleteList(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listID")
+
+	if err := validate.Var(listID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListNotFound.Error()})
+		return
+	}
+
+	if err := s.deleteList(r.Context(), r.Context().Value("user").(string), listID); err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleAddBookToList godoc
+//
+//	@Summary		Add book to list
+//	@Description	Add a book to the end of your list. Collections only take books you wrote.
+//	@Tags			lists
+//	@Param			listID	path		string	true	"list id"
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/lists/{listID}/books/{bookID} [put]
+func (s *server) handleAddBookToList(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listID")
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(listID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListNotFound.Error()})
+		return
+	}
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	if err := s.addBookToList(r.Context(), r.Context().Value("user").(string), listID, bookID); err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleRemoveBookFromList godoc
+//
+//	@Summary		Remove book from list
+//	@Description	Remove a book from your list
+//	@Tags			lists
+//	@Param			listID	path		string	true	"list id"
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/lists/{listID}/books/{bookID} [delete]
+func (s *server) handleRemoveBookFromList(w http.ResponseWriter, r *http.Request) {
+	listID := chi.URLParam(r, "listID")
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(listID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListNotFound.Error()})
+		return
+	}
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errListBookNotFound.Error()})
+		return
+	}
+
+	if err := s.removeBookFromList(r.Context(), r.Context().Value("user").(string), listID, bookID); err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleGetUserLists godoc
+//
+//	@Summary		Get user lists
+//	@Description	Get the public collections and reading lists of a user
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		string	true	"user id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetUserLists.response
+//	@Router			/users/{userID}/lists [get]
+func (s *server) handleGetUserLists(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Lists []listResponse `json:"lists"`
+	}
+
+	userID := chi.URLParam(r, "userID")
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errUserNotFound.Error()})
+		return
+	}
+
+	readerID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), readerID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	lists, err := s.getUserLists(r.Context(), userID, false, filter)
+	if err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Lists: listsResponse(lists)})
+}
+
+// handleGetMyLists godoc
+//
+//	@Summary		Get my lists
+//	@Description	Get your collections and reading lists, private ones included
+//	@Tags			users
+//	@Produce		json
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetMyLists.response
+//	@Router			/users/me/lists [get]
+func (s *server) handleGetMyLists(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Lists []listResponse `json:"lists"`
+	}
+
+	userID := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	lists, err := s.getUserLists(r.Context(), userID, true, filter)
+	if err != nil {
+		s.listError(w, err)
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Lists: listsResponse(lists)})
+}
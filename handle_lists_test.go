@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleLists(t *testing.T) {
+	db := connectTestDb(t)
+	ownerID := createAndCleanUpUser(t, db)
+	otherID := createAndCleanUpFollowed(t, db)
+
+	ownerToken, err := createJWTToken(ownerID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	otherToken, err := createJWTToken(otherID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+	ownBookID := createBook(t, ownerID, db)
+	otherBookID := createNamedBook(t, otherID, db)
+
+	createList := func(kind string, public bool) string {
+		body, _ := json.Marshal(map[string]any{"kind": kind, "name": "test list", "public": public})
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/lists", bytes.NewReader(body))
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: ownerToken})
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		var resp struct {
+			Id string `json:"id"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+		return resp.Id
+	}
+
+	collectionID := createList("collection", true)
+	readingListID := createList("reading_list", false)
+
+	tests := []struct {
+		name         string
+		token        string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{
+			name:         "only the owner can add books",
+			token:        otherToken,
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", readingListID, otherBookID),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "collections only take the owner's books",
+			token:        ownerToken,
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", collectionID, otherBookID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "add own book to collection",
+			token:        ownerToken,
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", collectionID, ownBookID),
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "add any book to reading list",
+			token:        ownerToken,
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", readingListID, otherBookID),
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "add own book to reading list",
+			token:        ownerToken,
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", readingListID, ownBookID),
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "private lists are hidden",
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/api/v1/lists/%v", readingListID),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "public lists are visible",
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/api/v1/lists/%v", collectionID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "remove book not in list",
+			token:        ownerToken,
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/api/v1/lists/%v/books/%v", collectionID, otherBookID),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.token != "" {
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: tc.token})
+			}
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("user lists", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			token         string
+			path          string
+			expectedLists int
+		}{
+			{
+				name:          "public lists",
+				path:          fmt.Sprintf("/api/v1/users/%v/lists", ownerID),
+				expectedLists: 1,
+			},
+			{
+				name:          "own lists",
+				token:         ownerToken,
+				path:          "/api/v1/users/me/lists",
+				expectedLists: 2,
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+				if tc.token != "" {
+					r.AddCookie(&http.Cookie{Name: "access_token", Value: tc.token})
+				}
+				rr := httptest.NewRecorder()
+
+				svr.router.ServeHTTP(rr, r)
+
+				if rr.Code != http.StatusOK {
+					t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+				}
+
+				var resp struct {
+					Lists []struct {
+						Id    string `json:"id"`
+						Books []struct {
+							Id string `json:"id"`
+						} `json:"books"`
+					} `json:"lists"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+					t.Fatal(err.Error())
+				}
+
+				if len(resp.Lists) != tc.expectedLists {
+					t.Fatalf("expected %d lists, got %d", tc.expectedLists, len(resp.Lists))
+				}
+
+				for _, l := range resp.Lists {
+					if l.Id == readingListID && (len(l.Books) != 2 || l.Books[0].Id != otherBookID || l.Books[1].Id != ownBookID) {
+						t.Fatalf("expected reading list to hold %s then %s, got %v", otherBookID, ownBookID, l.Books)
+					}
+				}
+			})
+		}
+	})
+}
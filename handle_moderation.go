@@ -0,0 +1,641 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// moderationClaimTTL is how long an admin keeps a book to themselves before
+// other admins can pick it up
+func moderationClaimTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("MODERATION_CLAIM_TTL")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// handleGetPendingBooks godoc
+//
+//	@Summary		Get pending books
+//	@Description	Get the books waiting for approval, oldest first unless sort is newest
+//	@Tags			admin
+//	@Produce		json
+//	@Param			offset		query		string		true	"offset"
+//	@Param			limit		query		string		true	"limit"
+//	@Param			genre		query		[]string	false	"genres"
+//	@Param			language	query		[]string	false	"BCP 47 language codes"
+//	@Param			claim		query		string		false	"mine or unclaimed"
+//	@Param			sort		query		string		false	"oldest or newest"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		401			{object}	errorResponse
+//	@Failure		403			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		200			{object}	main.handleGetPendingBooks.response
+//	@Router			/admin/books/pending [get]
+func (s *server) handleGetPendingBooks(w http.ResponseWriter, r *http.Request) {
+	type responseBook struct {
+		Id            string   `json:"id"`
+		Name          string   `json:"name"`
+		AuthorId      *string  `json:"authorId"`
+		Author        *string  `json:"author"`
+		Language      string   `json:"language"`
+		Genres        []string `json:"genres"`
+		ChapterCount  int      `json:"chapterCount"`
+		ClaimedBy     *string  `json:"claimedBy"`
+		ClaimedByName *string  `json:"claimedByName"`
+		ClaimedUntil  *string  `json:"claimedUntil"`
+		// PlagiarismOverlap is the share of the book found in the earlier
+		// book by another author it overlaps with the most
+		PlagiarismOverlap *float64 `json:"plagiarismOverlap"`
+		CreatedAt         string   `json:"createdAt"`
+	}
+
+	type response struct {
+		Books []responseBook `json:"books"`
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "offset should be a valid number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "limit should be a valid number"})
+		return
+	}
+
+	claim := r.URL.Query().Get("claim")
+	if claim != "" && claim != "mine" && claim != "unclaimed" {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "claim should be mine or unclaimed"})
+		return
+	}
+
+	books, err := s.getPendingBooks(r.Context(), pendingBooksFilter{
+		languages:   r.URL.Query()["language"],
+		genres:      r.URL.Query()["genre"],
+		claim:       claim,
+		moderatorID: r.Context().Value("user").(string),
+		newestFirst: r.URL.Query().Get("sort") == "newest",
+		offset:      offset,
+		limit:       limit,
+	})
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseBook{}
+	for _, book := range books {
+		item := responseBook{Id: book.id, Name: book.name, Language: book.language, Genres: book.genres, ChapterCount: book.chapterCount, CreatedAt: book.createdAt.Format(time.RFC3339)}
+
+		if book.authorID.Valid {
+			item.AuthorId = &book.authorID.String
+			item.Author = &book.authorName.String
+		}
+
+		if book.claimedBy.Valid {
+			claimedUntil := book.claimedUntil.Time.Format(time.RFC3339)
+			item.ClaimedBy = &book.claimedBy.String
+			item.ClaimedByName = &book.claimedByName.String
+			item.ClaimedUntil = &claimedUntil
+		}
+
+		if book.plagiarismOverlap.Valid {
+			item.PlagiarismOverlap = &book.plagiarismOverlap.Float64
+		}
+
+		resp = append(resp, item)
+	}
+
+	encode(w, http.StatusOK, &response{Books: resp})
+}
+
+// moderationError answers with the status matching an error returned while
+// moderating a book
+func (s *server) moderationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBookNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errBookNotPending), errors.Is(err, errBookClaimed):
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errNotModerator):
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+// handleClaimBook godoc
+//
+//	@Summary		Claim book for review
+//	@Description	Claim a pending book so other admins don't review it at the same time, or assign it to another moderator. Claims expire after MODERATION_CLAIM_TTL.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			bookID	path		string							true	"book id"
+//	@Param			param	body		main.handleClaimBook.request	false	"claim book body, leave out to claim for yourself"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleClaimBook.response
+//	@Router			/admin/books/{bookID}/claim [post]
+func (s *server) handleClaimBook(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		AssigneeId string `json:"assigneeId" validate:"omitempty,uuid"`
+	}
+
+	type response struct {
+		ClaimedBy    string `json:"claimedBy"`
+		ClaimedUntil string `json:"claimedUntil"`
+	}
+
+	moderatorID := r.Context().Value("user").(string)
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	var params request
+	if r.ContentLength != 0 {
+		if err := decode(r, &params); err != nil {
+			if errors.Is(err, errValidation) {
+				encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+				return
+			}
+			encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+			return
+		}
+	}
+
+	assigneeID := params.AssigneeId
+	if assigneeID == "" {
+		assigneeID = moderatorID
+	}
+
+	claimedUntil, err := s.claimBook(r.Context(), moderatorID, assigneeID, bookID, moderationClaimTTL())
+	if err != nil {
+		s.moderationError(w, err)
+		return
+	}
+
+	if err := s.publishEvent(r.Context(), newEvent(moderationUpdatedEvent{BookId: bookID, Status: moderationPending, ClaimedBy: assigneeID, ClaimedUntil: &claimedUntil})); err != nil {
+		s.logger.Error(err.Error())
+	}
+
+	encode(w, http.StatusOK, &response{ClaimedBy: assigneeID, ClaimedUntil: claimedUntil.Format(time.RFC3339)})
+}
+
+// handleReleaseBook godoc
+//
+//	@Summary		Release book
+//	@Description	Give up the claim on a pending book so other admins can review it
+//	@Tags			admin
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/books/{bookID}/claim [delete]
+func (s *server) handleReleaseBook(w http.ResponseWriter, r *http.Request) {
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	if err := s.releaseBook(r.Context(), r.Context().Value("user").(string), bookID); err != nil {
+		s.moderationError(w, err)
+		return
+	}
+
+	if err := s.publishEvent(r.Context(), newEvent(moderationUpdatedEvent{BookId: bookID, Status: moderationPending})); err != nil {
+		s.logger.Error(err.Error())
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+type moderationRequest struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+// approveBookRequest is a moderationRequest that also acknowledges the book's
+// plagiarism report, which books overlapping earlier books by other authors
+// can't be approved without
+type approveBookRequest struct {
+	moderationRequest
+	PlagiarismReviewed bool `json:"plagiarismReviewed"`
+}
+
+// decideOnBook approves or rejects the book in the url and lets the author and
+// the other admins know
+func (s *server) decideOnBook(w http.ResponseWriter, r *http.Request, approve bool) {
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	var params approveBookRequest
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if approve && !params.PlagiarismReviewed {
+		overlap, err := s.getPlagiarismOverlap(r.Context(), bookID)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if overlap.Valid {
+			encode(w, http.StatusConflict, &errorResponse{Error: fmt.Sprintf("book overlaps %.0f%% with an earlier book by another author, review its plagiarism report and set plagiarismReviewed", overlap.Float64*100)})
+			return
+		}
+	}
+
+	authorID, err := s.moderateBook(r.Context(), r.Context().Value("user").(string), bookID, approve, params.Reason)
+	if err != nil {
+		s.moderationError(w, err)
+		return
+	}
+
+	status := moderationApproved
+	if !approve {
+		status = moderationRejected
+	}
+
+	if authorID != "" {
+		if err := s.publishEvent(r.Context(), newEvent(bookModeratedEvent{BookId: bookID, AuthorId: authorID, Status: status, Reason: params.Reason, Message: fmt.Sprintf("your book was %s", status)})); err != nil {
+			s.logger.Error(err.Error())
+		}
+	}
+
+	if err := s.publishEvent(r.Context(), newEvent(moderationUpdatedEvent{BookId: bookID, Status: status})); err != nil {
+		s.logger.Error(err.Error())
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleApproveBook godoc
+//
+//	@Summary		Approve book
+//	@Description	Approve a pending book. Books claimed by another admin can't be approved, and books overlapping earlier books by other authors need plagiarismReviewed.
+//	@Tags			admin
+//	@Accept			json
+//	@Param			bookID	path		string					true	"book id"
+//	@Param			param	body		main.approveBookRequest	true	"approve book body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/books/{bookID}/approve [post]
+func (s *server) handleApproveBook(w http.ResponseWriter, r *http.Request) {
+	s.decideOnBook(w, r, true)
+}
+
+// handleRejectBook godoc
+//
+//	@Summary		Reject book
+//	@Description	Reject a pending book. Books claimed by another admin can't be rejected.
+//	@Tags			admin
+//	@Accept			json
+//	@Param			bookID	path		string					true	"book id"
+//	@Param			param	body		main.moderationRequest	true	"reject book body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/books/{bookID}/reject [post]
+func (s *server) handleRejectBook(w http.ResponseWriter, r *http.Request) {
+	s.decideOnBook(w, r, false)
+}
+
+// handleGetModerationHistory godoc
+//
+//	@Summary		Get moderation history
+//	@Description	Get every claim, release and decision made on a book and its chapters, newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetModerationHistory.response
+//	@Router			/admin/books/{bookID}/moderation [get]
+func (s *server) handleGetModerationHistory(w http.ResponseWriter, r *http.Request) {
+	type responseAction struct {
+		Id          string  `json:"id"`
+		ChapterId   *string `json:"chapterId"`
+		ModeratorId *string `json:"moderatorId"`
+		Moderator   *string `json:"moderator"`
+		Action      string  `json:"action"`
+		AssigneeId  *string `json:"assigneeId"`
+		Reason      *string `json:"reason"`
+		CreatedAt   string  `json:"createdAt"`
+	}
+
+	type response struct {
+		Actions []responseAction `json:"actions"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	actions, err := s.getModerationHistory(r.Context(), bookID)
+	if err != nil {
+		s.moderationError(w, err)
+		return
+	}
+
+	resp := []responseAction{}
+	for _, a := range actions {
+		item := responseAction{Id: a.id, Action: a.action, CreatedAt: a.createdAt.Format(time.RFC3339)}
+
+		if a.chapterID.Valid {
+			item.ChapterId = &a.chapterID.String
+		}
+		if a.moderatorID.Valid {
+			item.ModeratorId = &a.moderatorID.String
+			item.Moderator = &a.moderatorName.String
+		}
+		if a.assigneeID.Valid {
+			item.AssigneeId = &a.assigneeID.String
+		}
+		if a.reason.Valid {
+			item.Reason = &a.reason.String
+		}
+
+		resp = append(resp, item)
+	}
+
+	encode(w, http.StatusOK, &response{Actions: resp})
+}
+
+// handleGetPlagiarismReport godoc
+//
+//	@Summary		Get plagiarism report
+//	@Description	Get the earlier books by other authors a book overlaps with, by share of the book's fingerprints found in them, with the chapters that overlap
+//	@Tags			admin
+//	@Produce		json
+//	@Param			bookID	path		string	true	"book id"
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetPlagiarismReport.response
+//	@Router			/admin/books/{bookID}/plagiarism [get]
+func (s *server) handleGetPlagiarismReport(w http.ResponseWriter, r *http.Request) {
+	type responseChapter struct {
+		ChapterId       string `json:"chapterId"`
+		ChapterNo       int    `json:"chapterNo"`
+		SourceChapterId string `json:"sourceChapterId"`
+		SourceChapterNo int    `json:"sourceChapterNo"`
+		Shared          int    `json:"shared"`
+	}
+
+	type responseMatch struct {
+		SourceBookId   string            `json:"sourceBookId"`
+		SourceBook     string            `json:"sourceBook"`
+		SourceAuthorId *string           `json:"sourceAuthorId"`
+		SourceAuthor   *string           `json:"sourceAuthor"`
+		Shared         int               `json:"shared"`
+		Total          int               `json:"total"`
+		Overlap        float64           `json:"overlap"`
+		Chapters       []responseChapter `json:"chapters"`
+		UpdatedAt      string            `json:"updatedAt"`
+	}
+
+	type response struct {
+		Matches []responseMatch `json:"matches"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	matches, err := s.getPlagiarismMatches(r.Context(), bookID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseMatch{}
+	for _, m := range matches {
+		item := responseMatch{SourceBookId: m.sourceBookID, SourceBook: m.sourceBookName, Shared: m.shared, Total: m.total, Overlap: m.overlap, Chapters: []responseChapter{}, UpdatedAt: m.updatedAt.Format(time.RFC3339)}
+
+		if m.sourceAuthorID.Valid {
+			item.SourceAuthorId = &m.sourceAuthorID.String
+			item.SourceAuthor = &m.sourceAuthorName.String
+		}
+
+		for _, c := range m.chapters {
+			item.Chapters = append(item.Chapters, responseChapter{ChapterId: c.chapterID, ChapterNo: c.chapterNo, SourceChapterId: c.sourceChapterID, SourceChapterNo: c.sourceChapterNo, Shared: c.shared})
+		}
+
+		resp = append(resp, item)
+	}
+
+	encode(w, http.StatusOK, &response{Matches: resp})
+}
+
+// handleGetFlaggedChapters godoc
+//
+//	@Summary		Get flagged chapters
+//	@Description	Get the chapters automated moderation held back from publication, oldest first, with the verdict of every check
+//	@Tags			admin
+//	@Produce		json
+//	@Param			offset	query		string	true	"offset"
+//	@Param			limit	query		string	true	"limit"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetFlaggedChapters.response
+//	@Router			/admin/chapters/flagged [get]
+func (s *server) handleGetFlaggedChapters(w http.ResponseWriter, r *http.Request) {
+	type responseVerdict struct {
+		Check   string  `json:"check"`
+		Flagged bool    `json:"flagged"`
+		Score   float64 `json:"score"`
+		Details *string `json:"details"`
+	}
+
+	type responseChapter struct {
+		Id        string            `json:"id"`
+		BookId    string            `json:"bookId"`
+		Book      string            `json:"book"`
+		AuthorId  *string           `json:"authorId"`
+		ChapterNo int               `json:"chapterNo"`
+		Title     string            `json:"title"`
+		Verdicts  []responseVerdict `json:"verdicts"`
+		CreatedAt string            `json:"createdAt"`
+	}
+
+	type response struct {
+		Chapters []responseChapter `json:"chapters"`
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "offset should be a valid number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "limit should be a valid number"})
+		return
+	}
+
+	chapters, err := s.getFlaggedChapters(r.Context(), offset, limit)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseChapter{}
+	for _, ch := range chapters {
+		item := responseChapter{Id: ch.id, BookId: ch.bookID, Book: ch.bookName, ChapterNo: ch.chapterNo, Title: ch.title, Verdicts: []responseVerdict{}, CreatedAt: ch.createdAt.Format(time.RFC3339)}
+
+		if ch.authorID.Valid {
+			item.AuthorId = &ch.authorID.String
+		}
+
+		for _, v := range ch.verdicts {
+			verdict := responseVerdict{Check: v.check, Flagged: v.flagged, Score: v.score}
+			if v.details.Valid {
+				verdict.Details = &v.details.String
+			}
+			item.Verdicts = append(item.Verdicts, verdict)
+		}
+
+		resp = append(resp, item)
+	}
+
+	encode(w, http.StatusOK, &response{Chapters: resp})
+}
+
+// decideOnChapter approves or rejects the flagged chapter in the url. Approved
+// chapters are published right away.
+func (s *server) decideOnChapter(w http.ResponseWriter, r *http.Request, approve bool) {
+	chapterID := chi.URLParam(r, "chapterID")
+
+	if err := validate.Var(chapterID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errChapterNotFound.Error()})
+		return
+	}
+
+	var params moderationRequest
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.moderateChapter(r.Context(), r.Context().Value("user").(string), chapterID, approve, params.Reason); err != nil {
+		switch {
+		case errors.Is(err, errChapterNotFound):
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+		case errors.Is(err, errChapterNotFlagged):
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+		default:
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		}
+		return
+	}
+
+	if approve {
+		if err := s.publishChapter(r.Context(), chapterID); err != nil {
+			s.logger.Error(err.Error())
+		}
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleApproveChapter godoc
+//
+//	@Summary		Approve chapter
+//	@Description	Publish a chapter automated moderation held back
+//	@Tags			admin
+//	@Accept			json
+//	@Param			chapterID	path		string					true	"chapter id"
+//	@Param			param		body		main.moderationRequest	true	"approve chapter body"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		401			{object}	errorResponse
+//	@Failure		403			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/chapters/{chapterID}/approve [post]
+func (s *server) handleApproveChapter(w http.ResponseWriter, r *http.Request) {
+	s.decideOnChapter(w, r, true)
+}
+
+// handleRejectChapter godoc
+//
+//	@Summary		Reject chapter
+//	@Description	Keep a chapter automated moderation held back from being published
+//	@Tags			admin
+//	@Accept			json
+//	@Param			chapterID	path		string					true	"chapter id"
+//	@Param			param		body		main.moderationRequest	true	"reject chapter body"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		401			{object}	errorResponse
+//	@Failure		403			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/chapters/{chapterID}/reject [post]
+func (s *server) handleRejectChapter(w http.ResponseWriter, r *http.Request) {
+	s.decideOnChapter(w, r, false)
+}
@@ -0,0 +1,683 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func uploadPendingBook(t *testing.T, svr *server, authorID string) string {
+	bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book " + uuid.NewString(), description: "test-book description", authorID: authorID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bookID
+}
+
+func makeAdmin(t *testing.T, svr *server) {
+	query :=
+		`
+			UPDATE users SET roles = ARRAY['ADMIN']::role_type[] WHERE display_name = 'test_display';
+		`
+	if _, err := svr.store.ExecContext(context.Background(), query); err != nil {
+		t.Fatalf("error updating users, %v", err)
+	}
+}
+
+// createOtherAdmin adds a second admin, cleaned up along with the test user
+func createOtherAdmin(t *testing.T, svr *server) string {
+	var id string
+	query :=
+		`
+			INSERT INTO users (display_name, email, password, roles) VALUES ('other_admin', 'user@user.com', 'password', ARRAY['ADMIN']::role_type[]) RETURNING id;
+		`
+	if err := svr.store.QueryRowContext(context.Background(), query).Scan(&id); err != nil {
+		t.Fatalf("error creating admin, %v", err)
+	}
+	return id
+}
+
+func TestHandleModerateBook(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+
+	tests := []struct {
+		name         string
+		cookieName   string
+		cookieValue  string
+		bookID       string
+		decision     string
+		admin        bool
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "no access token cookie",
+			bookID:       uuid.NewString(),
+			decision:     "approve",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid/malformed token",
+			cookieName:   "access_token",
+			cookieValue:  "invalid token",
+			bookID:       uuid.NewString(),
+			decision:     "approve",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "role is not admin",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       uuid.NewString(),
+			decision:     "approve",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "reason is required",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       uploadPendingBook(t, svr, userID),
+			decision:     "reject",
+			admin:        true,
+			body:         moderationRequest{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "book not found",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       uuid.NewString(),
+			decision:     "approve",
+			admin:        true,
+			body:         moderationRequest{Reason: "looks good"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "book already decided on",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       createBook(t, userID, db),
+			decision:     "approve",
+			admin:        true,
+			body:         moderationRequest{Reason: "looks good"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "approve book",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       uploadPendingBook(t, svr, userID),
+			decision:     "approve",
+			admin:        true,
+			body:         moderationRequest{Reason: "looks good"},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "reject book",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			bookID:       uploadPendingBook(t, svr, userID),
+			decision:     "reject",
+			admin:        true,
+			body:         moderationRequest{Reason: "copied from another site"},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.admin {
+				makeAdmin(t, svr)
+			}
+
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/books/%v/%v", tc.bookID, tc.decision), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: tc.cookieName, Value: tc.cookieValue})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleClaimBook(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+	otherAdminID := createOtherAdmin(t, svr)
+
+	claimedByOther := uploadPendingBook(t, svr, userID)
+	if _, err := svr.claimBook(context.Background(), otherAdminID, otherAdminID, claimedByOther, time.Minute); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		bookID       string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "book not found",
+			bookID:       uuid.NewString(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "assignee is not an admin",
+			bookID:       uploadPendingBook(t, svr, userID),
+			body:         map[string]string{"assigneeId": uuid.NewString()},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "claimed by another admin",
+			bookID:       claimedByOther,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "claim book",
+			bookID:       uploadPendingBook(t, svr, userID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "assign book",
+			bookID:       uploadPendingBook(t, svr, userID),
+			body:         map[string]string{"assigneeId": otherAdminID},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/books/%v/claim", tc.bookID), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	// a claimed book can't be decided on by anyone else
+	if _, err := svr.moderateBook(context.Background(), userID, claimedByOther, true, "looks good"); err != errBookClaimed {
+		t.Fatalf("expected %v, got %v", errBookClaimed, err)
+	}
+}
+
+func TestHandleGetPendingBooks(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+
+	claimed := uploadPendingBook(t, svr, userID)
+	unclaimed := uploadPendingBook(t, svr, userID)
+	createBook(t, userID, db)
+
+	if _, err := svr.claimBook(context.Background(), userID, userID, claimed, time.Minute); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedCode  int
+		expectedBooks []string
+	}{
+		{
+			name:         "invalid offset",
+			query:        "offset=a&limit=10",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid claim",
+			query:        "offset=0&limit=10&claim=theirs",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:          "every pending book",
+			query:         "offset=0&limit=10",
+			expectedCode:  http.StatusOK,
+			expectedBooks: []string{claimed, unclaimed},
+		},
+		{
+			name:          "newest first",
+			query:         "offset=0&limit=10&sort=newest",
+			expectedCode:  http.StatusOK,
+			expectedBooks: []string{unclaimed, claimed},
+		},
+		{
+			name:          "claimed by me",
+			query:         "offset=0&limit=10&claim=mine",
+			expectedCode:  http.StatusOK,
+			expectedBooks: []string{claimed},
+		},
+		{
+			name:          "unclaimed",
+			query:         "offset=0&limit=10&claim=unclaimed&language=en&genre=Action",
+			expectedCode:  http.StatusOK,
+			expectedBooks: []string{unclaimed},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/admin/books/pending?"+tc.query, nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Books []struct {
+					Id string `json:"id"`
+				} `json:"books"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(resp.Books) != len(tc.expectedBooks) {
+				t.Fatalf("expected %d books, got %d", len(tc.expectedBooks), len(resp.Books))
+			}
+			for i, id := range tc.expectedBooks {
+				if resp.Books[i].Id != id {
+					t.Fatalf("expected book %d to be %s, got %s", i, id, resp.Books[i].Id)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleGetModerationHistory(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+
+	bookID := uploadPendingBook(t, svr, userID)
+	if _, err := svr.claimBook(context.Background(), userID, userID, bookID, time.Minute); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := svr.moderateBook(context.Background(), userID, bookID, false, "copied from another site"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name            string
+		bookID          string
+		expectedCode    int
+		expectedActions []string
+	}{
+		{
+			name:         "book not found",
+			bookID:       uuid.NewString(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:            "get history",
+			bookID:          bookID,
+			expectedCode:    http.StatusOK,
+			expectedActions: []string{"reject", "claim"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/admin/books/%v/moderation", tc.bookID), nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Actions []struct {
+					Action string `json:"action"`
+				} `json:"actions"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(resp.Actions) != len(tc.expectedActions) {
+				t.Fatalf("expected %d actions, got %d", len(tc.expectedActions), len(resp.Actions))
+			}
+			for i, action := range tc.expectedActions {
+				if resp.Actions[i].Action != action {
+					t.Fatalf("expected action %d to be %s, got %s", i, action, resp.Actions[i].Action)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleModerateChapter(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+	makeAdmin(t, svr)
+	bookID := createBook(t, userID, db)
+
+	flagChapter := func(chapterNo int) string {
+		chapterID, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: chapterNo, content: "test chapter content", bookID: bookID})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		query :=
+			`
+				WITH flagged AS (
+					UPDATE chapters SET moderation_status = 'flagged' WHERE id = $1
+				)
+				INSERT INTO chapter_verdicts (chapter_id, check_name, flagged, score, details) VALUES ($1, 'links', true, 12, '12 links, at most 3 are allowed');
+			`
+		if _, err := db.ExecContext(context.Background(), query, chapterID); err != nil {
+			t.Fatalf("error flagging chapter, %v", err)
+		}
+		return chapterID
+	}
+
+	approved := flagChapter(1)
+	rejected := flagChapter(2)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/admin/chapters/flagged?offset=0&limit=10", nil)
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rr := httptest.NewRecorder()
+
+	svr.router.ServeHTTP(rr, r)
+
+	var flagged struct {
+		Chapters []struct {
+			Id       string `json:"id"`
+			Verdicts []struct {
+				Check string `json:"check"`
+			} `json:"verdicts"`
+		} `json:"chapters"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&flagged); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(flagged.Chapters) != 2 || flagged.Chapters[0].Id != approved || len(flagged.Chapters[0].Verdicts) != 1 {
+		t.Fatalf("expected both flagged chapters with their verdicts, got %+v", flagged)
+	}
+
+	tests := []struct {
+		name         string
+		chapterID    string
+		decision     string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "chapter not found",
+			chapterID:    uuid.NewString(),
+			decision:     "approve",
+			body:         moderationRequest{Reason: "links are to the author's site"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "reason is required",
+			chapterID:    approved,
+			decision:     "approve",
+			body:         moderationRequest{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "approve chapter",
+			chapterID:    approved,
+			decision:     "approve",
+			body:         moderationRequest{Reason: "links are to the author's site"},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "chapter not flagged",
+			chapterID:    approved,
+			decision:     "reject",
+			body:         moderationRequest{Reason: "spam"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "reject chapter",
+			chapterID:    rejected,
+			decision:     "reject",
+			body:         moderationRequest{Reason: "spam"},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/chapters/%v/%v", tc.chapterID, tc.decision), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	if _, err := svr.getChapter(context.Background(), userID, approved); err != nil {
+		t.Fatalf("expected approved chapter to be published, got %v", err)
+	}
+	if _, err := svr.getChapter(context.Background(), userID, rejected); err != errChapterNotFound {
+		t.Fatalf("expected rejected chapter to stay hidden, got %v", err)
+	}
+}
+
+func TestHandleGetPlagiarismReport(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+
+	content := "The rain had not stopped for three days, and the river at the edge of the village was already climbing over the old stone wall that nobody had repaired since the war."
+
+	upload := func(authorID string) string {
+		bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book " + uuid.NewString(), description: "test-book description", authorID: authorID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: content}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bookID
+	}
+
+	sourceID := upload(createOtherAdmin(t, svr))
+	copyID := upload(userID)
+	ownBookID := upload(userID)
+
+	// fingerprinting and matching happen in the chapter moderation worker, what
+	// it would store for these books is written here
+	for _, bookID := range []string{sourceID, copyID, ownBookID} {
+		query :=
+			`
+				INSERT INTO chapter_fingerprints (chapter_id, hash, position)
+				SELECT c.id, h, h::int FROM chapters c, unnest(ARRAY[1, 2, 3]::bigint[]) AS h WHERE c.book_id = $1;
+			`
+		if _, err := db.ExecContext(context.Background(), query, bookID); err != nil {
+			t.Fatalf("error inserting chapter fingerprints, %v", err)
+		}
+	}
+
+	for _, bookID := range []string{copyID, ownBookID} {
+		query :=
+			`
+				INSERT INTO plagiarism_matches (book_id, source_book_id, shared, total, overlap) VALUES ($1, $2, 3, 3, 1);
+			`
+		if _, err := db.ExecContext(context.Background(), query, bookID, sourceID); err != nil {
+			t.Fatalf("error inserting plagiarism matches, %v", err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		bookID          string
+		expectedCode    int
+		expectedSources []string
+	}{
+		{
+			name:         "book not found",
+			bookID:       "invalid id",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:            "copied from another author",
+			bookID:          copyID,
+			expectedCode:    http.StatusOK,
+			expectedSources: []string{sourceID},
+		},
+		{
+			name:            "earlier book by the same author isn't reported",
+			bookID:          ownBookID,
+			expectedCode:    http.StatusOK,
+			expectedSources: []string{sourceID},
+		},
+		{
+			name:            "earliest book",
+			bookID:          sourceID,
+			expectedCode:    http.StatusOK,
+			expectedSources: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/admin/books/%v/plagiarism", tc.bookID), nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Matches []struct {
+					SourceBookId string `json:"sourceBookId"`
+					Chapters     []any  `json:"chapters"`
+				} `json:"matches"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(resp.Matches) != len(tc.expectedSources) {
+				t.Fatalf("expected %d matches, got %d", len(tc.expectedSources), len(resp.Matches))
+			}
+			for i, source := range tc.expectedSources {
+				if resp.Matches[i].SourceBookId != source {
+					t.Fatalf("expected match %d to be %s, got %s", i, source, resp.Matches[i].SourceBookId)
+				}
+				if len(resp.Matches[i].Chapters) == 0 {
+					t.Fatalf("expected match %d to list the overlapping chapters", i)
+				}
+			}
+		})
+	}
+
+	approveTests := []struct {
+		name         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "plagiarism report not reviewed",
+			body:         approveBookRequest{moderationRequest: moderationRequest{Reason: "looks good"}},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "plagiarism report reviewed",
+			body:         approveBookRequest{moderationRequest: moderationRequest{Reason: "licensed translation"}, PlagiarismReviewed: true},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range approveTests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/books/%v/approve", copyID), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// reportHideThreshold is how many people have to report the same content
+// before it is hidden until an admin reviews it
+func reportHideThreshold() int {
+	if n, err := strconv.Atoi(os.Getenv("REPORT_HIDE_THRESHOLD")); err == nil && n > 0 {
+		return n
+	}
+	return 3
+}
+
+// reportTrustedAccountAge is how old an account has to be before its reports
+// count towards hiding content, so a few throwaway accounts can't take
+// anything down
+func reportTrustedAccountAge() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("REPORT_TRUSTED_ACCOUNT_DAYS")); err == nil && days >= 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// handleCreateReport godoc
+//
+//	@Summary		Report content
+//	@Description	Report a book, chapter or user. Content reported by enough people with established accounts is hidden until an admin reviews it.
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleCreateReport.request	true	"report body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201		{object}	main.handleCreateReport.response
+//	@Router			/reports [post]
+func (s *server) handleCreateReport(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		TargetType string `json:"targetType" validate:"required,oneof=book chapter user"`
+		TargetId   string `json:"targetId" validate:"required,uuid"`
+		Category   string `json:"category" validate:"required,oneof=plagiarism abuse inappropriate spam other"`
+		Details    string `json:"details" validate:"max=2000"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	userID := r.Context().Value("user").(string)
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if params.TargetType == "user" && params.TargetId == userID {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "you can't report yourself"})
+		return
+	}
+
+	rep := &report{
+		reporterID: sql.NullString{String: userID, Valid: true},
+		targetType: params.TargetType,
+		targetID:   params.TargetId,
+		category:   params.Category,
+		details:    sql.NullString{String: params.Details, Valid: params.Details != ""},
+	}
+
+	openReports, hidden, err := s.createReport(r.Context(), rep, reportHideThreshold(), reportTrustedAccountAge())
+	if err != nil {
+		switch {
+		case errors.Is(err, errReportTargetNotFound):
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+		case errors.Is(err, errAlreadyReported):
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+		default:
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		}
+		return
+	}
+
+	e := newEvent(reportEvent{
+		ReportId:    rep.id,
+		TargetType:  rep.targetType,
+		TargetId:    rep.targetID,
+		Category:    rep.category,
+		OpenReports: openReports,
+		Hidden:      hidden,
+		Message:     fmt.Sprintf("%s reported for %s", rep.targetType, rep.category),
+	})
+	if err := s.publishEvent(r.Context(), e); err != nil {
+		s.logger.Error(err.Error())
+	}
+
+	encode(w, http.StatusCreated, &response{Id: rep.id})
+}
+
+// handleGetReports godoc
+//
+//	@Summary		Get reports
+//	@Description	Get reports for triage, the most reported content first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			offset		query		string	true	"offset"
+//	@Param			limit		query		string	true	"limit"
+//	@Param			status		query		string	false	"open (default), dismissed or upheld"
+//	@Param			targetType	query		string	false	"book, chapter or user"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		401			{object}	errorResponse
+//	@Failure		403			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		200			{object}	main.handleGetReports.response
+//	@Router			/admin/reports [get]
+func (s *server) handleGetReports(w http.ResponseWriter, r *http.Request) {
+	type responseReport struct {
+		Id           string  `json:"id"`
+		ReporterId   *string `json:"reporterId"`
+		Reporter     *string `json:"reporter"`
+		TargetType   string  `json:"targetType"`
+		TargetId     string  `json:"targetId"`
+		Category     string  `json:"category"`
+		Details      *string `json:"details"`
+		Status       string  `json:"status"`
+		ResolvedBy   *string `json:"resolvedBy"`
+		Resolution   *string `json:"resolution"`
+		ResolvedAt   *string `json:"resolvedAt"`
+		CreatedAt    string  `json:"createdAt"`
+		OpenReports  int     `json:"openReports"`
+		TargetHidden bool    `json:"targetHidden"`
+	}
+
+	type response struct {
+		Reports []responseReport `json:"reports"`
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "offset should be a valid number"})
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "limit should be a valid number"})
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportOpen
+	}
+
+	if err := validate.Var(status, "oneof=open dismissed upheld"); err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "status should be open, dismissed or upheld"})
+		return
+	}
+
+	targetType := r.URL.Query().Get("targetType")
+	if _, ok := reportTargets[targetType]; targetType != "" && !ok {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "targetType should be book, chapter or user"})
+		return
+	}
+
+	reports, err := s.getReports(r.Context(), reportsFilter{status: status, targetType: targetType, offset: offset, limit: limit})
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := []responseReport{}
+	for _, rep := range reports {
+		item := responseReport{
+			Id:           rep.id,
+			TargetType:   rep.targetType,
+			TargetId:     rep.targetID,
+			Category:     rep.category,
+			Status:       rep.status,
+			CreatedAt:    rep.createdAt.Format(time.RFC3339),
+			OpenReports:  rep.openReports,
+			TargetHidden: rep.targetHidden,
+		}
+
+		if rep.reporterID.Valid {
+			item.ReporterId = &rep.reporterID.String
+			item.Reporter = &rep.reporterName.String
+		}
+		if rep.details.Valid {
+			item.Details = &rep.details.String
+		}
+		if rep.resolvedBy.Valid {
+			item.ResolvedBy = &rep.resolvedBy.String
+		}
+		if rep.resolution.Valid {
+			item.Resolution = &rep.resolution.String
+		}
+		if rep.resolvedAt.Valid {
+			resolvedAt := rep.resolvedAt.Time.Format(time.RFC3339)
+			item.ResolvedAt = &resolvedAt
+		}
+
+		resp = append(resp, item)
+	}
+
+	encode(w, http.StatusOK, &response{Reports: resp})
+}
+
+// handleResolveReport godoc
+//
+//	@Summary		Resolve report
+//	@Description	Uphold or dismiss a report along with every other open report on the same content. Upheld content stays hidden, dismissed content is shown again.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			reportID	path		string							true	"report id"
+//	@Param			param		body		main.handleResolveReport.request	true	"resolve report body"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		401			{object}	errorResponse
+//	@Failure		403			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		200			{object}	main.handleResolveReport.response
+//	@Router			/admin/reports/{reportID} [patch]
+func (s *server) handleResolveReport(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Status     string `json:"status" validate:"required,oneof=dismissed upheld"`
+		Resolution string `json:"resolution" validate:"required,max=1000"`
+	}
+
+	type response struct {
+		Resolved int `json:"resolved"`
+	}
+
+	reportID := chi.URLParam(r, "reportID")
+
+	if err := validate.Var(reportID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errReportNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	resolved, err := s.resolveReport(r.Context(), r.Context().Value("user").(string), reportID, params.Status, params.Resolution)
+	if err != nil {
+		switch {
+		case errors.Is(err, errReportNotFound):
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+		case errors.Is(err, errReportResolved):
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+		default:
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		}
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Resolved: resolved})
+}
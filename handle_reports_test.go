@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleCreateReport(t *testing.T) {
+	type request struct {
+		TargetType string `json:"targetType"`
+		TargetId   string `json:"targetId"`
+		Category   string `json:"category"`
+		Details    string `json:"details"`
+	}
+
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	svr.limits["report"] = rateLimit{requests: 100, per: time.Minute}
+	bookID := createBook(t, userID, db)
+
+	tests := []struct {
+		name         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "validation error",
+			body:         request{TargetType: "comment", TargetId: bookID, Category: "spam"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "report yourself",
+			body:         request{TargetType: "user", TargetId: userID, Category: "abuse"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "content not found",
+			body:         request{TargetType: "chapter", TargetId: uuid.NewString(), Category: "inappropriate"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "report book",
+			body:         request{TargetType: "book", TargetId: bookID, Category: "plagiarism", Details: "copied from another site"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "already reported",
+			body:         request{TargetType: "book", TargetId: bookID, Category: "spam"},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/reports", bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestReportThreshold(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	makeAdmin(t, svr)
+	otherID := createOtherAdmin(t, svr)
+	bookID := createBook(t, userID, db)
+
+	for _, reporterID := range []string{userID, otherID} {
+		if _, _, err := svr.createReport(context.Background(), &report{reporterID: sql.NullString{String: reporterID, Valid: true}, targetType: "book", targetID: bookID, category: "spam"}, 2, 0); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if err := svr.checkIfBookExists(context.Background(), bookID); err != errBookNotFound {
+		t.Fatalf("expected reported book to be hidden, got %v", err)
+	}
+
+	reports, err := svr.getReports(context.Background(), reportsFilter{status: reportOpen, targetType: "book", limit: 10})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(reports) != 2 || reports[0].openReports != 2 || !reports[0].targetHidden {
+		t.Fatalf("expected 2 open reports on a hidden book, got %+v", reports)
+	}
+
+	tests := []struct {
+		name         string
+		reportID     string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "report not found",
+			reportID:     uuid.NewString(),
+			body:         map[string]string{"status": "dismissed", "resolution": "not spam"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "resolution is required",
+			reportID:     reports[0].id,
+			body:         map[string]string{"status": "dismissed"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "dismiss reports",
+			reportID:     reports[0].id,
+			body:         map[string]string{"status": "dismissed", "resolution": "not spam"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "already resolved",
+			reportID:     reports[1].id,
+			body:         map[string]string{"status": "upheld", "resolution": "spam"},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/admin/reports/%v", tc.reportID), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	if err := svr.checkIfBookExists(context.Background(), bookID); err != nil {
+		t.Fatalf("expected dismissed book to be shown again, got %v", err)
+	}
+}
+
+func TestReportThresholdNewAccounts(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	otherID := createAndCleanUpFollowed(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	bookID := createBook(t, userID, db)
+
+	var openReports int
+	var hidden bool
+	var err error
+
+	for _, reporterID := range []string{userID, otherID} {
+		openReports, hidden, err = svr.createReport(context.Background(), &report{reporterID: sql.NullString{String: reporterID, Valid: true}, targetType: "book", targetID: bookID, category: "spam"}, 2, 24*time.Hour)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if openReports != 2 || hidden {
+		t.Fatalf("expected 2 open reports on a visible book, got %d reports, hidden %v", openReports, hidden)
+	}
+}
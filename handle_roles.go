@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// grantableRoles are the roles admins hand out. Every user keeps REGULAR.
+const grantableRoles = "ADMIN MODERATOR AUTHOR"
+
+// roleError answers with the status matching an error returned while changing
+// a user's roles
+func (s *server) roleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errUserNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errRoleAlreadyGranted), errors.Is(err, errRoleNotGranted):
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errRevokeOwnAdmin):
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+// handleGetRoles godoc
+//
+//	@Summary		Get user roles
+//	@Description	Get a user's roles, what they allow and every time one was granted or revoked, newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		string	true	"user id"
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetRoles.response
+//	@Router			/admin/users/{userID}/roles [get]
+func (s *server) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	type responseChange struct {
+		Id            string  `json:"id"`
+		Role          string  `json:"role"`
+		Action        string  `json:"action"`
+		ChangedBy     *string `json:"changedBy"`
+		ChangedByName *string `json:"changedByName"`
+		Reason        *string `json:"reason"`
+		CreatedAt     string  `json:"createdAt"`
+	}
+
+	type response struct {
+		Roles       []string         `json:"roles"`
+		Permissions []string         `json:"permissions"`
+		History     []responseChange `json:"history"`
+	}
+
+	userID := chi.URLParam(r, "userID")
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errUserNotFound.Error()})
+		return
+	}
+
+	if err := s.checkIfUserExistsByID(r.Context(), userID); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	user, err := s.getUser(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	changes, err := s.getRoleChanges(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	permissions := []string{}
+	for _, role := range user.roles {
+		for _, p := range rolePermissions[role] {
+			if !slices.Contains(permissions, string(p)) {
+				permissions = append(permissions, string(p))
+			}
+		}
+	}
+	slices.Sort(permissions)
+
+	history := []responseChange{}
+	for _, c := range changes {
+		item := responseChange{Id: c.id, Role: c.role, Action: c.action, CreatedAt: c.createdAt.Format(time.RFC3339)}
+
+		if c.changedBy.Valid {
+			item.ChangedBy = &c.changedBy.String
+			item.ChangedByName = &c.changedByName.String
+		}
+
+		if c.reason.Valid {
+			item.Reason = &c.reason.String
+		}
+
+		history = append(history, item)
+	}
+
+	encode(w, http.StatusOK, &response{Roles: user.roles, Permissions: permissions, History: history})
+}
+
+// handleGrantRole godoc
+//
+//	@Summary		Grant role
+//	@Description	Give a user the ADMIN, MODERATOR or AUTHOR role
+//	@Tags			admin
+//	@Accept			json
+//	@Param			userID	path		string						true	"user id"
+//	@Param			param	body		main.handleGrantRole.request	true	"grant role body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/users/{userID}/roles [post]
+func (s *server) handleGrantRole(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Role   string `json:"role" validate:"required,oneof=ADMIN MODERATOR AUTHOR"`
+		Reason string `json:"reason" validate:"max=1000"`
+	}
+
+	userID := chi.URLParam(r, "userID")
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errUserNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.changeRole(r.Context(), r.Context().Value("user").(string), userID, params.Role, true, params.Reason); err != nil {
+		s.roleError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleRevokeRole godoc
+//
+//	@Summary		Revoke role
+//	@Description	Take the ADMIN, MODERATOR or AUTHOR role away from a user. Admins can't revoke their own admin role.
+//	@Tags			admin
+//	@Accept			json
+//	@Param			userID	path		string							true	"user id"
+//	@Param			role	path		string							true	"ADMIN, MODERATOR or AUTHOR"
+//	@Param			param	body		main.handleRevokeRole.request	false	"revoke role body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/users/{userID}/roles/{role} [delete]
+func (s *server) handleRevokeRole(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Reason string `json:"reason" validate:"max=1000"`
+	}
+
+	userID := chi.URLParam(r, "userID")
+	role := chi.URLParam(r, "role")
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errUserNotFound.Error()})
+		return
+	}
+
+	if err := validate.Var(role, "oneof="+grantableRoles); err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "role should be one of " + grantableRoles})
+		return
+	}
+
+	var params request
+	if r.ContentLength != 0 {
+		if err := decode(r, &params); err != nil {
+			if errors.Is(err, errValidation) {
+				encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+				return
+			}
+			encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+			return
+		}
+	}
+
+	if err := s.changeRole(r.Context(), r.Context().Value("user").(string), userID, role, false, params.Reason); err != nil {
+		s.roleError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
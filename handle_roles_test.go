@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleChangeRole(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	otherID := createAndCleanUpFollowed(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		admin        bool
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "missing manage_roles permission",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles", otherID),
+			body:         map[string]string{"role": "MODERATOR"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid role",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles", otherID),
+			admin:        true,
+			body:         map[string]string{"role": "REGULAR"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "user not found",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles", uuid.NewString()),
+			admin:        true,
+			body:         map[string]string{"role": "MODERATOR"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "grant role",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles", otherID),
+			admin:        true,
+			body:         map[string]string{"role": "MODERATOR", "reason": "helps with the queue"},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "role already granted",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles", otherID),
+			admin:        true,
+			body:         map[string]string{"role": "MODERATOR"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "revoke role",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles/MODERATOR", otherID),
+			admin:        true,
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "role not granted",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles/MODERATOR", otherID),
+			admin:        true,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "revoke own admin role",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/api/v1/admin/users/%v/roles/ADMIN", userID),
+			admin:        true,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.admin {
+				makeAdmin(t, svr)
+			}
+
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+			r := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("role history", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/admin/users/%v/roles", otherID), nil)
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Roles   []string `json:"roles"`
+			History []struct {
+				Action string `json:"action"`
+			} `json:"history"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(resp.Roles) != 1 || resp.Roles[0] != "REGULAR" {
+			t.Fatalf("expected only the REGULAR role, got %v", resp.Roles)
+		}
+		if len(resp.History) != 2 || resp.History[0].Action != "revoke" || resp.History[1].Action != "grant" {
+			t.Fatalf("expected a revoke after a grant, got %v", resp.History)
+		}
+	})
+}
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []string
+		allowed  []permission
+		disallow []permission
+	}{
+		{
+			name:     "regular",
+			roles:    []string{"REGULAR"},
+			allowed:  []permission{permUploadBook, permReportContent},
+			disallow: []permission{permViewBookStats, permApproveBook, permManageRoles},
+		},
+		{
+			name:     "author",
+			roles:    []string{"REGULAR", "AUTHOR"},
+			allowed:  []permission{permUploadBook, permViewBookStats},
+			disallow: []permission{permApproveBook},
+		},
+		{
+			name:     "moderator",
+			roles:    []string{"REGULAR", "MODERATOR"},
+			allowed:  []permission{permApproveBook, permApproveChapter, permResolveReport, permDeleteComment},
+			disallow: []permission{permBanUser, permManageRoles, permViewStats, permManageTags, permManageGenres, permManageLanguages},
+		},
+		{
+			name:    "admin",
+			roles:   []string{"ADMIN"},
+			allowed: []permission{permApproveBook, permBanUser, permManageRoles, permViewStats, permManageTags, permManageGenres, permManageLanguages},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u := &user{roles: tc.roles}
+			for _, p := range tc.allowed {
+				if !u.can(p) {
+					t.Fatalf("expected %v to have %s", tc.roles, p)
+				}
+			}
+			for _, p := range tc.disallow {
+				if u.can(p) {
+					t.Fatalf("expected %v not to have %s", tc.roles, p)
+				}
+			}
+		})
+	}
+}
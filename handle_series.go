@@ -0,0 +1,216 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// seriesError answers with the status matching an error returned while
+// managing a series
+func (s *server) seriesError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errSeriesNotFound), errors.Is(err, errBookNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errSeriesExists), errors.Is(err, errBookInSeries):
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+// handleCreateSeries godoc
+//
+//	@Summary		Create series
+//	@Description	Create a series out of your books. The books become its volumes in the order given.
+//	@Tags			series
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleCreateSeries.request	true	"create series body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201		{object}	main.handleCreateSeries.response
+//	@Router			/series [post]
+func (s *server) handleCreateSeries(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name        string   `json:"name" validate:"required,max=255"`
+		Description *string  `json:"description"`
+		BookIds     []string `json:"bookIds" validate:"required,min=1,unique,dive,uuid"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	sr := &series{authorID: r.Context().Value("user").(string), name: params.Name}
+	if params.Description != nil {
+		sr.description = sql.NullString{String: *params.Description, Valid: true}
+	}
+
+	id, err := s.createSeries(r.Context(), sr, params.BookIds)
+	if err != nil {
+		s.seriesError(w, err)
+		return
+	}
+
+	encode(w, http.StatusCreated, &response{Id: id})
+}
+
+// handleGetSeries godoc
+//
+//	@Summary		Get series
+//	@Description	Get a series with its published volumes in order, leaving out the ones rated above what you can read
+//	@Tags			series
+//	@Produce		json
+//	@Param			seriesID	path		string	true	"series id"
+//	@Failure		404			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		200			{object}	main.handleGetSeries.response
+//	@Router			/series/{seriesID} [get]
+func (s *server) handleGetSeries(w http.ResponseWriter, r *http.Request) {
+	type volume struct {
+		Volume      int     `json:"volume"`
+		Id          string  `json:"id"`
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Image       *string `json:"image"`
+		Views       int     `json:"views"`
+		Rating      float32 `json:"rating"`
+	}
+
+	type response struct {
+		Id          string   `json:"id"`
+		Name        string   `json:"name"`
+		Description *string  `json:"description"`
+		AuthorId    string   `json:"authorId"`
+		Author      string   `json:"author"`
+		Volumes     []volume `json:"volumes"`
+		CreatedAt   string   `json:"createdAt"`
+	}
+
+	seriesID := chi.URLParam(r, "seriesID")
+
+	if err := validate.Var(seriesID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errSeriesNotFound.Error()})
+		return
+	}
+
+	readerID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), readerID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	sr, err := s.getSeries(r.Context(), seriesID, filter)
+	if err != nil {
+		s.seriesError(w, err)
+		return
+	}
+
+	resp := response{Id: sr.id, Name: sr.name, AuthorId: sr.authorID, Author: sr.authorName, Volumes: []volume{}, CreatedAt: sr.createdAt.Format(time.RFC3339)}
+	if sr.description.Valid {
+		resp.Description = &sr.description.String
+	}
+
+	for _, b := range sr.books {
+		v := volume{Volume: b.series.volume, Id: b.id, Name: b.name, Description: b.description, Views: b.views, Rating: b.rating}
+		if b.image.Valid {
+			v.Image = &b.image.String
+		}
+		resp.Volumes = append(resp.Volumes, v)
+	}
+
+	encode(w, http.StatusOK, &resp)
+}
+
+// handleUpdateSeries godoc
+//
+//	@Summary		Update series
+//	@Description	Rename your series, change its description or replace its volumes with the books given, in order
+//	@Tags			series
+//	@Accept			json
+//	@Param			seriesID	path		string							true	"series id"
+//	@Param			param		body		main.handleUpdateSeries.request	true	"update series body"
+//	@Failure		400			{object}	errorResponse
+//	@Failure		404			{object}	errorResponse
+//	@Failure		409			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		204
+//	@Router			/series/{seriesID} [patch]
+func (s *server) handleUpdateSeries(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name        *string  `json:"name" validate:"omitempty,min=1,max=255"`
+		Description *string  `json:"description"`
+		BookIds     []string `json:"bookIds" validate:"omitempty,min=1,unique,dive,uuid"`
+	}
+
+	seriesID := chi.URLParam(r, "seriesID")
+
+	if err := validate.Var(seriesID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errSeriesNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if err := s.updateSeries(r.Context(), r.Context().Value("user").(string), seriesID, params.Name, params.Description, params.BookIds); err != nil {
+		s.seriesError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleDeleteSeries godoc
+//
+//	@Summary		Delete series
+//	@Description	Delete your series. Its books are kept.
+//	@Tags			series
+//	@Param			seriesID	path		string	true	"series id"
+//	@Failure		404			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		204
+//	@Router			/series/{seriesID} [delete]
+func (s *server) handleDeleteSeries(w http.ResponseWriter, r *http.Request) {
+	seriesID := chi.URLParam(r, "seriesID")
+
+	if err := validate.Var(seriesID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errSeriesNotFound.Error()})
+		return
+	}
+
+	if err := s.deleteSeries(r.Context(), r.Context().Value("user").(string), seriesID); err != nil {
+		s.seriesError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
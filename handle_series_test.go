@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createNamedBook(t *testing.T, authorID string, db *sql.DB) string {
+	var id string
+	query :=
+		`
+			INSERT INTO books(name, description, author_id, approved, moderation_status) VALUES ($1, 'test book description', $2, 'true', 'approved') RETURNING id;
+		`
+	if err := db.QueryRowContext(context.Background(), query, "test-book "+uuid.NewString(), authorID).Scan(&id); err != nil {
+		t.Fatalf("error creating new book, %v", err)
+	}
+	return id
+}
+
+func TestHandleSeries(t *testing.T) {
+	db := connectTestDb(t)
+	authorID := createAndCleanUpUser(t, db)
+	otherID := createAndCleanUpFollowed(t, db)
+
+	authorToken, err := createJWTToken(authorID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	otherToken, err := createJWTToken(otherID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+	firstID := createBook(t, authorID, db)
+	secondID := createNamedBook(t, authorID, db)
+	otherBookID := createNamedBook(t, otherID, db)
+
+	var seriesID string
+
+	t.Run("create series", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			body         map[string]any
+			expectedCode int
+		}{
+			{
+				name:         "no books",
+				body:         map[string]any{"name": "test series", "bookIds": []string{}},
+				expectedCode: http.StatusBadRequest,
+			},
+			{
+				name:         "someone else's book",
+				body:         map[string]any{"name": "test series", "bookIds": []string{firstID, otherBookID}},
+				expectedCode: http.StatusNotFound,
+			},
+			{
+				name:         "create series",
+				body:         map[string]any{"name": "test series", "bookIds": []string{secondID, firstID}},
+				expectedCode: http.StatusCreated,
+			},
+			{
+				name:         "book already in a series",
+				body:         map[string]any{"name": "another test series", "bookIds": []string{firstID}},
+				expectedCode: http.StatusConflict,
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				body, _ := json.Marshal(tc.body)
+				r := httptest.NewRequest(http.MethodPost, "/api/v1/series", bytes.NewReader(body))
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: authorToken})
+				rr := httptest.NewRecorder()
+
+				svr.router.ServeHTTP(rr, r)
+
+				if rr.Code != tc.expectedCode {
+					t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+				}
+
+				if rr.Code == http.StatusCreated {
+					var resp struct {
+						Id string `json:"id"`
+					}
+					if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+						t.Fatal(err.Error())
+					}
+					seriesID = resp.Id
+				}
+			})
+		}
+	})
+
+	t.Run("volumes are in order", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/series/%v", seriesID), nil)
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Volumes []struct {
+				Id     string `json:"id"`
+				Volume int    `json:"volume"`
+			} `json:"volumes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(resp.Volumes) != 2 || resp.Volumes[0].Id != secondID || resp.Volumes[1].Id != firstID {
+			t.Fatalf("expected volumes %s and %s, got %v", secondID, firstID, resp.Volumes)
+		}
+	})
+
+	t.Run("mature volumes are hidden from signed out readers", func(t *testing.T) {
+		if _, err := db.Exec(`UPDATE books SET content_rating = 'mature' WHERE id = $1;`, firstID); err != nil {
+			t.Fatal(err.Error())
+		}
+		defer db.Exec(`UPDATE books SET content_rating = 'everyone' WHERE id = $1;`, firstID)
+
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/series/%v", seriesID), nil)
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Volumes []struct {
+				Id string `json:"id"`
+			} `json:"volumes"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(resp.Volumes) != 1 || resp.Volumes[0].Id != secondID {
+			t.Fatalf("expected only volume %s, got %v", secondID, resp.Volumes)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		token        string
+		body         map[string]any
+		expectedCode int
+	}{
+		{
+			name:         "only the author can update",
+			token:        otherToken,
+			body:         map[string]any{"name": "renamed series"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "reorder volumes",
+			token:        authorToken,
+			body:         map[string]any{"bookIds": []string{firstID, secondID}},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/series/%v", seriesID), bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: tc.token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("series info on the book", func(t *testing.T) {
+		if _, err := svr.uploadChapter(context.Background(), authorID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: secondID}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		book, err := svr.getBook(context.Background(), secondID)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if book.series == nil || book.series.seriesID != seriesID || book.series.volume != 2 || book.series.volumes != 2 {
+			t.Fatalf("expected book to be volume 2 of 2 in %s, got %+v", seriesID, book.series)
+		}
+	})
+}
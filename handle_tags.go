@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tagError answers with the status matching an error returned while curating
+// tags
+func (s *server) tagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errTagNotFound):
+		encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errTagIsSynonym):
+		encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+	case errors.Is(err, errTagSynonymOfItself), errors.Is(err, errInvalidTag):
+		encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+	default:
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+	}
+}
+
+// tagName normalizes a tag sent by an admin
+func tagName(name string) (string, error) {
+	tags, err := normalizeTags([]string{name})
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", errInvalidTag
+	}
+	return tags[0], nil
+}
+
+// handleSearchTags godoc
+//
+//	@Summary		Search tags
+//	@Description	Autocomplete tags. Synonyms match their canonical tag, canonical tags come first, then the most used.
+//	@Tags			tags
+//	@Produce		json
+//	@Param			q		query		string	true	"start of the tag"
+//	@Param			limit	query		int		false	"limit (default 10, at most 50)"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleSearchTags.response
+//	@Router			/tags [get]
+func (s *server) handleSearchTags(w http.ResponseWriter, r *http.Request) {
+	type responseTag struct {
+		Id        string   `json:"id"`
+		Name      string   `json:"name"`
+		Canonical bool     `json:"canonical"`
+		Synonyms  []string `json:"synonyms"`
+		Books     int      `json:"books"`
+	}
+
+	type response struct {
+		Tags []responseTag `json:"tags"`
+	}
+
+	prefix := normalizeTag(r.URL.Query().Get("q"))
+	if prefix == "" {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "q should have at least one letter or number"})
+		return
+	}
+
+	limit := 10
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 50 {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: "limit should be a number between 1 and 50"})
+			return
+		}
+	}
+
+	tags, err := s.searchTags(r.Context(), prefix, limit)
+	if err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	resp := []responseTag{}
+	for _, t := range tags {
+		resp = append(resp, responseTag{Id: t.id, Name: t.name, Canonical: t.canonical, Synonyms: append([]string{}, t.synonyms...), Books: t.books})
+	}
+
+	encode(w, http.StatusOK, &response{Tags: resp})
+}
+
+// handleCreateTag godoc
+//
+//	@Summary		Create canonical tag
+//	@Description	Create a canonical tag, or make an existing tag canonical. Canonical tags are suggested first.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleCreateTag.request	true	"create tag body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		201		{object}	main.handleCreateTag.response
+//	@Router			/admin/tags [post]
+func (s *server) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	name, err := tagName(params.Name)
+	if err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	id, err := s.createCanonicalTag(r.Context(), name)
+	if err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	encode(w, http.StatusCreated, &response{Id: id})
+}
+
+// handleAddTagSynonym godoc
+//
+//	@Summary		Add tag synonym
+//	@Description	Make a tag a synonym of another one. Books tagged with the synonym are moved to the tag and new books get the tag instead.
+//	@Tags			admin
+//	@Accept			json
+//	@Param			tagID	path		string							true	"tag id"
+//	@Param			param	body		main.handleAddTagSynonym.request	true	"add synonym body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/tags/{tagID}/synonyms [post]
+func (s *server) handleAddTagSynonym(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	tagID := chi.URLParam(r, "tagID")
+
+	if err := validate.Var(tagID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errTagNotFound.Error()})
+		return
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	name, err := tagName(params.Name)
+	if err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	if err := s.addTagSynonym(r.Context(), tagID, name); err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleRemoveTagSynonym godoc
+//
+//	@Summary		Remove tag synonym
+//	@Description	Make a synonym a tag of its own again. Books already moved to the tag keep it.
+//	@Tags			admin
+//	@Param			tagID	path		string	true	"tag id"
+//	@Param			synonym	path		string	true	"synonym"
+//	@Failure		401		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/admin/tags/{tagID}/synonyms/{synonym} [delete]
+func (s *server) handleRemoveTagSynonym(w http.ResponseWriter, r *http.Request) {
+	tagID := chi.URLParam(r, "tagID")
+
+	if err := validate.Var(tagID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errTagNotFound.Error()})
+		return
+	}
+
+	synonym, err := url.PathUnescape(chi.URLParam(r, "synonym"))
+	if err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errTagNotFound.Error()})
+		return
+	}
+
+	if err := s.removeTagSynonym(r.Context(), tagID, normalizeTag(synonym)); err != nil {
+		s.tagError(w, err)
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestHandleTags(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(userID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, &mc{})
+
+	suffix := uuid.NewString()[:8]
+	canonical := "slow burn " + suffix
+	synonym := "slowburn " + suffix
+	other := "cultivation " + suffix
+
+	t.Cleanup(func() {
+		query :=
+			`
+				DELETE FROM tags WHERE name = ANY($1);
+			`
+		if _, err := db.ExecContext(context.Background(), query, pq.Array([]string{canonical, synonym, other})); err != nil {
+			t.Errorf("error deleting tags, %v", err)
+		}
+	})
+
+	bookID, err := svr.uploadBook(context.Background(), &book{name: "test-book " + uuid.NewString(), description: "test-book description", authorID: userID, genres: []string{"Action"}, tags: []string{synonym, other}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: "en", releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	query :=
+		`
+			UPDATE books SET approved = true, moderation_status = 'approved' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, bookID); err != nil {
+		t.Fatalf("error approving book, %v", err)
+	}
+
+	var tagID string
+
+	t.Run("create canonical tag", func(t *testing.T) {
+		send := func(admin bool) *httptest.ResponseRecorder {
+			if admin {
+				makeAdmin(t, svr)
+			}
+			body, _ := json.Marshal(map[string]string{"name": "Slow-Burn " + suffix})
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/admin/tags", bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+			svr.router.ServeHTTP(rr, r)
+			return rr
+		}
+
+		if rr := send(false); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+
+		rr := send(true)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected %d, got %d", http.StatusCreated, rr.Code)
+		}
+
+		var resp struct {
+			Id string `json:"id"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+		tagID = resp.Id
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "tag not found",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/tags/%v/synonyms", uuid.NewString()),
+			body:         map[string]string{"name": synonym},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "synonym of itself",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/tags/%v/synonyms", tagID),
+			body:         map[string]string{"name": canonical},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "add synonym",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/api/v1/admin/tags/%v/synonyms", tagID),
+			body:         map[string]string{"name": synonym},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "remove unknown synonym",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/api/v1/admin/tags/%v/synonyms/%v", tagID, url.PathEscape(other)),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			if tc.body != nil {
+				body, _ = json.Marshal(tc.body)
+			}
+			r := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(body))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("autocomplete synonym", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/tags?q="+url.QueryEscape(synonym), nil)
+		rr := httptest.NewRecorder()
+
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			Tags []struct {
+				Name  string `json:"name"`
+				Books int    `json:"books"`
+			} `json:"tags"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(resp.Tags) != 1 || resp.Tags[0].Name != canonical || resp.Tags[0].Books != 1 {
+			t.Fatalf("expected %s on 1 book, got %v", canonical, resp.Tags)
+		}
+	})
+
+	t.Run("filter books by synonym", func(t *testing.T) {
+		books, err := svr.getBooksByTags(context.Background(), []string{synonym, other}, nil, nil, defaultContentFilter, 0, 10, "views", "desc")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if len(books) != 1 || books[0].id != bookID {
+			t.Fatalf("expected book %s, got %v", bookID, books)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type translationResponse struct {
+	Id                 string `json:"id"`
+	Name               string `json:"name"`
+	Language           string `json:"language"`
+	Original           bool   `json:"original"`
+	TranslatedChapters int    `json:"translatedChapters"`
+	OriginalChapters   int    `json:"originalChapters"`
+	// Progress is the percentage of the original's chapters translated
+	Progress int `json:"progress"`
+}
+
+func translationsResponse(translations []translation) []translationResponse {
+	resp := []translationResponse{}
+	for _, t := range translations {
+		item := translationResponse{Id: t.id, Name: t.name, Language: t.language, Original: t.original, TranslatedChapters: t.translatedChapters, OriginalChapters: t.originalChapters}
+
+		if t.originalChapters > 0 {
+			item.Progress = t.translatedChapters * 100 / t.originalChapters
+		}
+
+		resp = append(resp, item)
+	}
+	return resp
+}
+
+// handleGetTranslations godoc
+//
+//	@Summary		Get translations
+//	@Description	Get the original of a book and its other translations you can read, with how much of the original each one has translated
+//	@Tags			books
+//	@Produce		json
+//	@Param			bookID		path		string		true	"book id"
+//	@Param			language	query		[]string	false	"BCP 47 language codes"
+//	@Failure		404			{object}	errorResponse
+//	@Failure		500			{object}	errorResponse
+//	@Success		200			{object}	main.handleGetTranslations.response
+//	@Router			/books/{bookID}/translations [get]
+func (s *server) handleGetTranslations(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Translations []translationResponse `json:"translations"`
+	}
+
+	bookID := chi.URLParam(r, "bookID")
+
+	if err := validate.Var(bookID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errBookNotFound.Error()})
+		return
+	}
+
+	if err := s.checkIfBookExists(r.Context(), bookID); err != nil {
+		if errors.Is(err, errBookNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	userID, _ := r.Context().Value("user").(string)
+
+	filter, err := s.readerFilter(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	translations, err := s.getTranslations(r.Context(), bookID, r.URL.Query()["language"], filter)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusOK, &response{Translations: translationsResponse(translations)})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleGetTranslations(t *testing.T) {
+	db := connectTestDb(t)
+	userID := createAndCleanUpUser(t, db)
+	svr := newServer(nil, db, nil, nil)
+
+	originalID := createBook(t, userID, db)
+	var sourceChapters []string
+	for i := 1; i <= 2; i++ {
+		id, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: i, content: "test chapter content", bookID: originalID})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		sourceChapters = append(sourceChapters, id)
+	}
+
+	upload := func(sourceBookID, language string) (string, error) {
+		return svr.uploadBook(context.Background(), &book{name: "test-book " + uuid.NewString(), description: "test-book description", authorID: userID, genres: []string{"Action"}, draftChapter: draftChapter{Title: "draft chapter title", Content: "draft chapter content"}, language: language, releaseSchedule: []releaseSchedule{{Day: "Monday", Chapters: 1}}, contentRating: ratingTeen, sourceBookID: sourceBookID})
+	}
+
+	if _, err := upload(originalID, "en"); !errors.Is(err, errTranslationLanguage) {
+		t.Fatalf("expected %v, got %v", errTranslationLanguage, err)
+	}
+
+	if _, err := upload(uuid.NewString(), "ja"); !errors.Is(err, errSourceBookNotFound) {
+		t.Fatalf("expected %v, got %v", errSourceBookNotFound, err)
+	}
+
+	translationID, err := upload(originalID, "ja")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: translationID, sourceChapterID: translationID}); !errors.Is(err, errSourceChapterNotFound) {
+		t.Fatalf("expected %v, got %v", errSourceChapterNotFound, err)
+	}
+
+	if _, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 1, content: "test chapter content", bookID: translationID, sourceChapterID: sourceChapters[0]}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := svr.uploadChapter(context.Background(), userID, &chapter{title: "test chapter", chapterNo: 2, content: "test chapter content", bookID: translationID, sourceChapterID: sourceChapters[0]}); !errors.Is(err, errChapterAlreadyTranslated) {
+		t.Fatalf("expected %v, got %v", errChapterAlreadyTranslated, err)
+	}
+
+	query :=
+		`
+			UPDATE books SET approved = true, moderation_status = 'approved' WHERE id = $1;
+		`
+	if _, err := db.ExecContext(context.Background(), query, translationID); err != nil {
+		t.Fatalf("error approving book, %v", err)
+	}
+
+	query =
+		`
+			UPDATE chapters SET moderation_status = 'approved' WHERE book_id = ANY(ARRAY[$1, $2]::uuid[]);
+		`
+	if _, err := db.ExecContext(context.Background(), query, originalID, translationID); err != nil {
+		t.Fatalf("error approving chapters, %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		bookID           string
+		query            string
+		expectedCode     int
+		expectedBooks    []string
+		expectedProgress []int
+	}{
+		{
+			name:         "book not found",
+			bookID:       uuid.NewString(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:             "translations of the original",
+			bookID:           originalID,
+			expectedCode:     http.StatusOK,
+			expectedBooks:    []string{translationID},
+			expectedProgress: []int{50},
+		},
+		{
+			name:             "original of a translation",
+			bookID:           translationID,
+			expectedCode:     http.StatusOK,
+			expectedBooks:    []string{originalID},
+			expectedProgress: []int{100},
+		},
+		{
+			name:          "filter by language",
+			bookID:        originalID,
+			query:         "?language=fr",
+			expectedCode:  http.StatusOK,
+			expectedBooks: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/books/%v/translations%v", tc.bookID, tc.query), nil)
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Translations []struct {
+					Id       string `json:"id"`
+					Progress int    `json:"progress"`
+				} `json:"translations"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if len(resp.Translations) != len(tc.expectedBooks) {
+				t.Fatalf("expected %d translations, got %d", len(tc.expectedBooks), len(resp.Translations))
+			}
+			for i, id := range tc.expectedBooks {
+				if resp.Translations[i].Id != id {
+					t.Fatalf("expected translation %d to be %s, got %s", i, id, resp.Translations[i].Id)
+				}
+				if resp.Translations[i].Progress != tc.expectedProgress[i] {
+					t.Fatalf("expected translation %d to be %d%% done, got %d%%", i, tc.expectedProgress[i], resp.Translations[i].Progress)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// staffTwoFactorRequired reports whether an admin or moderator has to enroll in
+// two factor authentication before they can use staff endpoints
+func staffTwoFactorRequired(u *user) bool {
+	return os.Getenv("REQUIRE_ADMIN_2FA") == "true" && u.isStaff() && !u.totpEnabled
+}
+
+// handleTwoFactorEnroll godoc
+//
+//	@Summary		Start two factor enrollment
+//	@Description	Generate a totp secret and otpauth uri for an authenticator app. Two factor authentication is only enabled once confirmed.
+//	@Tags			auth
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		409	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		201	{object}	main.handleTwoFactorEnroll.response
+//	@Router			/auth/2fa/enroll [post]
+func (s *server) handleTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+
+	userID := r.Context().Value("user").(string)
+
+	user, err := s.getUser(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := s.setTOTPSecret(r.Context(), userID, secret); err != nil {
+		if errors.Is(err, errTwoFactorEnabled) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusCreated, &response{Secret: secret, URI: totpURI(secret, user.email)})
+}
+
+// handleTwoFactorConfirm godoc
+//
+//	@Summary		Confirm two factor enrollment
+//	@Description	Enable two factor authentication with a code from the authenticator app. Recovery codes are only shown once.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleTwoFactorConfirm.request	true	"confirm body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleTwoFactorConfirm.response
+//	@Router			/auth/2fa/confirm [post]
+func (s *server) handleTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Code string `json:"code" validate:"required"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	userID := r.Context().Value("user").(string)
+
+	tf, err := s.getTwoFactor(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if tf.enabled {
+		encode(w, http.StatusConflict, &errorResponse{Error: errTwoFactorEnabled.Error()})
+		return
+	}
+
+	if !tf.secret.Valid {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errTwoFactorNotEnrolled.Error()})
+		return
+	}
+
+	if err := s.verifySecondFactor(r.Context(), userID, tf, params.Code, ""); err != nil {
+		if errors.Is(err, errInvalidTwoFactorCode) {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := s.enableTwoFactor(r.Context(), userID, codes); err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusOK, &response{RecoveryCodes: codes})
+}
+
+// handleTwoFactorVerify godoc
+//
+//	@Summary		Verify second factor
+//	@Description	Second login step for users with two factor authentication, using the challenge token returned by login, or the challenge_token cookie set by a provider sign in, and either a totp code or a recovery code
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleTwoFactorVerify.request	true	"verify body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/auth/2fa/verify [post]
+func (s *server) handleTwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code" validate:"required_without=RecoveryCode"`
+		RecoveryCode   string `json:"recoveryCode" validate:"required_without=Code"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if params.ChallengeToken == "" {
+		if cookie, err := r.Cookie("challenge_token"); err == nil {
+			params.ChallengeToken = cookie.Value
+		}
+	}
+
+	userID, err := decodeChallengeToken(params.ChallengeToken)
+	if err != nil {
+		encode(w, http.StatusUnauthorized, &errorResponse{Error: "invalid or expired challenge token"})
+		return
+	}
+
+	// guesses spread over many addresses are still capped for every challenge
+	// and limited for the account across challenges
+	challenge := sha256.Sum256([]byte(params.ChallengeToken))
+
+	for _, l := range []struct{ name, key string }{
+		{name: "two_factor_challenge", key: hex.EncodeToString(challenge[:])},
+		{name: "two_factor_account", key: userID},
+	} {
+		allowed, retryAfter, err := s.limiter.allow(r.Context(), fmt.Sprintf("%s:%s", l.name, l.key), s.limits[l.name])
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+		if !allowed {
+			writeTooManyRequests(w, retryAfter)
+			return
+		}
+	}
+
+	tf, err := s.getTwoFactor(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if !tf.enabled {
+		encode(w, http.StatusUnauthorized, &errorResponse{Error: errTwoFactorNotEnabled.Error()})
+		return
+	}
+
+	if err := s.verifySecondFactor(r.Context(), userID, tf, params.Code, params.RecoveryCode); err != nil {
+		if errors.Is(err, errInvalidTwoFactorCode) {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := createAccessAndRefreshTokens(w, userID); err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	clearChallengeCookie(w)
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleTwoFactorDisable godoc
+//
+//	@Summary		Disable two factor authentication
+//	@Description	Disable two factor authentication using a totp code or a recovery code
+//	@Tags			auth
+//	@Accept			json
+//	@Param			param	body		main.handleTwoFactorDisable.request	true	"disable body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		403		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/auth/2fa [delete]
+func (s *server) handleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Code         string `json:"code" validate:"required_without=RecoveryCode"`
+		RecoveryCode string `json:"recoveryCode" validate:"required_without=Code"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	userID := r.Context().Value("user").(string)
+
+	user, err := s.getUser(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if !user.totpEnabled {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: errTwoFactorNotEnabled.Error()})
+		return
+	}
+
+	if os.Getenv("REQUIRE_ADMIN_2FA") == "true" && user.isStaff() {
+		encode(w, http.StatusForbidden, &errorResponse{Error: "two factor authentication is required for staff"})
+		return
+	}
+
+	tf, err := s.getTwoFactor(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := s.verifySecondFactor(r.Context(), userID, tf, params.Code, params.RecoveryCode); err != nil {
+		if errors.Is(err, errInvalidTwoFactorCode) {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := s.disableTwoFactor(r.Context(), userID); err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleTwoFactorEnroll(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		cookieName   string
+		cookieValue  string
+		expectedCode int
+	}{
+		{
+			name:         "no access token cookie",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid/malformed token",
+			cookieName:   "access_token",
+			cookieValue:  "invalid token",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "enroll",
+			cookieName:   "access_token",
+			cookieValue:  token,
+			expectedCode: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa/enroll", nil)
+			r.AddCookie(&http.Cookie{Name: tc.cookieName, Value: tc.cookieValue})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTwoFactorConfirm(t *testing.T) {
+	type request struct {
+		Code string `json:"code"`
+	}
+
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	secret, _ := generateTOTPSecret()
+	if err := svr.setTOTPSecret(context.Background(), id, secret); err != nil {
+		t.Fatal(err.Error())
+	}
+	code, _ := totpCode(secret, time.Now())
+
+	tests := []struct {
+		name         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "validation error",
+			body:         struct{ name string }{name: "invalid structure"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid code",
+			body:         request{Code: "000000x"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "confirm",
+			body:         request{Code: code},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "already enabled",
+			body:         request{Code: code},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa/confirm", bytes.NewReader(payload))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTwoFactorVerify(t *testing.T) {
+	type request struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code,omitempty"`
+		RecoveryCode   string `json:"recoveryCode,omitempty"`
+	}
+
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	svr.limits["two_factor"] = rateLimit{requests: 100, per: time.Minute}
+	secret, _ := generateTOTPSecret()
+	if err := svr.setTOTPSecret(context.Background(), id, secret); err != nil {
+		t.Fatal(err.Error())
+	}
+	recoveryCodes, _ := generateRecoveryCodes()
+	if err := svr.enableTwoFactor(context.Background(), id, recoveryCodes); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	challengeToken, err := createChallengeToken(id)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	accessToken, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	code, _ := totpCode(secret, time.Now())
+
+	tests := []struct {
+		name         string
+		body         any
+		cookie       string
+		expectedCode int
+	}{
+		{
+			name:         "missing code",
+			body:         request{ChallengeToken: challengeToken},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "access token used as challenge",
+			body:         request{ChallengeToken: accessToken, Code: code},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "verify with totp code",
+			body:         request{ChallengeToken: challengeToken, Code: code},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "reused totp code",
+			body:         request{ChallengeToken: challengeToken, Code: code},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "verify with recovery code",
+			body:         request{ChallengeToken: challengeToken, RecoveryCode: recoveryCodes[0]},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "reused recovery code",
+			body:         request{ChallengeToken: challengeToken, RecoveryCode: recoveryCodes[0]},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing challenge token",
+			body:         request{RecoveryCode: recoveryCodes[1]},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "verify with challenge cookie",
+			body:         request{RecoveryCode: recoveryCodes[1]},
+			cookie:       challengeToken,
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa/verify", bytes.NewReader(payload))
+			if tc.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "challenge_token", Value: tc.cookie})
+			}
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTwoFactorVerifyRateLimit(t *testing.T) {
+	type request struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code"`
+	}
+
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+
+	svr := newServer(nil, db, nil, nil)
+	svr.limits["two_factor"] = rateLimit{requests: 100, per: time.Minute}
+	svr.limits["two_factor_challenge"] = rateLimit{requests: 2, per: time.Hour}
+	svr.limits["two_factor_account"] = rateLimit{requests: 3, per: time.Hour}
+	secret, _ := generateTOTPSecret()
+	if err := svr.setTOTPSecret(context.Background(), id, secret); err != nil {
+		t.Fatal(err.Error())
+	}
+	recoveryCodes, _ := generateRecoveryCodes()
+	if err := svr.enableTwoFactor(context.Background(), id, recoveryCodes); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	first, err := createChallengeToken(id)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// tokens are signed with second precision, a new second gives a new token
+	time.Sleep(time.Second)
+	second, err := createChallengeToken(id)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name           string
+		challengeToken string
+		expectedCode   int
+	}{
+		{name: "first guess", challengeToken: first, expectedCode: http.StatusUnauthorized},
+		{name: "second guess", challengeToken: first, expectedCode: http.StatusUnauthorized},
+		{name: "challenge used up", challengeToken: first, expectedCode: http.StatusTooManyRequests},
+		{name: "new challenge", challengeToken: second, expectedCode: http.StatusUnauthorized},
+		{name: "account limited", challengeToken: second, expectedCode: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(request{ChallengeToken: tc.challengeToken, Code: "000000"})
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa/verify", bytes.NewReader(payload))
+			rr := httptest.NewRecorder()
+
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
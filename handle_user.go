@@ -1,8 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // handleGetProfile godoc
@@ -45,3 +56,679 @@ func (s *server) handleGetProfile(w http.ResponseWriter, r *http.Request) {
 
 	encode(w, http.StatusOK, &response{Email: user.email, DisplayName: user.displayName, Image: image, About: about, Roles: user.roles})
 }
+
+// handleEditProfile godoc
+//
+//	@Summary		Edit current user profile
+//	@Description	Edit the display name, about and profile picture of the current user
+//	@Tags			users
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			display_name	formData	string	false	"display name"
+//	@Param			about			formData	string	false	"about"
+//	@Param			image			formData	file	false	"profile picture (max 400KB)"
+//	@Failure		400				{object}	errorResponse
+//	@Failure		404				{object}	errorResponse
+//	@Failure		409				{object}	errorResponse
+//	@Failure		413				{object}	errorResponse
+//	@Failure		500				{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me [patch]
+func (s *server) handleEditProfile(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("user").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, 500<<10)
+
+	if err := r.ParseMultipartForm(500 << 10); err != nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "should at least pass one field to update"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var params user
+
+	if displayName := strings.TrimSpace(r.FormValue("display_name")); displayName != "" {
+		if err := validateDisplayName(displayName); err != nil {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+			return
+		}
+		params.displayName = displayName
+	}
+
+	if about := r.FormValue("about"); about != "" {
+		params.about = sql.NullString{String: about, Valid: true}
+	}
+
+	file, header, err := r.FormFile("image")
+
+	if err != nil && err != http.ErrMissingFile {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("error retrieving file: %v", err)})
+		return
+	}
+
+	if err == nil {
+		defer file.Close()
+
+		image, err := io.ReadAll(file)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("error reading bytes, %v", err))
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if len(image) > 400<<10 {
+			encode(w, http.StatusRequestEntityTooLarge, &errorResponse{Error: "image too large"})
+			return
+		}
+
+		if contentType := http.DetectContentType(image); !strings.HasPrefix(contentType, "image/") {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid file type"})
+			return
+		}
+
+		url, err := s.objectStore.upload(r.Context(), fmt.Sprintf("users/%s_%s", id, header.Filename), bytes.NewReader(image))
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		params.image = sql.NullString{String: url, Valid: true}
+	}
+
+	if params.displayName == "" && !params.about.Valid && !params.image.Valid {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "should at least pass one field to update"})
+		return
+	}
+
+	if err := s.updateUser(r.Context(), id, &params); err != nil {
+		if errors.Is(err, errDisplayNameTaken) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleChangePassword godoc
+//
+//	@Summary		Change password
+//	@Description	Change the password of the current user and sign out every other session. Users who signed up through an oauth provider and never set a password leave oldPassword empty and either send their two factor code or sign in with /auth/{provider}/reauth first.
+//	@Tags			users
+//	@Accept			json
+//	@Param			param	body		main.handleChangePassword.request	true	"change password body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me/password [put]
+func (s *server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		OldPassword  string `json:"oldPassword"`
+		NewPassword  string `json:"newPassword" validate:"required,min=8"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	id := r.Context().Value("user").(string)
+
+	if !s.confirmIdentity(w, r, id, params.OldPassword, params.Code, params.RecoveryCode) {
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error hashing password, %v", err))
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	// whoever else holds a session loses it, this one gets new tokens
+	if err := s.updateUserPassword(r.Context(), id, string(hash), time.Now().Truncate(time.Second)); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if err := createAccessAndRefreshTokens(w, id); err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// confirmIdentity makes sure the request comes from the account holder before
+// a sensitive change, writing the error response when it doesn't. Users with a
+// password give it, users who only sign in through a provider give their
+// second factor or signed in with their provider again within
+// reauthenticationWindow.
+func (s *server) confirmIdentity(w http.ResponseWriter, r *http.Request, userID, password, code, recoveryCode string) bool {
+	hash, err := s.getUserPassword(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return false
+	}
+
+	if hash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: errInvalidPassword.Error()})
+			return false
+		}
+		return true
+	}
+
+	if code == "" && recoveryCode == "" {
+		reauthenticated, err := s.consumeReauthentication(r.Context(), userID, reauthenticationWindow)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return false
+		}
+
+		if !reauthenticated {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: errReauthenticationRequired.Error()})
+			return false
+		}
+		return true
+	}
+
+	allowed, retryAfter, err := s.limiter.allow(r.Context(), fmt.Sprintf("two_factor_account:%s", userID), s.limits["two_factor_account"])
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return false
+	}
+	if !allowed {
+		writeTooManyRequests(w, retryAfter)
+		return false
+	}
+
+	tf, err := s.getTwoFactor(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return false
+	}
+
+	if !tf.enabled {
+		encode(w, http.StatusUnauthorized, &errorResponse{Error: errReauthenticationRequired.Error()})
+		return false
+	}
+
+	if err := s.verifySecondFactor(r.Context(), userID, tf, code, recoveryCode); err != nil {
+		if errors.Is(err, errInvalidTwoFactorCode) {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: err.Error()})
+			return false
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return false
+	}
+
+	return true
+}
+
+// handleDeleteAccount godoc
+//
+//	@Summary		Delete account
+//	@Description	Delete the current user. Personal data is removed straight away; books are either transferred to another user or removed, and the rest of the account is cleaned up in the background. Users without a password either send their two factor code or sign in with /auth/{provider}/reauth first.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			param	body		main.handleDeleteAccount.request	true	"delete account body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		401		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		429		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		202		{object}	main.handleDeleteAccount.response
+//	@Router			/users/me [delete]
+func (s *server) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+		Books        string `json:"books" validate:"required,oneof=transfer remove"`
+		TransferTo   string `json:"transferTo" validate:"required_if=Books transfer,omitempty,uuid"`
+	}
+
+	type response struct {
+		Id string `json:"id"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	id := r.Context().Value("user").(string)
+
+	if !s.confirmIdentity(w, r, id, params.Password, params.Code, params.RecoveryCode) {
+		return
+	}
+
+	deletionID, err := s.requestAccountDeletion(r.Context(), id, params.Books, params.TransferTo)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		if errors.Is(err, errAccountDeleted) {
+			encode(w, http.StatusConflict, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	messageBody, err := json.Marshal(struct {
+		DeletionID string
+	}{
+		DeletionID: deletionID,
+	})
+
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("error marshalling message, %v", err))
+	} else if err := s.ch.PublishWithContext(r.Context(), "", queueAccountDeleted, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: messageBody}); err != nil {
+		// the personal data is already gone, the worker picks up pending
+		// deletions when it starts so there is no need to fail the request
+		s.logger.Error(fmt.Sprintf("error publishing message to queue, %v", err))
+	}
+
+	clearAuthCookies(w)
+
+	encode(w, http.StatusAccepted, &response{Id: deletionID})
+}
+
+// handleGetUserProfile godoc
+//
+//	@Summary		Get user profile
+//	@Description	Public profile of a user with their approved books. The library and reading history are left out when the user hid them, reading stats are only included when the user opted in.
+//	@Tags			users
+//	@Produce		json
+//	@Param			userID	path		string	true	"user id"
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		200		{object}	main.handleGetUserProfile.response
+//	@Router			/users/{userID} [get]
+func (s *server) handleGetUserProfile(w http.ResponseWriter, r *http.Request) {
+	type responseRecentlyRead struct {
+		Name            string  `json:"name"`
+		Image           *string `json:"image"`
+		LastReadChapter int     `json:"lastReadChapter"`
+		LastReadAt      string  `json:"lastReadAt"`
+	}
+
+	type responseReadingStats struct {
+		BooksRead    int `json:"booksRead"`
+		ChaptersRead int `json:"chaptersRead"`
+		LibraryBooks int `json:"libraryBooks"`
+	}
+
+	type response struct {
+		DisplayName  string                 `json:"displayName"`
+		Image        *string                `json:"image"`
+		About        *string                `json:"about"`
+		Followers    int                    `json:"followers"`
+		Following    int                    `json:"following"`
+		Joined       string                 `json:"joined"`
+		Online       bool                   `json:"online"`
+		Books        []getResponseBook      `json:"books"`
+		Library      []getResponseBook      `json:"library,omitempty"`
+		RecentlyRead []responseRecentlyRead `json:"recentlyRead,omitempty"`
+		ReadingStats *responseReadingStats  `json:"readingStats,omitempty"`
+	}
+
+	userID := chi.URLParam(r, "userID")
+
+	if err := validate.Var(userID, "uuid"); err != nil {
+		encode(w, http.StatusNotFound, &errorResponse{Error: errUserNotFound.Error()})
+		return
+	}
+
+	user, err := s.getPublicProfile(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	var about *string
+	if user.about.Valid {
+		about = &user.about.String
+	}
+
+	var image *string
+	if user.image.Valid {
+		image = &user.image.String
+	}
+
+	books, err := s.getAuthoredBooks(r.Context(), userID)
+	if err != nil {
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	resp := response{
+		DisplayName: user.displayName,
+		Image:       image,
+		About:       about,
+		Followers:   user.followers,
+		Following:   user.following,
+		Joined:      user.createdAt.Format("Jan 2, 2006"),
+		Online:      s.onlineUsers(userID)[userID],
+		Books:       mapToGetBooks(books),
+	}
+
+	if user.privacy.libraryPublic {
+		library, err := s.getLibraryBooks(r.Context(), userID)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+		resp.Library = mapToGetBooks(library)
+	}
+
+	if user.privacy.readingHistoryPublic {
+		recentlyRead, err := s.getRecentlyReadBooks(r.Context(), userID, 0, 10)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		for _, book := range recentlyRead {
+			var img *string
+			if book.image.Valid {
+				img = &book.image.String
+			}
+			resp.RecentlyRead = append(resp.RecentlyRead, responseRecentlyRead{Name: book.name, Image: img, LastReadChapter: book.lastReadChapter, LastReadAt: book.updatedAt.Format("Jan 2, 2006")})
+		}
+	}
+
+	if user.privacy.readingStatsPublic {
+		stats, err := s.getReadingStats(r.Context(), userID)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+		resp.ReadingStats = &responseReadingStats{BooksRead: stats.booksRead, ChaptersRead: stats.chaptersRead, LibraryBooks: stats.libraryBooks}
+	}
+
+	encode(w, http.StatusOK, &resp)
+}
+
+// handleGetPrivacySettings godoc
+//
+//	@Summary		Get privacy settings
+//	@Description	Get what the current user shows on their public profile
+//	@Tags			users
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetPrivacySettings.response
+//	@Router			/users/me/privacy [get]
+func (s *server) handleGetPrivacySettings(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		LibraryPublic        bool `json:"libraryPublic"`
+		ReadingHistoryPublic bool `json:"readingHistoryPublic"`
+		ReadingStatsPublic   bool `json:"readingStatsPublic"`
+	}
+
+	settings, err := s.getPrivacySettings(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusOK, &response{LibraryPublic: settings.libraryPublic, ReadingHistoryPublic: settings.readingHistoryPublic, ReadingStatsPublic: settings.readingStatsPublic})
+}
+
+// handleUpdatePrivacySettings godoc
+//
+//	@Summary		Update privacy settings
+//	@Description	Choose whether the library, reading history and reading stats show on the public profile. All three are private until the user opts in. Fields left out are unchanged.
+//	@Tags			users
+//	@Accept			json
+//	@Param			param	body		main.handleUpdatePrivacySettings.request	true	"privacy settings body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me/privacy [patch]
+func (s *server) handleUpdatePrivacySettings(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		LibraryPublic        *bool `json:"libraryPublic"`
+		ReadingHistoryPublic *bool `json:"readingHistoryPublic"`
+		ReadingStatsPublic   *bool `json:"readingStatsPublic"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if params.LibraryPublic == nil && params.ReadingHistoryPublic == nil && params.ReadingStatsPublic == nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "should at least pass one field to update"})
+		return
+	}
+
+	id := r.Context().Value("user").(string)
+
+	settings, err := s.getPrivacySettings(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	if params.LibraryPublic != nil {
+		settings.libraryPublic = *params.LibraryPublic
+	}
+	if params.ReadingHistoryPublic != nil {
+		settings.readingHistoryPublic = *params.ReadingHistoryPublic
+	}
+	if params.ReadingStatsPublic != nil {
+		settings.readingStatsPublic = *params.ReadingStatsPublic
+	}
+
+	if err := s.updatePrivacySettings(r.Context(), id, settings); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
+
+// handleGetContentPreferences godoc
+//
+//	@Summary		Get content preferences
+//	@Description	Get the current user's birth date, whether they see mature books, the content warnings they hid and the content ratings they can read
+//	@Tags			users
+//	@Produce		json
+//	@Failure		404	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetContentPreferences.response
+//	@Router			/users/me/content-preferences [get]
+func (s *server) handleGetContentPreferences(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		BirthDate         *string  `json:"birthDate"`
+		ShowMatureContent bool     `json:"showMatureContent"`
+		HiddenWarnings    []string `json:"hiddenWarnings"`
+		AllowedRatings    []string `json:"allowedRatings"`
+	}
+
+	preferences, err := s.getContentPreferences(r.Context(), r.Context().Value("user").(string))
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	var birthDate *string
+	if preferences.birthDate.Valid {
+		date := preferences.birthDate.Time.Format(time.DateOnly)
+		birthDate = &date
+	}
+
+	encode(w, http.StatusOK, &response{BirthDate: birthDate, ShowMatureContent: preferences.showMature, HiddenWarnings: append([]string{}, preferences.hiddenWarnings...), AllowedRatings: preferences.allowedRatings(time.Now())})
+}
+
+// handleUpdateContentPreferences godoc
+//
+//	@Summary		Update content preferences
+//	@Description	Set the birth date (once), turn mature books on or off (18 and over only) and choose the content warnings to hide when browsing. Fields left out are unchanged.
+//	@Tags			users
+//	@Accept			json
+//	@Param			param	body		main.handleUpdateContentPreferences.request	true	"content preferences body"
+//	@Failure		400		{object}	errorResponse
+//	@Failure		404		{object}	errorResponse
+//	@Failure		409		{object}	errorResponse
+//	@Failure		500		{object}	errorResponse
+//	@Success		204
+//	@Router			/users/me/content-preferences [patch]
+func (s *server) handleUpdateContentPreferences(w http.ResponseWriter, r *http.Request) {
+	type request struct {
+		BirthDate         *string   `json:"birthDate"`
+		ShowMatureContent *bool     `json:"showMatureContent"`
+		HiddenWarnings    *[]string `json:"hiddenWarnings"`
+	}
+
+	var params request
+	if err := decode(r, &params); err != nil {
+		if errors.Is(err, errValidation) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: fmt.Sprintf("invalid data, %v", err)})
+			return
+		}
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "invalid json"})
+		return
+	}
+
+	if params.BirthDate == nil && params.ShowMatureContent == nil && params.HiddenWarnings == nil {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "should at least pass one field to update"})
+		return
+	}
+
+	id := r.Context().Value("user").(string)
+
+	preferences, err := s.getContentPreferences(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	now := time.Now()
+
+	if params.BirthDate != nil {
+		birthDate, err := time.Parse(time.DateOnly, *params.BirthDate)
+		if err != nil || !birthDate.Before(now) || birthDate.Year() < 1900 {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: errInvalidBirthDate.Error()})
+			return
+		}
+
+		if preferences.birthDate.Valid && !preferences.birthDate.Time.Equal(birthDate) {
+			encode(w, http.StatusConflict, &errorResponse{Error: errBirthDateSet.Error()})
+			return
+		}
+
+		preferences.birthDate = sql.NullTime{Time: birthDate, Valid: true}
+	}
+
+	if params.HiddenWarnings != nil {
+		warnings, err := normalizeContentWarnings(*params.HiddenWarnings)
+		if err != nil {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
+			return
+		}
+		preferences.hiddenWarnings = warnings
+	}
+
+	if params.ShowMatureContent != nil {
+		if *params.ShowMatureContent && (!preferences.birthDate.Valid || age(preferences.birthDate.Time, now) < adultAge) {
+			encode(w, http.StatusBadRequest, &errorResponse{Error: errMatureContentAge.Error()})
+			return
+		}
+		preferences.showMature = *params.ShowMatureContent
+	}
+
+	if err := s.updateContentPreferences(r.Context(), id, preferences); err != nil {
+		if errors.Is(err, errUserNotFound) {
+			encode(w, http.StatusNotFound, &errorResponse{Error: err.Error()})
+			return
+		}
+		s.logger.Error(err.Error())
+		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+		return
+	}
+
+	encode(w, http.StatusNoContent, nil)
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestHandleGetProfile(t *testing.T) {
@@ -60,3 +67,488 @@ func TestHandleGetProfile(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleEditProfile(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		req          map[string]string
+		expectedCode int
+	}{
+		{
+			name:         "no fields",
+			req:          map[string]string{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid display name",
+			req:          map[string]string{"display_name": "ab"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "edit profile",
+			req:          map[string]string{"display_name": "new_display", "about": "about me"},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for key, val := range tc.req {
+				writer.WriteField(key, val)
+			}
+			writer.Close()
+
+			r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me", body)
+			r.Header.Set("Content-Type", writer.FormDataContentType())
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleChangePassword(t *testing.T) {
+	type request struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "password too short",
+			body:         request{OldPassword: "test_password", NewPassword: "short"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "wrong old password",
+			body:         request{OldPassword: "wrong_password", NewPassword: "new_password"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "change password",
+			body:         request{OldPassword: "test_password", NewPassword: "new_password"},
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewReader(payload))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	svr := newServer(nil, db, nil, nil)
+
+	getProfile := func(token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		rr := httptest.NewRecorder()
+		svr.router.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	t.Run("other sessions are signed out", func(t *testing.T) {
+		// tokens are signed with second precision, a new second tells them apart
+		time.Sleep(time.Second)
+
+		payload, _ := json.Marshal(request{OldPassword: "new_password", NewPassword: "newer_password"})
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewReader(payload))
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		rr := httptest.NewRecorder()
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d", http.StatusNoContent, rr.Code)
+		}
+
+		var newToken string
+		for _, cookie := range rr.Result().Cookies() {
+			if cookie.Name == "access_token" {
+				newToken = cookie.Value
+			}
+		}
+
+		if code := getProfile(token); code != http.StatusUnauthorized {
+			t.Fatalf("expected old session to get %d, got %d", http.StatusUnauthorized, code)
+		}
+		if code := getProfile(newToken); code != http.StatusOK {
+			t.Fatalf("expected current session to get %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("users without a password sign in with their provider again", func(t *testing.T) {
+		if _, err := db.Exec(`UPDATE users SET password = NULL WHERE id = $1;`, id); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		token, err := createJWTToken(id, 5*time.Second)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		changePassword := func() int {
+			payload, _ := json.Marshal(request{NewPassword: "new_password"})
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewReader(payload))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+			svr.router.ServeHTTP(rr, r)
+			return rr.Code
+		}
+
+		if code := changePassword(); code != http.StatusUnauthorized {
+			t.Fatalf("expected %d without signing in again, got %d", http.StatusUnauthorized, code)
+		}
+
+		if err := svr.markReauthenticated(context.Background(), id); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if code := changePassword(); code != http.StatusNoContent {
+			t.Fatalf("expected %d after signing in again, got %d", http.StatusNoContent, code)
+		}
+	})
+}
+
+func TestHandleDeleteAccount(t *testing.T) {
+	type request struct {
+		Password   string `json:"password"`
+		Books      string `json:"books"`
+		TransferTo string `json:"transferTo,omitempty"`
+	}
+
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	createBook(t, id, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	refreshToken, err := createJWTToken(id, time.Hour)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), `DELETE FROM account_deletions WHERE user_id = $1;`, id); err != nil {
+			t.Errorf("error deleting account deletions, %v", err)
+		}
+		if _, err := db.ExecContext(context.Background(), `DELETE FROM users WHERE id = $1;`, id); err != nil {
+			t.Errorf("error deleting user, %v", err)
+		}
+	})
+
+	tests := []struct {
+		name         string
+		body         any
+		expectedCode int
+	}{
+		{
+			name:         "invalid books action",
+			body:         request{Password: "test_password", Books: "keep"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "transfer without user",
+			body:         request{Password: "test_password", Books: "transfer"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "wrong password",
+			body:         request{Password: "wrong_password", Books: "remove"},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "transfer to unknown user",
+			body:         request{Password: "test_password", Books: "transfer", TransferTo: uuid.NewString()},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "delete account",
+			body:         request{Password: "test_password", Books: "remove"},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "access token after deletion",
+			body:         request{Books: "remove"},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, _ := json.Marshal(tc.body)
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", bytes.NewReader(payload))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, &mc{})
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("refresh after deletion", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh-token", nil)
+		r.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+		rr := httptest.NewRecorder()
+
+		svr := newServer(nil, db, nil, nil)
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+}
+
+func TestHandleGetUserProfile(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	createBook(t, id, db)
+
+	tests := []struct {
+		name         string
+		userID       string
+		expectedCode int
+	}{
+		{
+			name:         "invalid user id",
+			userID:       "invalid",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "user not found",
+			userID:       uuid.NewString(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "get user profile",
+			userID:       id,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+tc.userID, nil)
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetPrivacySettings(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/privacy", nil)
+	r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+	rr := httptest.NewRecorder()
+
+	svr := newServer(nil, db, nil, nil)
+	svr.router.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var resp struct {
+		LibraryPublic        bool `json:"libraryPublic"`
+		ReadingHistoryPublic bool `json:"readingHistoryPublic"`
+		ReadingStatsPublic   bool `json:"readingStatsPublic"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if resp.LibraryPublic || resp.ReadingHistoryPublic || resp.ReadingStatsPublic {
+		t.Fatalf("expected everything to be private by default, got %+v", resp)
+	}
+}
+
+func TestHandleUpdatePrivacySettings(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "no fields",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "update privacy settings",
+			body:         `{"libraryPublic": true, "readingStatsPublic": true}`,
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me/privacy", bytes.NewReader([]byte(tc.body)))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleUpdateContentPreferences(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "no fields",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid birth date",
+			body:         `{"birthDate": "15/06/1990"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "birth date in the future",
+			body:         `{"birthDate": "2999-01-01"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "mature content without birth date",
+			body:         `{"showMatureContent": true}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown content warning",
+			body:         `{"hiddenWarnings": ["spiders"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "set birth date",
+			body:         `{"birthDate": "1990-06-15", "hiddenWarnings": ["Gore"]}`,
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "change birth date",
+			body:         `{"birthDate": "2015-06-15"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "turn mature content on",
+			body:         `{"showMatureContent": true}`,
+			expectedCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me/content-preferences", bytes.NewReader([]byte(tc.body)))
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			rr := httptest.NewRecorder()
+
+			svr := newServer(nil, db, nil, nil)
+			svr.router.ServeHTTP(rr, r)
+
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("expected %d, got %d", tc.expectedCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("get content preferences", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/content-preferences", nil)
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		rr := httptest.NewRecorder()
+
+		svr := newServer(nil, db, nil, nil)
+		svr.router.ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		var resp struct {
+			BirthDate      string   `json:"birthDate"`
+			HiddenWarnings []string `json:"hiddenWarnings"`
+			AllowedRatings []string `json:"allowedRatings"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if resp.BirthDate != "1990-06-15" || !slices.Equal(resp.HiddenWarnings, []string{"gore"}) || !slices.Contains(resp.AllowedRatings, ratingMature) {
+			t.Fatalf("unexpected content preferences, %+v", resp)
+		}
+	})
+}
@@ -1,154 +1,473 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-type eventType int
-
 const (
-	NEW_BOOK eventType = iota
-	CHAPTER_UPLOADED
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
+	sendBufferSize = 16
+	// hubBacklog is how many events can wait for the hub before new ones are
+	// dropped instead of blocking the publisher
+	hubBacklog = 256
 )
 
-func (e eventType) String() string {
-	switch e {
-	case NEW_BOOK:
-		return "NEW_BOOK"
-	case CHAPTER_UPLOADED:
-		return "CHAPTER_UPLOADED"
-	}
-	return "Unknown event"
-}
-
-type event struct {
-	Type    eventType
-	Payload interface{}
-}
+// slowClientPolicy decides what happens to a connection whose send buffer is
+// full when the hub has something for it
+type slowClientPolicy int
 
-type chapterUploadEvent struct {
-	BookId  string
-	Message string
-}
+const (
+	disconnectSlowClients slowClientPolicy = iota
+	dropForSlowClients
+)
 
 type client struct {
-	id   string
+	id          string
+	connID      string
+	displayName string
+	admin       bool
+	// conn is nil for clients streaming server-sent events
 	conn *websocket.Conn
+	sse  bool
 	send chan []byte
+	// closed is closed by the hub once the connection is removed. send is never
+	// closed so the reading goroutine can't panic writing to it.
+	closed    chan struct{}
+	closeCode int
+	// rooms is only touched by the goroutine reading from the connection
+	rooms map[string]bool
 }
 
 type roomUser struct {
 	roomID string
-	userID string
+	client *client
+}
+
+type connectRequest struct {
+	client *client
+	result chan error
+}
+
+type presenceQuery struct {
+	userIDs []string
+	result  chan map[string]bool
+}
+
+var errTooManyConnections = errors.New("too many connections")
+
+// hubStats are updated without holding the hub so they can be read from any
+// goroutine
+type hubStats struct {
+	connections   atomic.Int64
+	dropped       atomic.Int64
+	disconnected  atomic.Int64
+	droppedEvents atomic.Int64
 }
 
+// every map of clients is keyed by user id and then by connection id, so a
+// user can be connected from several tabs or devices at once
 type hub struct {
-	regular            map[string]*client
-	admins             map[string]*client
+	regular            map[string]map[string]*client
+	admins             map[string]map[string]*client
 	rooms              map[string]map[string]*client
+	maxConnections     int
+	bufferSize         int
+	policy             slowClientPolicy
+	replayLimit        int
+	replayMaxAge       time.Duration
+	stats              hubStats
 	joinRoom           chan *roomUser
-	connectAdmin       chan *client
-	connectRegular     chan *client
-	disconnectAdmin    chan string
-	disconnectRegular  chan string
+	connect            chan *connectRequest
+	disconnect         chan *client
 	disconnectRoomUser chan *roomUser
+	presence           chan *presenceQuery
 	broadcast          chan *event
 }
 
 func newHub() *hub {
+	maxConnections := 5
+	if n, err := strconv.Atoi(os.Getenv("WS_MAX_CONNECTIONS_PER_USER")); err == nil && n > 0 {
+		maxConnections = n
+	}
+
+	bufferSize := sendBufferSize
+	if n, err := strconv.Atoi(os.Getenv("WS_SEND_BUFFER_SIZE")); err == nil && n > 0 {
+		bufferSize = n
+	}
+
+	policy := disconnectSlowClients
+	if os.Getenv("WS_SLOW_CLIENT_POLICY") == "drop" {
+		policy = dropForSlowClients
+	}
+
+	replayLimit := 100
+	if n, err := strconv.Atoi(os.Getenv("WS_REPLAY_MAX_EVENTS")); err == nil && n > 0 {
+		replayLimit = n
+	}
+
+	replayMaxAge := 72 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("WS_REPLAY_MAX_AGE")); err == nil && d > 0 {
+		replayMaxAge = d
+	}
+
 	return &hub{
-		regular:            make(map[string]*client),
-		admins:             make(map[string]*client),
+		regular:            make(map[string]map[string]*client),
+		admins:             make(map[string]map[string]*client),
 		rooms:              make(map[string]map[string]*client),
+		maxConnections:     maxConnections,
+		bufferSize:         bufferSize,
+		policy:             policy,
+		replayLimit:        replayLimit,
+		replayMaxAge:       replayMaxAge,
 		joinRoom:           make(chan *roomUser),
-		connectAdmin:       make(chan *client),
-		connectRegular:     make(chan *client),
-		disconnectAdmin:    make(chan string),
-		disconnectRegular:  make(chan string),
+		connect:            make(chan *connectRequest),
+		disconnect:         make(chan *client),
 		disconnectRoomUser: make(chan *roomUser),
-		broadcast:          make(chan *event),
+		presence:           make(chan *presenceQuery),
+		broadcast:          make(chan *event, hubBacklog),
+	}
+}
+
+func (h *hub) newClient(userID string, conn *websocket.Conn) *client {
+	return &client{
+		id:     userID,
+		connID: newConnectionID(),
+		conn:   conn,
+		send:   make(chan []byte, h.bufferSize),
+		closed: make(chan struct{}),
+		rooms:  map[string]bool{},
 	}
 }
 
-// writing to pump because some connections can be slow
+func newConnectionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// writePump is the only goroutine writing to the connection. It pings the
+// client to keep the read deadline moving and sends a close frame once the hub
+// removes the connection.
 func (c *client) writePump() {
-	for msg := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-c.closed:
+			code := c.closeCode
+			if code == 0 {
+				code = websocket.CloseNormalClosure
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""))
 			return
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
 
-func (s *server) handleNewBookEvent(event *event) {
-	body, err := json.Marshal(event)
+// eventFrame formats an event for the client's transport
+func (c *client) eventFrame(env *envelope) ([]byte, error) {
+	if c.sse {
+		return sseFrame(env.Type, env.Seq, env)
+	}
+	return eventMessage(env)
+}
+
+func (c *client) replayedFrame(count int, truncated bool) []byte {
+	if c.sse {
+		frame, _ := sseFrame("replayed", 0, replayPayload{Count: count, Truncated: truncated})
+		return frame
+	}
+	return replayedMessage(count, truncated)
+}
+
+// reply queues a response to one of the client's own commands. It waits for
+// room in the buffer, which only slows down the client that sent the command.
+func (c *client) reply(msg []byte) {
+	select {
+	case c.send <- msg:
+	case <-c.closed:
+	}
+}
+
+// deliver never blocks the hub. When the connection's buffer is full the
+// message is dropped and, unless the policy says otherwise, the connection is
+// closed so the client can reconnect and catch up.
+func (h *hub) deliver(c *client, e *event) {
+	env, err := e.wrap(e.Seqs[c.id])
 	if err != nil {
 		return
 	}
-	for _, client := range s.hub.admins {
-		client.send <- body
+
+	body, err := c.eventFrame(env)
+	if err != nil {
+		return
+	}
+
+	select {
+	case c.send <- body:
+		return
+	default:
+	}
+
+	h.stats.dropped.Add(1)
+
+	if h.policy == dropForSlowClients || e.Type.ephemeral() {
+		return
+	}
+
+	c.closeCode = websocket.CloseTryAgainLater
+	h.removeClient(c)
+	h.stats.disconnected.Add(1)
+}
+
+// handleAdminEvent delivers the event to every admin connection
+func (s *server) handleAdminEvent(event *event) {
+	for _, conns := range s.hub.admins {
+		for _, client := range conns {
+			s.hub.deliver(client, event)
+		}
 	}
 }
 
 func (s *server) handleNewChapterUploadedEvent(event *event) {
 	payload := event.Payload.(chapterUploadEvent)
 
-	if room, ok := s.hub.rooms[payload.BookId]; ok {
-		for _, client := range room {
-			client.send <- []byte(payload.Message)
+	for _, client := range s.hub.rooms[payload.BookId] {
+		s.hub.deliver(client, event)
+	}
+}
+
+// handleUserEvent delivers the event to every connection of a single user
+func (s *server) handleUserEvent(event *event, userID string) {
+	for _, client := range s.hub.regular[userID] {
+		s.hub.deliver(client, event)
+	}
+}
+
+func (s *server) handleTypingEvent(event *event) {
+	payload := event.Payload.(typingEvent)
+
+	for _, client := range s.hub.rooms[payload.BookId] {
+		if client.id != payload.UserId {
+			s.hub.deliver(client, event)
 		}
 	}
 }
 
+// dispatch hands an event to the hub without waiting for it, so HTTP handlers
+// never stall behind websocket clients. Events are dropped while the hub is
+// backlogged.
+func (h *hub) dispatch(e *event) {
+	select {
+	case h.broadcast <- e:
+	default:
+		h.stats.droppedEvents.Add(1)
+	}
+}
+
+func (h *hub) addClient(c *client) error {
+	if len(h.regular[c.id]) >= h.maxConnections {
+		return errTooManyConnections
+	}
+
+	if h.regular[c.id] == nil {
+		h.regular[c.id] = map[string]*client{}
+	}
+	h.regular[c.id][c.connID] = c
+
+	if c.admin {
+		if h.admins[c.id] == nil {
+			h.admins[c.id] = map[string]*client{}
+		}
+		h.admins[c.id][c.connID] = c
+	}
+
+	h.stats.connections.Add(1)
+	return nil
+}
+
+// removeClient forgets a connection and closes its closed channel, which makes
+// writePump close the connection. Other connections of the same user are left
+// alone.
+func (h *hub) removeClient(c *client) {
+	if _, ok := h.regular[c.id][c.connID]; !ok {
+		return
+	}
+
+	for _, conns := range []map[string]map[string]*client{h.regular, h.admins} {
+		delete(conns[c.id], c.connID)
+		if len(conns[c.id]) == 0 {
+			delete(conns, c.id)
+		}
+	}
+
+	for roomID, room := range h.rooms {
+		delete(room, c.connID)
+		if len(room) == 0 {
+			delete(h.rooms, roomID)
+		}
+	}
+
+	h.stats.connections.Add(-1)
+	close(c.closed)
+}
+
+func (h *hub) onlineUsers(userIDs []string) map[string]bool {
+	online := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		online[id] = len(h.regular[id]) > 0
+	}
+	return online
+}
+
+// onlineUsers reports which of the users have at least one open websocket
+// connection to this replica
+func (s *server) onlineUsers(userIDs ...string) map[string]bool {
+	q := &presenceQuery{userIDs: userIDs, result: make(chan map[string]bool, 1)}
+	s.hub.presence <- q
+	return <-q.result
+}
+
 func (s *server) run() {
 	for {
 		select {
-		case client := <-s.hub.connectAdmin:
-			s.hub.admins[client.id] = client
-			s.hub.regular[client.id] = client
-		case client := <-s.hub.connectRegular:
-			s.hub.regular[client.id] = client
+		case req := <-s.hub.connect:
+			req.result <- s.hub.addClient(req.client)
 		case ru := <-s.hub.joinRoom:
+			// the connection may already be gone
+			if _, ok := s.hub.regular[ru.client.id][ru.client.connID]; !ok {
+				continue
+			}
 			if s.hub.rooms[ru.roomID] == nil {
 				s.hub.rooms[ru.roomID] = map[string]*client{}
 			}
-			if client, ok := s.hub.regular[ru.userID]; ok {
-				s.hub.rooms[ru.roomID][ru.userID] = client
-			}
-
-		case id := <-s.hub.disconnectAdmin:
-			if client, ok := s.hub.admins[id]; ok {
-				delete(s.hub.admins, id)
-				client.conn.Close()
-			}
-			if client, ok := s.hub.regular[id]; ok {
-				delete(s.hub.regular, id)
-				client.conn.Close()
-			}
-		case id := <-s.hub.disconnectRegular:
-			if client, ok := s.hub.regular[id]; ok {
-				delete(s.hub.regular, id)
-				client.conn.Close()
+			s.hub.rooms[ru.roomID][ru.client.connID] = ru.client
+		case client := <-s.hub.disconnect:
+			s.hub.removeClient(client)
+		case ru := <-s.hub.disconnectRoomUser:
+			delete(s.hub.rooms[ru.roomID], ru.client.connID)
+			if len(s.hub.rooms[ru.roomID]) == 0 {
+				delete(s.hub.rooms, ru.roomID)
 			}
-		case room := <-s.hub.disconnectRoomUser:
-			delete(s.hub.rooms[room.roomID], room.userID)
+		case q := <-s.hub.presence:
+			q.result <- s.hub.onlineUsers(q.userIDs)
 		case event := <-s.hub.broadcast:
 			switch event.Type {
-			case NEW_BOOK:
-				s.handleNewBookEvent(event)
+			case NEW_BOOK, MODERATION_UPDATED, NEW_REPORT:
+				s.handleAdminEvent(event)
 			case CHAPTER_UPLOADED:
 				s.handleNewChapterUploadedEvent(event)
+			case NEW_FOLLOWER:
+				s.handleUserEvent(event, event.Payload.(followerEvent).UserId)
+			case BOOK_MODERATED:
+				s.handleUserEvent(event, event.Payload.(bookModeratedEvent).AuthorId)
+			case TYPING:
+				s.handleTypingEvent(event)
 			}
 		}
 	}
 }
 
+// newUserClient builds the hub client for a user and looks up the books whose
+// rooms it joins once attached
+func (s *server) newUserClient(ctx context.Context, userID string) (*client, []string, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Add client to book room if he has book in his library
+	bookIDs, err := s.getUserLibrary(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting book, %v", err)
+	}
+
+	c := s.hub.newClient(userID, nil)
+	c.displayName = user.displayName
+
+	c.admin = user.can(permApproveBook) && !staffTwoFactorRequired(user)
+
+	return c, bookIDs, nil
+}
+
+// attachClient registers the client with the hub and joins its rooms. Once it
+// succeeds the client has to be sent to s.hub.disconnect when it goes away.
+func (s *server) attachClient(c *client, bookIDs []string) error {
+	req := &connectRequest{client: c, result: make(chan error, 1)}
+	s.hub.connect <- req
+	if err := <-req.result; err != nil {
+		return err
+	}
+
+	for _, bookID := range bookIDs {
+		s.hub.joinRoom <- &roomUser{roomID: bookID, client: c}
+		c.rooms[bookID] = true
+	}
+
+	return nil
+}
+
+// missedEventFrames formats the events the client missed since lastSeenSeq,
+// followed by the summary of the replay, for the client's transport
+func (s *server) missedEventFrames(ctx context.Context, c *client, lastSeenSeq int64) ([][]byte, error) {
+	events, truncated, err := s.getMissedEvents(ctx, c.id, lastSeenSeq, c.admin, s.hub.replayLimit, s.hub.replayMaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([][]byte, 0, len(events)+1)
+	for _, env := range events {
+		frame, err := c.eventFrame(env)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return append(frames, c.replayedFrame(len(events), truncated)), nil
+}
+
 func (s *server) handleWS(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user").(string)
-	user, err := s.getUser(r.Context(), userID)
+
+	replay := r.URL.Query().Has("lastSeenSeq")
+	lastSeenSeq, err := strconv.ParseInt(r.URL.Query().Get("lastSeenSeq"), 10, 64)
+	if replay && (err != nil || lastSeenSeq < 0) {
+		encode(w, http.StatusBadRequest, &errorResponse{Error: "lastSeenSeq should be a valid number"})
+		return
+	}
+
+	newClient, bookIDs, err := s.newUserClient(r.Context(), userID)
 	if err != nil {
 		s.logger.Error(err.Error())
 		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
@@ -166,65 +485,104 @@ func (s *server) handleWS(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("error upgrading ws connection, %v", err))
-		encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
 		return
 	}
+	newClient.conn = conn
 
-	var isAdmin bool
-	for _, role := range user.roles {
-		if role == "ADMIN" {
-			isAdmin = true
-			break
-		}
+	if err := s.attachClient(newClient, bookIDs); err != nil {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(writeWait))
+		conn.Close()
+		return
 	}
 
-	newClient := &client{id: userID, conn: conn, send: make(chan []byte)}
-	go newClient.writePump()
+	defer func() {
+		s.hub.disconnect <- newClient
+	}()
 
-	if isAdmin == true {
-		s.hub.connectAdmin <- newClient
-	} else {
-		s.hub.connectRegular <- newClient
-	}
+	// live events are buffered while the missed ones are written straight to the
+	// connection, before writePump starts
+	if replay {
+		frames, err := s.missedEventFrames(r.Context(), newClient, lastSeenSeq)
+		if err != nil {
+			s.logger.Error(err.Error())
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal server error"), time.Now().Add(writeWait))
+			conn.Close()
+			return
+		}
 
-	// Add client to book room if he has book in his library
-	bookIDs, err := s.getUserLibrary(r.Context(), userID)
-	if err != nil {
-		s.logger.Error(fmt.Sprintf("error getting book, %v", err))
-		conn.WriteMessage(websocket.CloseMessage, []byte("internal server error"))
-		return
-	}
-	for _, bookID := range bookIDs {
-		s.hub.joinRoom <- &roomUser{roomID: bookID, userID: userID}
-		defer func(id string) {
-			s.hub.disconnectRoomUser <- &roomUser{
-				roomID: id,
-				userID: userID,
+		for _, frame := range frames {
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				conn.Close()
+				return
 			}
-		}(bookID)
+		}
 	}
 
-	defer func() {
-		if isAdmin {
-			s.hub.disconnectAdmin <- userID
-		} else {
-			s.hub.disconnectRegular <- userID
-		}
-	}()
+	go newClient.writePump()
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
 
 	for {
 		_, message, err := conn.ReadMessage()
-
 		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.logger.Error(fmt.Sprintf("error reading ws message, %v", err))
+			}
 			break
 		}
 
-		var evt event
-		if err := json.Unmarshal(message, &evt); err != nil {
-			if err := conn.WriteJSON(&errorResponse{Error: fmt.Sprintf("unable to unmarshal json, %v", err)}); err != nil {
-				break
-			}
+		var msg clientMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			newClient.reply(errorMessage("", fmt.Errorf("unable to unmarshal json, %v", err)))
+			continue
+		}
+
+		if err := validate.Struct(&msg); err != nil {
+			newClient.reply(errorMessage(msg.ID, fmt.Errorf("%w, %w", errValidation, err)))
 			continue
 		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), writeWait)
+		err = s.handleCommand(ctx, newClient, &msg)
+		cancel()
+
+		if err != nil {
+			newClient.reply(errorMessage(msg.ID, err))
+			continue
+		}
+
+		newClient.reply(ackMessage(msg.ID))
 	}
 }
+
+// handleGetWSStats godoc
+//
+//	@Summary		Get websocket delivery stats
+//	@Description	Get connection count and dropped message counters for this replica
+//	@Tags			ws
+//	@Failure		401	{object}	errorResponse
+//	@Failure		403	{object}	errorResponse
+//	@Failure		500	{object}	errorResponse
+//	@Success		200	{object}	main.handleGetWSStats.response
+//	@Router			/ws/stats [get]
+func (s *server) handleGetWSStats(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Connections     int64 `json:"connections"`
+		DroppedMessages int64 `json:"droppedMessages"`
+		SlowDisconnects int64 `json:"slowDisconnects"`
+		DroppedEvents   int64 `json:"droppedEvents"`
+	}
+
+	encode(w, http.StatusOK, &response{
+		Connections:     s.hub.stats.connections.Load(),
+		DroppedMessages: s.hub.stats.dropped.Load(),
+		SlowDisconnects: s.hub.stats.disconnected.Load(),
+		DroppedEvents:   s.hub.stats.droppedEvents.Load(),
+	})
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHandleCommand(t *testing.T) {
+	svr := newServer(nil, nil, nil, nil)
+	c := &client{id: "123", send: make(chan []byte, sendBufferSize), rooms: map[string]bool{}}
+
+	tests := []struct {
+		name        string
+		msg         clientMessage
+		expectedErr error
+	}{
+		{
+			name:        "unknown command",
+			msg:         clientMessage{ID: "1", Type: "unknown"},
+			expectedErr: errUnknownCommand,
+		},
+		{
+			name:        "missing payload",
+			msg:         clientMessage{ID: "2", Type: commandSubscribe},
+			expectedErr: errValidation,
+		},
+		{
+			name:        "invalid book id",
+			msg:         clientMessage{ID: "3", Type: commandSubscribe, Payload: json.RawMessage(`{"bookId": "invalid"}`)},
+			expectedErr: errValidation,
+		},
+		{
+			name:        "typing without subscribing",
+			msg:         clientMessage{ID: "4", Type: commandTyping, Payload: json.RawMessage(`{"bookId": "6f1e7a52-3c8b-4d0e-9b7a-0f4c7f2d9e11"}`)},
+			expectedErr: errNotSubscribed,
+		},
+		{
+			name:        "unsubscribe without subscribing",
+			msg:         clientMessage{ID: "5", Type: commandUnsubscribe, Payload: json.RawMessage(`{"bookId": "6f1e7a52-3c8b-4d0e-9b7a-0f4c7f2d9e11"}`)},
+			expectedErr: errNotSubscribed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := svr.handleCommand(context.Background(), c, &tc.msg); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func connectClient(t *testing.T, s *server, c *client) {
+	req := &connectRequest{client: c, result: make(chan error, 1)}
+	s.hub.connect <- req
+	if err := <-req.result; err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestHubConnections(t *testing.T) {
+	h := newHub()
+	h.maxConnections = 2
+
+	first := &client{id: "123", connID: "a", admin: true, send: make(chan []byte, 1), closed: make(chan struct{})}
+	second := &client{id: "123", connID: "b", send: make(chan []byte, 1), closed: make(chan struct{})}
+	third := &client{id: "123", connID: "c", send: make(chan []byte, 1), closed: make(chan struct{})}
+
+	if err := h.addClient(first); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := h.addClient(second); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := h.addClient(third); !errors.Is(err, errTooManyConnections) {
+		t.Fatalf("expected %v, got %v", errTooManyConnections, err)
+	}
+
+	h.rooms["book"] = map[string]*client{first.connID: first, second.connID: second}
+
+	h.removeClient(first)
+
+	if _, ok := h.regular["123"]["b"]; !ok {
+		t.Fatal("expected second connection to be kept")
+	}
+	if _, ok := h.rooms["book"]["b"]; !ok {
+		t.Fatal("expected second connection to stay in the room")
+	}
+	if _, ok := h.admins["123"]; ok {
+		t.Fatal("expected admin connection to be removed")
+	}
+	select {
+	case <-first.closed:
+	default:
+		t.Fatal("expected connection to be closed")
+	}
+	if !h.onlineUsers([]string{"123"})["123"] {
+		t.Fatal("expected user to be online")
+	}
+
+	h.removeClient(second)
+	// removing twice must not close the channel again
+	h.removeClient(second)
+
+	if _, ok := h.rooms["book"]; ok {
+		t.Fatal("expected empty room to be removed")
+	}
+	if h.onlineUsers([]string{"123"})["123"] {
+		t.Fatal("expected user to be offline")
+	}
+}
+
+func TestOnlineUsers(t *testing.T) {
+	svr := newServer(nil, nil, nil, nil)
+	connectClient(t, svr, svr.hub.newClient("online", nil))
+
+	online := svr.onlineUsers("online", "offline")
+
+	if !online["online"] || online["offline"] {
+		t.Fatalf("expected only online user to be online, got %v", online)
+	}
+}
+
+func TestSlowClients(t *testing.T) {
+	const events = 50
+
+	tests := []struct {
+		name             string
+		policy           slowClientPolicy
+		event            *event
+		expectConnected  bool
+		expectedDisconns int64
+	}{
+		{
+			name:             "disconnect slow client",
+			policy:           disconnectSlowClients,
+			event:            newEvent(chapterUploadEvent{BookId: "book"}),
+			expectConnected:  false,
+			expectedDisconns: 1,
+		},
+		{
+			name:            "drop messages for slow client",
+			policy:          dropForSlowClients,
+			event:           newEvent(chapterUploadEvent{BookId: "book"}),
+			expectConnected: true,
+		},
+		{
+			name:            "typing is always dropped",
+			policy:          disconnectSlowClients,
+			event:           newEvent(typingEvent{BookId: "book", UserId: "typist"}),
+			expectConnected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := newServer(nil, nil, nil, nil)
+			svr.hub.policy = tc.policy
+			svr.hub.bufferSize = 1
+
+			// slow never reads, fast reads everything
+			slow := svr.hub.newClient("slow", nil)
+			svr.hub.bufferSize = events
+			fast := svr.hub.newClient("fast", nil)
+
+			for _, c := range []*client{slow, fast} {
+				connectClient(t, svr, c)
+				svr.hub.joinRoom <- &roomUser{roomID: "book", client: c}
+			}
+
+			for range events {
+				if err := svr.fanOutEvent(context.Background(), tc.event); err != nil {
+					t.Fatal(err.Error())
+				}
+			}
+
+			for i := range events {
+				select {
+				case <-fast.send:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("fast client got %d of %d events", i, events)
+				}
+			}
+
+			// the presence query is served after the last event was delivered
+			online := svr.onlineUsers("slow", "fast")
+
+			if !online["fast"] {
+				t.Fatal("expected fast client to stay connected")
+			}
+			if online["slow"] != tc.expectConnected {
+				t.Fatalf("expected slow client connected to be %v", tc.expectConnected)
+			}
+			if got := svr.hub.stats.dropped.Load(); got != events-1 && tc.expectConnected {
+				t.Fatalf("expected %d dropped messages, got %d", events-1, got)
+			}
+			if got := svr.hub.stats.disconnected.Load(); got != tc.expectedDisconns {
+				t.Fatalf("expected %d disconnects, got %d", tc.expectedDisconns, got)
+			}
+
+			if !tc.expectConnected {
+				<-slow.closed
+				if slow.closeCode != websocket.CloseTryAgainLater {
+					t.Fatalf("expected close code %d, got %d", websocket.CloseTryAgainLater, slow.closeCode)
+				}
+			}
+		})
+	}
+}
+
+func TestFanOutEventBacklog(t *testing.T) {
+	// nothing runs this hub, so every event past the backlog has to be dropped
+	svr := &server{hub: newHub()}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range hubBacklog + 10 {
+			svr.fanOutEvent(context.Background(), newEvent(newBookEvent{}))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected publishing to never block")
+	}
+
+	if got := svr.hub.stats.droppedEvents.Load(); got != 10 {
+		t.Fatalf("expected 10 dropped events, got %d", got)
+	}
+}
+
+func TestWritePump(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	received := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("error upgrading connection, %v", err)
+			return
+		}
+
+		c := &client{id: "123", conn: conn, send: make(chan []byte, 1), closed: make(chan struct{})}
+		go c.writePump()
+
+		c.send <- ackMessage("1")
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+		}
+		close(c.closed)
+	}))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg serverMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if msg.Type != "ack" || msg.ID != "1" {
+		t.Fatalf("expected ack for 1, got %+v", msg)
+	}
+	close(received)
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected normal close, got %v", err)
+	}
+}
+
+func TestHandleWS(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	ts := httptest.NewServer(svr.router)
+	defer ts.Close()
+
+	header := http.Header{}
+	header.Add("Cookie", (&http.Cookie{Name: "access_token", Value: token}).String())
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/ws", header)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name         string
+		msg          string
+		expectedType string
+	}{
+		{
+			name:         "invalid json",
+			msg:          `{`,
+			expectedType: "error",
+		},
+		{
+			name:         "unknown command",
+			msg:          `{"id": "1", "type": "unknown"}`,
+			expectedType: "error",
+		},
+		{
+			name:         "book not found",
+			msg:          `{"id": "2", "type": "subscribe", "payload": {"bookId": "6f1e7a52-3c8b-4d0e-9b7a-0f4c7f2d9e11"}}`,
+			expectedType: "error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tc.msg)); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+			var msg serverMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if msg.Type != tc.expectedType {
+				t.Fatalf("expected %s, got %s", tc.expectedType, msg.Type)
+			}
+		})
+	}
+}
+
+func TestHandleWSReplay(t *testing.T) {
+	db := connectTestDb(t)
+	id := createAndCleanUpUser(t, db)
+	token, err := createJWTToken(id, 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	svr := newServer(nil, db, nil, nil)
+	ts := httptest.NewServer(svr.router)
+	defer ts.Close()
+
+	var seqs []int64
+	for range 3 {
+		e := newEvent(followerEvent{UserId: id, Message: "user followed you"})
+		if err := svr.publishEvent(context.Background(), e); err != nil {
+			t.Fatal(err.Error())
+		}
+		seqs = append(seqs, e.Seqs[id])
+	}
+
+	header := http.Header{}
+	header.Add("Cookie", (&http.Cookie{Name: "access_token", Value: token}).String())
+
+	url := fmt.Sprintf("ws%s/api/v1/ws?lastSeenSeq=%d", strings.TrimPrefix(ts.URL, "http"), seqs[0])
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for _, seq := range seqs[1:] {
+		var msg serverMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err.Error())
+		}
+		if msg.Event == nil || msg.Event.Type != NEW_FOLLOWER.String() || msg.Event.Seq != seq {
+			t.Fatalf("expected %s with seq %d, got %+v", NEW_FOLLOWER, seq, msg)
+		}
+	}
+
+	var msg struct {
+		Type    string        `json:"type"`
+		Payload replayPayload `json:"payload"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err.Error())
+	}
+	if msg.Type != "replayed" || msg.Payload.Count != 2 || msg.Payload.Truncated {
+		t.Fatalf("expected 2 replayed events, got %+v", msg)
+	}
+}
@@ -9,8 +9,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const scopeTwoFactor = "2fa"
+
 type userClaims struct {
-	Id string
+	Id    string
+	Scope string `json:",omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -19,6 +22,7 @@ func createJWTToken(id string, t time.Duration) (string, error) {
 		Id: id,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "pagesy",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(t)),
 		},
 	}).SignedString([]byte(os.Getenv("JWT_SECRET")))
@@ -30,12 +34,101 @@ func createJWTToken(id string, t time.Duration) (string, error) {
 	return token, nil
 }
 
-func decodeJWTToken(token string) (string, error) {
+// createChallengeToken is handed out after a correct password when the user
+// still has to pass their second factor. It can't be used as an access token.
+func createChallengeToken(id string) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &userClaims{
+		Id:    id,
+		Scope: scopeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "pagesy",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		},
+	}).SignedString([]byte(os.Getenv("JWT_SECRET")))
+
+	if err != nil {
+		return "", fmt.Errorf("error creating challenge token, %v", err)
+	}
+
+	return token, nil
+}
+
+// setChallengeCookie hands the challenge token of a provider sign in to the
+// verify endpoint, so it never shows up in the frontend url, its history or
+// referrers
+func setChallengeCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "challenge_token",
+		Value:    token,
+		Path:     "/api/v1/auth/2fa",
+		HttpOnly: true,
+		Secure:   false,
+		MaxAge:   5 * 60,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearChallengeCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   "challenge_token",
+		Value:  "",
+		Path:   "/api/v1/auth/2fa",
+		MaxAge: -1,
+	})
+}
+
+func parseJWTToken(token string) (*userClaims, error) {
 	var user userClaims
 	if _, err := jwt.ParseWithClaims(token, &user, func(t *jwt.Token) (any, error) {
 		return []byte(os.Getenv("JWT_SECRET")), nil
 	}); err != nil {
-		return "", fmt.Errorf("error parsing token, %v", err)
+		return nil, fmt.Errorf("error parsing token, %v", err)
+	}
+
+	return &user, nil
+}
+
+func decodeJWTToken(token string) (string, error) {
+	user, err := parseJWTToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	if user.Scope != "" {
+		return "", fmt.Errorf("error parsing token, invalid scope %v", user.Scope)
+	}
+
+	return user.Id, nil
+}
+
+// decodeSessionToken returns the user and when the access or refresh token was
+// issued, tokens issued before the user's sessions were revoked are refused
+func decodeSessionToken(token string) (string, time.Time, error) {
+	user, err := parseJWTToken(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if user.Scope != "" {
+		return "", time.Time{}, fmt.Errorf("error parsing token, invalid scope %v", user.Scope)
+	}
+
+	var issuedAt time.Time
+	if user.IssuedAt != nil {
+		issuedAt = user.IssuedAt.Time
+	}
+
+	return user.Id, issuedAt, nil
+}
+
+func decodeChallengeToken(token string) (string, error) {
+	user, err := parseJWTToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	if user.Scope != scopeTwoFactor {
+		return "", fmt.Errorf("error parsing token, not a challenge token")
 	}
 
 	return user.Id, nil
@@ -81,3 +81,31 @@ func TestCreateAccessAndRefreshToken(t *testing.T) {
 		t.Fatal("expected accesss and refresh token")
 	}
 }
+
+func TestChallengeToken(t *testing.T) {
+	token, err := createChallengeToken("123")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := decodeJWTToken(token); err == nil {
+		t.Fatal("expected challenge token to be rejected as an access token")
+	}
+
+	id, err := decodeChallengeToken(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if id != "123" {
+		t.Fatalf("expected 123, got %v", id)
+	}
+
+	accessToken, err := createJWTToken("123", 5*time.Second)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := decodeChallengeToken(accessToken); err == nil {
+		t.Fatal("expected access token to be rejected as a challenge token")
+	}
+}
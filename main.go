@@ -18,6 +18,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
+	"github.com/markbates/goth/providers/discord"
+	"github.com/markbates/goth/providers/github"
 	"github.com/markbates/goth/providers/google"
 
 	_ "github.com/lib/pq"
@@ -27,6 +29,15 @@ import (
 
 const (
 	queueChapterUploaded = "book.chapter_uploaded"
+	queueAccountDeleted  = "user.account_deleted"
+	queueDataExport      = "user.data_export"
+	// uploaded chapters wait on queueChapterModeration until the moderation
+	// worker checks them, and come back on queueChapterPublished if they pass
+	queueChapterModeration = "book.chapter_moderation"
+	queueChapterPublished  = "book.chapter_published"
+	// the moderation worker fingerprints the chapters of books sent on
+	// queueChapterFingerprint and matches them against earlier books
+	queueChapterFingerprint = "book.chapter_fingerprint"
 )
 
 type channel interface {
@@ -40,6 +51,8 @@ type server struct {
 	objectStore objectStore
 	hub         *hub
 	ch          channel
+	limiter     limiterStore
+	limits      map[string]rateLimit
 }
 
 func newServer(logger *slog.Logger, store *sql.DB, objectStore objectStore, ch channel) *server {
@@ -50,6 +63,12 @@ func newServer(logger *slog.Logger, store *sql.DB, objectStore objectStore, ch c
 		objectStore: objectStore,
 		hub:         newHub(),
 		ch:          ch,
+		limiter:     newMemoryLimiterStore(),
+		limits:      loadRateLimits(),
+	}
+	// buckets are shared between replicas only when they live in postgres
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		s.limiter = newPostgresLimiterStore(store)
 	}
 	go s.run()
 	s.routes()
@@ -61,9 +80,16 @@ func newServer(logger *slog.Logger, store *sql.DB, objectStore objectStore, ch c
 // @BasePath	/api/v1
 func main() {
 	godotenv.Load()
-	goth.UseProviders(
+	providers := []goth.Provider{
 		google.New(os.Getenv("GOOGLE_CLIENT_ID"), os.Getenv("GOOGLE_CLIENT_SECRET"), fmt.Sprintf("%s/api/v1/auth/google/callback", os.Getenv("HOST"))),
-	)
+	}
+	if os.Getenv("GITHUB_CLIENT_ID") != "" {
+		providers = append(providers, github.New(os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"), fmt.Sprintf("%s/api/v1/auth/github/callback", os.Getenv("HOST")), "user:email"))
+	}
+	if os.Getenv("DISCORD_CLIENT_ID") != "" {
+		providers = append(providers, discord.New(os.Getenv("DISCORD_CLIENT_ID"), os.Getenv("DISCORD_CLIENT_SECRET"), fmt.Sprintf("%s/api/v1/auth/discord/callback", os.Getenv("HOST")), discord.ScopeIdentify, discord.ScopeEmail))
+	}
+	goth.UseProviders(providers...)
 
 	store := sessions.NewCookieStore([]byte(os.Getenv("SESSION_SECRET")))
 	store.MaxAge(86400)
@@ -130,7 +156,77 @@ func main() {
 		os.Exit(1)
 	}
 
+	_, err = ch.QueueDeclare(queueAccountDeleted, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	_, err = ch.QueueDeclare(queueDataExport, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	_, err = ch.QueueDeclare(queueChapterModeration, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	_, err = ch.QueueDeclare(queueChapterPublished, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	_, err = ch.QueueDeclare(queueChapterFingerprint, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	if err := ch.ExchangeDeclare(exchangeEvents, "fanout", true, false, false, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error declaring exchange, %v", err))
+		os.Exit(1)
+	}
+
+	// every replica gets its own queue which goes away with it
+	eventsQueue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	if err := ch.QueueBind(eventsQueue.Name, "", exchangeEvents, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error binding queue, %v", err))
+		os.Exit(1)
+	}
+
+	// the prefetch limit applies to every consumer started on the channel from
+	// here on, events and published chapters
+	if err := ch.Qos(eventsPrefetch, 0, false); err != nil {
+		logger.Error(fmt.Sprintf("error setting prefetch, %v", err))
+		os.Exit(1)
+	}
+
+	events, err := ch.ConsumeWithContext(context.Background(), eventsQueue.Name, "", false, true, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming events, %v", err))
+		os.Exit(1)
+	}
+
+	// unlike events, every published chapter is handled by one replica only
+	published, err := ch.ConsumeWithContext(context.Background(), queueChapterPublished, "", false, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming published chapters, %v", err))
+		os.Exit(1)
+	}
+
 	svr := newServer(logger, db, objectStore, ch)
+	go svr.consumeEvents(events)
+	go svr.consumePublishedChapters(published)
+	go svr.pruneRateLimits(time.Minute)
 	port := *flag.String("a", ":3000", "server address")
 	flag.Parse()
 	httpSvr := &http.Server{
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_books_author_id;
+
+ALTER TABLE users DROP COLUMN IF EXISTS reading_stats_public;
+ALTER TABLE users DROP COLUMN IF EXISTS reading_history_public;
+ALTER TABLE users DROP COLUMN IF EXISTS library_public;
\ No newline at end of file
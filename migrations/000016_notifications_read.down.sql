@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_user_id;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS read_at;
\ No newline at end of file
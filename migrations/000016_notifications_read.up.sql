@@ -0,0 +1,3 @@
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
\ No newline at end of file
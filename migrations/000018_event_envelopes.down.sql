@@ -0,0 +1,10 @@
+UPDATE user_events SET type = CASE type
+    WHEN 'book.created' THEN 'NEW_BOOK'
+    WHEN 'chapter.uploaded' THEN 'CHAPTER_UPLOADED'
+    WHEN 'user.followed' THEN 'NEW_FOLLOWER'
+    ELSE type
+END;
+
+ALTER TABLE user_events DROP COLUMN IF EXISTS occurred_at;
+ALTER TABLE user_events DROP COLUMN IF EXISTS version;
+ALTER TABLE user_events DROP COLUMN IF EXISTS event_id;
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE user_events ADD COLUMN IF NOT EXISTS event_id TEXT;
+ALTER TABLE user_events ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE user_events ADD COLUMN IF NOT EXISTS occurred_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE user_events SET
+    event_id = COALESCE(event_id, REPLACE(uuid_generate_v4()::text, '-', '')),
+    occurred_at = COALESCE(occurred_at, created_at),
+    type = CASE type
+        WHEN 'NEW_BOOK' THEN 'book.created'
+        WHEN 'CHAPTER_UPLOADED' THEN 'chapter.uploaded'
+        WHEN 'NEW_FOLLOWER' THEN 'user.followed'
+        ELSE type
+    END;
+
+ALTER TABLE user_events ALTER COLUMN event_id SET NOT NULL;
+ALTER TABLE user_events ALTER COLUMN occurred_at SET NOT NULL;
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_moderation_actions_book_id;
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP INDEX IF EXISTS idx_books_pending;
+
+ALTER TABLE books DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE books DROP COLUMN IF EXISTS claimed_by;
+ALTER TABLE books DROP COLUMN IF EXISTS moderation_status;
\ No newline at end of file
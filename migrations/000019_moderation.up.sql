@@ -0,0 +1,19 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS moderation_status TEXT NOT NULL DEFAULT 'pending' CHECK (moderation_status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE books ADD COLUMN IF NOT EXISTS claimed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP WITH TIME ZONE;
+
+UPDATE books SET moderation_status = 'approved' WHERE approved = true;
+
+CREATE INDEX IF NOT EXISTS idx_books_pending ON books(created_at) WHERE moderation_status = 'pending';
+
+CREATE TABLE IF NOT EXISTS moderation_actions(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('claim', 'release', 'approve', 'reject')),
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_book_id ON moderation_actions(book_id);
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_reports_open_target;
+DROP INDEX IF EXISTS idx_reports_open_reporter;
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users DROP COLUMN IF EXISTS hidden;
+ALTER TABLE chapters DROP COLUMN IF EXISTS hidden;
+ALTER TABLE books DROP COLUMN IF EXISTS hidden;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE moderation_actions DROP COLUMN IF EXISTS chapter_id;
+
+DROP TABLE IF EXISTS chapter_minhash_bands;
+DROP TABLE IF EXISTS chapter_minhashes;
+DROP INDEX IF EXISTS idx_chapter_verdicts_chapter_id;
+DROP TABLE IF EXISTS chapter_verdicts;
+DROP TABLE IF EXISTS banned_words;
+
+DROP INDEX IF EXISTS idx_chapters_flagged;
+ALTER TABLE chapters DROP COLUMN IF EXISTS moderation_status;
\ No newline at end of file
@@ -0,0 +1,35 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS moderation_status TEXT NOT NULL DEFAULT 'approved' CHECK (moderation_status IN ('pending', 'approved', 'flagged', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS idx_chapters_flagged ON chapters(created_at) WHERE moderation_status = 'flagged';
+
+CREATE TABLE IF NOT EXISTS banned_words(
+    language language_type NOT NULL,
+    word TEXT NOT NULL,
+    PRIMARY KEY(language, word)
+);
+
+CREATE TABLE IF NOT EXISTS chapter_verdicts(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    chapter_id UUID NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    check_name TEXT NOT NULL,
+    flagged BOOLEAN NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    details TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_verdicts_chapter_id ON chapter_verdicts(chapter_id);
+
+CREATE TABLE IF NOT EXISTS chapter_minhashes(
+    chapter_id UUID PRIMARY KEY REFERENCES chapters(id) ON DELETE CASCADE,
+    signature BIGINT[] NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chapter_minhash_bands(
+    band SMALLINT NOT NULL,
+    bucket BIGINT NOT NULL,
+    chapter_id UUID NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    PRIMARY KEY(band, bucket, chapter_id)
+);
+
+ALTER TABLE moderation_actions ADD COLUMN IF NOT EXISTS chapter_id UUID REFERENCES chapters(id) ON DELETE CASCADE;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS plagiarism_matches;
+DROP INDEX IF EXISTS idx_chapter_fingerprints_hash;
+DROP TABLE IF EXISTS chapter_fingerprints;
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS chapter_fingerprints(
+    chapter_id UUID NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    hash BIGINT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY(chapter_id, hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chapter_fingerprints_hash ON chapter_fingerprints(hash);
+
+CREATE TABLE IF NOT EXISTS plagiarism_matches(
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    source_book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    shared INT NOT NULL,
+    total INT NOT NULL,
+    overlap DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(book_id, source_book_id)
+);
\ No newline at end of file
@@ -0,0 +1,8 @@
+UPDATE users SET roles = array_remove(array_remove(roles, 'MODERATOR'), 'AUTHOR');
+
+CREATE TYPE role_type_old AS ENUM ('REGULAR', 'ADMIN');
+ALTER TABLE users ALTER COLUMN roles DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN roles TYPE role_type_old[] USING roles::text[]::role_type_old[];
+DROP TYPE role_type;
+ALTER TYPE role_type_old RENAME TO role_type;
+ALTER TABLE users ALTER COLUMN roles SET DEFAULT ARRAY['REGULAR']::role_type[];
\ No newline at end of file
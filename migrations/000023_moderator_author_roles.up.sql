@@ -0,0 +1,2 @@
+ALTER TYPE role_type ADD VALUE IF NOT EXISTS 'MODERATOR';
+ALTER TYPE role_type ADD VALUE IF NOT EXISTS 'AUTHOR';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_role_changes_user_id;
+DROP TABLE IF EXISTS role_changes;
\ No newline at end of file
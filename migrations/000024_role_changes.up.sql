@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS role_changes(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role role_type NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('grant', 'revoke')),
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_changes_user_id ON role_changes(user_id, created_at);
+
+UPDATE users SET roles = array_append(roles, 'AUTHOR')
+WHERE NOT 'AUTHOR' = ANY(roles) AND EXISTS (SELECT 1 FROM books WHERE books.author_id = users.id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_book_collaborators_user_id;
+DROP TABLE IF EXISTS book_collaborators;
\ No newline at end of file
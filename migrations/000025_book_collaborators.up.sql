@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS book_collaborators(
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('co_author', 'editor', 'translator')),
+    revenue_share SMALLINT NOT NULL DEFAULT 0 CHECK (revenue_share BETWEEN 0 AND 100),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'declined')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY(book_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_collaborators_user_id ON book_collaborators(user_id, status);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_chapters_source_chapter_id;
+ALTER TABLE chapters DROP COLUMN IF EXISTS source_chapter_id;
+
+DROP INDEX IF EXISTS idx_books_source_book_id;
+ALTER TABLE books DROP COLUMN IF EXISTS source_book_id;
\ No newline at end of file
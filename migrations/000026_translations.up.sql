@@ -0,0 +1,7 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS source_book_id UUID REFERENCES books(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_books_source_book_id ON books(source_book_id) WHERE source_book_id IS NOT NULL;
+
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS source_chapter_id UUID REFERENCES chapters(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chapters_source_chapter_id ON chapters(book_id, source_chapter_id) WHERE source_chapter_id IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS book_list_items;
+DROP INDEX IF EXISTS idx_book_lists_owner_id;
+DROP TABLE IF EXISTS book_lists;
+DROP TABLE IF EXISTS series_volumes;
+DROP TABLE IF EXISTS series;
\ No newline at end of file
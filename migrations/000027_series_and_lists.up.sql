@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS series(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(author_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS series_volumes(
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    book_id UUID NOT NULL UNIQUE REFERENCES books(id) ON DELETE CASCADE,
+    volume INT NOT NULL CHECK (volume > 0),
+    PRIMARY KEY(series_id, book_id),
+    UNIQUE(series_id, volume) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE TABLE IF NOT EXISTS book_lists(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('collection', 'reading_list')),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    public BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_lists_owner_id ON book_lists(owner_id);
+
+CREATE TABLE IF NOT EXISTS book_list_items(
+    list_id UUID NOT NULL REFERENCES book_lists(id) ON DELETE CASCADE,
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(list_id, book_id)
+);
\ No newline at end of file
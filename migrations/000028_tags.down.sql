@@ -0,0 +1,21 @@
+DROP TABLE IF EXISTS books_tags;
+DROP TABLE IF EXISTS tags;
+
+CREATE TYPE genre_type AS ENUM(
+  'Romance',
+  'Action',
+  'Mystery',
+  'Thriller',
+  'Science Fiction',
+  'Fantasy',
+  'Horror',
+  'Historical',
+  'Biography/Memoir',
+  'Children',
+  'Young Adult',
+  'Poetry'
+);
+
+DELETE FROM genres WHERE genre NOT IN (SELECT unnest(enum_range(NULL::genre_type))::text);
+ALTER TABLE genres DROP CONSTRAINT IF EXISTS genres_genre_key;
+ALTER TABLE genres ALTER COLUMN genre TYPE genre_type USING genre::genre_type;
\ No newline at end of file
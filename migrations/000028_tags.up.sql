@@ -0,0 +1,23 @@
+ALTER TABLE genres ALTER COLUMN genre TYPE TEXT USING genre::text;
+ALTER TABLE genres ADD CONSTRAINT genres_genre_key UNIQUE(genre);
+DROP TYPE IF EXISTS genre_type;
+
+CREATE TABLE IF NOT EXISTS tags(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL UNIQUE,
+    canonical BOOLEAN NOT NULL DEFAULT false,
+    canonical_id UUID REFERENCES tags(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (canonical_id IS NULL OR canonical_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tags_name_prefix ON tags(name text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_tags_canonical_id ON tags(canonical_id) WHERE canonical_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS books_tags(
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY(book_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_books_tags_tag_id ON books_tags(tag_id);
\ No newline at end of file
@@ -0,0 +1,51 @@
+CREATE TYPE language_type AS ENUM(
+  'Mandarin Chinese',
+  'Spanish',
+  'English',
+  'Hindi',
+  'Portugese',
+  'Vietnamese',
+  'Russian',
+  'Japanese',
+  'Korean',
+  'Indonesian'
+);
+
+DELETE FROM banned_words WHERE language NOT IN ('zh', 'es', 'en', 'hi', 'pt', 'vi', 'ru', 'ja', 'ko', 'id');
+ALTER TABLE banned_words DROP CONSTRAINT IF EXISTS banned_words_language_fkey;
+ALTER TABLE banned_words ALTER COLUMN language TYPE language_type USING (
+  CASE language
+    WHEN 'zh' THEN 'Mandarin Chinese'
+    WHEN 'es' THEN 'Spanish'
+    WHEN 'en' THEN 'English'
+    WHEN 'hi' THEN 'Hindi'
+    WHEN 'pt' THEN 'Portugese'
+    WHEN 'vi' THEN 'Vietnamese'
+    WHEN 'ru' THEN 'Russian'
+    WHEN 'ja' THEN 'Japanese'
+    WHEN 'ko' THEN 'Korean'
+    WHEN 'id' THEN 'Indonesian'
+  END
+)::language_type;
+
+DROP INDEX IF EXISTS idx_books_language;
+ALTER TABLE books DROP CONSTRAINT IF EXISTS books_language_fkey;
+ALTER TABLE books ALTER COLUMN language DROP DEFAULT;
+ALTER TABLE books ALTER COLUMN language TYPE language_type USING (
+  CASE language
+    WHEN 'zh' THEN 'Mandarin Chinese'
+    WHEN 'es' THEN 'Spanish'
+    WHEN 'en' THEN 'English'
+    WHEN 'hi' THEN 'Hindi'
+    WHEN 'pt' THEN 'Portugese'
+    WHEN 'vi' THEN 'Vietnamese'
+    WHEN 'ru' THEN 'Russian'
+    WHEN 'ja' THEN 'Japanese'
+    WHEN 'ko' THEN 'Korean'
+    WHEN 'id' THEN 'Indonesian'
+    ELSE 'English'
+  END
+)::language_type;
+ALTER TABLE books ALTER COLUMN language SET DEFAULT 'English'::language_type;
+
+DROP TABLE IF EXISTS languages;
\ No newline at end of file
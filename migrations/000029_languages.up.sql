@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS languages(
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  native_name TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO languages(code, name, native_name)
+VALUES
+  ('zh', 'Mandarin Chinese', '中文'),
+  ('es', 'Spanish', 'Español'),
+  ('en', 'English', 'English'),
+  ('hi', 'Hindi', 'हिन्दी'),
+  ('pt', 'Portuguese', 'Português'),
+  ('vi', 'Vietnamese', 'Tiếng Việt'),
+  ('ru', 'Russian', 'Русский'),
+  ('ja', 'Japanese', '日本語'),
+  ('ko', 'Korean', '한국어'),
+  ('id', 'Indonesian', 'Bahasa Indonesia')
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE books ALTER COLUMN language DROP DEFAULT;
+ALTER TABLE books ALTER COLUMN language TYPE TEXT USING (
+  CASE language::text
+    WHEN 'Mandarin Chinese' THEN 'zh'
+    WHEN 'Spanish' THEN 'es'
+    WHEN 'English' THEN 'en'
+    WHEN 'Hindi' THEN 'hi'
+    WHEN 'Portugese' THEN 'pt'
+    WHEN 'Vietnamese' THEN 'vi'
+    WHEN 'Russian' THEN 'ru'
+    WHEN 'Japanese' THEN 'ja'
+    WHEN 'Korean' THEN 'ko'
+    WHEN 'Indonesian' THEN 'id'
+  END
+);
+ALTER TABLE books ALTER COLUMN language SET DEFAULT 'en';
+ALTER TABLE books ADD CONSTRAINT books_language_fkey FOREIGN KEY (language) REFERENCES languages(code) ON UPDATE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_books_language ON books(language);
+
+ALTER TABLE banned_words ALTER COLUMN language TYPE TEXT USING (
+  CASE language::text
+    WHEN 'Mandarin Chinese' THEN 'zh'
+    WHEN 'Spanish' THEN 'es'
+    WHEN 'English' THEN 'en'
+    WHEN 'Hindi' THEN 'hi'
+    WHEN 'Portugese' THEN 'pt'
+    WHEN 'Vietnamese' THEN 'vi'
+    WHEN 'Russian' THEN 'ru'
+    WHEN 'Japanese' THEN 'ja'
+    WHEN 'Korean' THEN 'ko'
+    WHEN 'Indonesian' THEN 'id'
+  END
+);
+ALTER TABLE banned_words ADD CONSTRAINT banned_words_language_fkey FOREIGN KEY (language) REFERENCES languages(code) ON UPDATE CASCADE ON DELETE CASCADE;
+
+DROP TYPE IF EXISTS language_type;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE users DROP COLUMN IF EXISTS hidden_content_warnings;
+ALTER TABLE users DROP COLUMN IF EXISTS show_mature_content;
+ALTER TABLE users DROP COLUMN IF EXISTS birth_date;
+
+DROP INDEX IF EXISTS idx_books_content_rating;
+
+ALTER TABLE books DROP COLUMN IF EXISTS content_warnings;
+ALTER TABLE books DROP COLUMN IF EXISTS content_rating;
\ No newline at end of file
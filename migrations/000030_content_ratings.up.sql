@@ -0,0 +1,13 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS content_rating TEXT NOT NULL DEFAULT 'teen' CHECK (content_rating IN ('everyone', 'teen', 'mature'));
+ALTER TABLE books ADD COLUMN IF NOT EXISTS content_warnings TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE books SET content_rating = 'everyone'
+WHERE id IN (
+  SELECT bg.book_id FROM books_genres bg JOIN genres g ON (g.id = bg.genre_id) WHERE g.genre = 'Children'
+);
+
+CREATE INDEX IF NOT EXISTS idx_books_content_rating ON books(content_rating);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS birth_date DATE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS show_mature_content BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS hidden_content_warnings TEXT[] NOT NULL DEFAULT '{}';
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_data_exports_expires_at;
+
+UPDATE data_exports SET status = 'failed' WHERE status = 'expired';
+
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_status_check;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_status_check CHECK (status IN ('pending', 'ready', 'failed'));
+
+ALTER TABLE data_exports DROP COLUMN IF EXISTS object_key;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS object_key TEXT;
+
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_status_check;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_status_check CHECK (status IN ('pending', 'ready', 'failed', 'expired'));
+
+-- exports uploaded before were public, they expire straight away so the worker
+-- deletes them
+UPDATE data_exports SET expires_at = NOW() WHERE url IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at) WHERE object_key IS NOT NULL OR url IS NOT NULL;
\ No newline at end of file
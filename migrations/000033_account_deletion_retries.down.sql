@@ -0,0 +1,3 @@
+ALTER TABLE account_deletions DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE account_deletions DROP COLUMN IF EXISTS last_error;
+ALTER TABLE account_deletions DROP COLUMN IF EXISTS attempts;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE account_deletions ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE account_deletions ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE account_deletions ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP WITH TIME ZONE;
\ No newline at end of file
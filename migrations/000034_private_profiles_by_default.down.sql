@@ -0,0 +1,2 @@
+ALTER TABLE users ALTER COLUMN reading_history_public SET DEFAULT TRUE;
+ALTER TABLE users ALTER COLUMN library_public SET DEFAULT TRUE;
\ No newline at end of file
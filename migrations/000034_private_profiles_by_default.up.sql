@@ -0,0 +1,6 @@
+ALTER TABLE users ALTER COLUMN library_public SET DEFAULT FALSE;
+ALTER TABLE users ALTER COLUMN reading_history_public SET DEFAULT FALSE;
+
+-- the library and reading history were public without anyone choosing it,
+-- users opt back in from their privacy settings
+UPDATE users SET library_public = FALSE, reading_history_public = FALSE;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_rate_limits_expires_at;
+
+ALTER TABLE rate_limits DROP COLUMN IF EXISTS expires_at;
\ No newline at end of file
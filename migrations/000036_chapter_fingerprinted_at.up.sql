@@ -0,0 +1,5 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS fingerprinted_at TIMESTAMP WITH TIME ZONE;
+
+-- chapters with fingerprints were fingerprinted when they were written, the
+-- rest are left to the chapter moderation worker's backfill
+UPDATE chapters SET fingerprinted_at = NOW() WHERE EXISTS (SELECT 1 FROM chapter_fingerprints f WHERE f.chapter_id = chapters.id);
\ No newline at end of file
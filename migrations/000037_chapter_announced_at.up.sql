@@ -0,0 +1,4 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS announced_at TIMESTAMP WITH TIME ZONE;
+
+-- chapters approved before this were already announced to followers
+UPDATE chapters SET announced_at = NOW() WHERE moderation_status = 'approved';
\ No newline at end of file
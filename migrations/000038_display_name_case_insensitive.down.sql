@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_display_name_lower;
+
+ALTER TABLE users ADD CONSTRAINT users_display_name_key UNIQUE (display_name);
\ No newline at end of file
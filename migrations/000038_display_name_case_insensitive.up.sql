@@ -0,0 +1,13 @@
+-- display names are unique whatever their case. Accounts that clash with an
+-- older one get the start of their id added to their name.
+WITH clashes AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY LOWER(display_name) ORDER BY created_at, id) AS n
+    FROM users
+)
+UPDATE users SET display_name = LEFT(users.display_name, 21) || '_' || LEFT(users.id::TEXT, 8)
+FROM clashes
+WHERE clashes.id = users.id AND clashes.n > 1;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_display_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_display_name_lower ON users (LOWER(display_name));
\ No newline at end of file
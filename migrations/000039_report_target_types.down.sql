@@ -0,0 +1,4 @@
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_target_type_fkey;
+ALTER TABLE reports ADD CONSTRAINT reports_target_type_check CHECK (target_type IN ('book', 'chapter', 'user'));
+
+DROP TABLE IF EXISTS report_target_types;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- what can be reported lives in a table rather than a CHECK so new kinds of
+-- content, comments next, only need a row here and in reportTargets
+CREATE TABLE IF NOT EXISTS report_target_types(
+    name TEXT PRIMARY KEY
+);
+
+INSERT INTO report_target_types (name) VALUES ('book'), ('chapter'), ('user') ON CONFLICT DO NOTHING;
+
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_target_type_check;
+ALTER TABLE reports ADD CONSTRAINT reports_target_type_fkey FOREIGN KEY (target_type) REFERENCES report_target_types(name);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_chapters_pending;
+
+ALTER TABLE chapters DROP COLUMN IF EXISTS moderation_requested_at;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS moderation_requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_chapters_pending ON chapters(moderation_requested_at) WHERE moderation_status = 'pending';
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS notified_at TIMESTAMP WITH TIME ZONE;
+
+-- announced chapters were sent to the notification queue in the same step
+UPDATE chapters SET notified_at = announced_at WHERE announced_at IS NOT NULL;
\ No newline at end of file
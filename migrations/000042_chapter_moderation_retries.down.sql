@@ -0,0 +1,3 @@
+ALTER TABLE chapters DROP COLUMN IF EXISTS moderation_failed_at;
+ALTER TABLE chapters DROP COLUMN IF EXISTS moderation_error;
+ALTER TABLE chapters DROP COLUMN IF EXISTS moderation_attempts;
\ No newline at end of file
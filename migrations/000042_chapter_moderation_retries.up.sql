@@ -0,0 +1,3 @@
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS moderation_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS moderation_error TEXT;
+ALTER TABLE chapters ADD COLUMN IF NOT EXISTS moderation_failed_at TIMESTAMP WITH TIME ZONE;
\ No newline at end of file
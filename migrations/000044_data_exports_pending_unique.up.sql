@@ -0,0 +1,6 @@
+UPDATE data_exports d SET status = 'failed', completed_at = NOW()
+WHERE d.status = 'pending' AND EXISTS(
+    SELECT 1 FROM data_exports o WHERE o.user_id = d.user_id AND o.status = 'pending' AND (o.created_at, o.id) > (d.created_at, d.id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports(user_id) WHERE status = 'pending';
\ No newline at end of file
@@ -6,12 +6,45 @@ import (
 )
 
 type user struct {
+	id          string
 	displayName string
 	email       string
 	password    sql.NullString
 	about       sql.NullString
 	image       sql.NullString
 	roles       []string
+	totpEnabled bool
+	followers   int
+	following   int
+	privacy     privacySettings
+	createdAt   time.Time
+}
+
+type privacySettings struct {
+	libraryPublic        bool
+	readingHistoryPublic bool
+	readingStatsPublic   bool
+}
+
+// contentPreferences is what a reader told us about their age and the content
+// they want to see
+type contentPreferences struct {
+	birthDate      sql.NullTime
+	showMature     bool
+	hiddenWarnings []string
+}
+
+type readingStats struct {
+	booksRead    int
+	chaptersRead int
+	libraryBooks int
+}
+
+type identity struct {
+	provider       string
+	providerUserID string
+	email          string
+	createdAt      time.Time
 }
 
 type releaseSchedule struct {
@@ -26,12 +59,15 @@ type draftChapter struct {
 }
 
 type chapter struct {
-	id        string
-	title     string
-	chapterNo int
-	content   string
-	bookID    string
-	createdAt time.Time
+	id              string
+	title           string
+	chapterNo       int
+	content         string
+	bookID          string
+	sourceChapterID string
+	announced       bool
+	notified        bool
+	createdAt       time.Time
 }
 
 type book struct {
@@ -47,11 +83,17 @@ type book struct {
 	views           int
 	language        string
 	genres          []string
+	tags            []string
+	contentRating   string
+	contentWarnings []string
 	chapters        []chapter
 	draftChapter    draftChapter
 	rating          float32
 	completed       bool
 	approved        bool
+	sourceBookID    string
+	series          *seriesVolume
+	credits         []credit
 	createdAt       time.Time
 	updatedAt       time.Time
 }
@@ -62,3 +104,66 @@ type recentlyReadBook struct {
 	lastReadChapter int
 	updatedAt       time.Time
 }
+
+// credit is someone credited on a book, with their share of its revenue
+type credit struct {
+	userID       string
+	displayName  string
+	role         string
+	revenueShare int
+}
+
+type collaborator struct {
+	bookID       string
+	bookName     string
+	userID       string
+	displayName  string
+	role         string
+	revenueShare int
+	status       string
+	invitedBy    sql.NullString
+	createdAt    time.Time
+}
+
+// seriesVolume places a book in its series
+type seriesVolume struct {
+	seriesID   string
+	seriesName string
+	volume     int
+	volumes    int
+}
+
+type series struct {
+	id          string
+	authorID    string
+	authorName  string
+	name        string
+	description sql.NullString
+	books       []book
+	createdAt   time.Time
+}
+
+// bookList is a collection of an author's own books or a reading list anyone
+// can put together
+type bookList struct {
+	id          string
+	ownerID     string
+	ownerName   string
+	kind        string
+	name        string
+	description sql.NullString
+	public      bool
+	books       []book
+	createdAt   time.Time
+	updatedAt   time.Time
+}
+
+// tag is a free-form tag authors describe their books with. Synonyms point at
+// the canonical tag books actually get.
+type tag struct {
+	id        string
+	name      string
+	canonical bool
+	synonyms  []string
+	books     int
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
 type objectStore interface {
 	upload(ctx context.Context, key string, body io.Reader) (string, error)
+	// uploadPrivate stores a file that can only be reached through signedURL
+	uploadPrivate(ctx context.Context, key string, body io.Reader) error
+	signedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	delete(ctx context.Context, key string) error
 }
 
 type s3Object struct {
@@ -44,6 +50,37 @@ func (o *s3Object) upload(ctx context.Context, key string, body io.Reader) (stri
 	return "", nil
 }
 
+func (o *s3Object) uploadPrivate(ctx context.Context, key string, body io.Reader) error {
+	if _, err := o.upload(ctx, key, body); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *s3Object) signedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(o.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String("pagesy"),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+
+	if err != nil {
+		return "", fmt.Errorf("error signing s3 url, %v", err)
+	}
+	return req.URL, nil
+}
+
+func (o *s3Object) delete(ctx context.Context, key string) error {
+	_, err := o.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String("pagesy"),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return fmt.Errorf("error deleting object from s3, %v", err)
+	}
+	return nil
+}
+
 func (o *cloudinaryObject) upload(ctx context.Context, key string, body io.Reader) (string, error) {
 	resp, err := o.client.Upload.Upload(ctx, body, uploader.UploadParams{PublicID: key, Folder: "pagesy"})
 
@@ -53,3 +90,39 @@ func (o *cloudinaryObject) upload(ctx context.Context, key string, body io.Reade
 
 	return resp.SecureURL, nil
 }
+
+// private files are raw authenticated assets, cloudinary only serves them
+// through urls signed with the api secret
+func (o *cloudinaryObject) uploadPrivate(ctx context.Context, key string, body io.Reader) error {
+	_, err := o.client.Upload.Upload(ctx, body, uploader.UploadParams{PublicID: "pagesy/" + key, ResourceType: api.File, Type: api.Authenticated})
+
+	if err != nil {
+		return fmt.Errorf("error uploading file, %+v", err)
+	}
+
+	return nil
+}
+
+func (o *cloudinaryObject) signedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	expiresAt := time.Now().Add(ttl)
+
+	url, err := o.client.Upload.PrivateDownloadURL(uploader.PrivateDownloadURLParams{PublicID: "pagesy/" + key, DeliveryType: string(api.Authenticated), ResourceType: api.File, ExpiresAt: &expiresAt})
+	if err != nil {
+		return "", fmt.Errorf("error signing url, %v", err)
+	}
+
+	return url, nil
+}
+
+func (o *cloudinaryObject) delete(ctx context.Context, key string) error {
+	resp, err := o.client.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: "pagesy/" + key, Type: string(api.Authenticated), ResourceType: api.File})
+	if err != nil {
+		return fmt.Errorf("error deleting file, %v", err)
+	}
+
+	if resp.Error.Message != "" {
+		return fmt.Errorf("error deleting file, %v", resp.Error.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+)
+
+type permission string
+
+const (
+	permUploadBook      permission = "upload_book"
+	permReportContent   permission = "report_content"
+	permViewBookStats   permission = "view_book_stats"
+	permApproveBook     permission = "approve_book"
+	permApproveChapter  permission = "approve_chapter"
+	permResolveReport   permission = "resolve_report"
+	permDeleteComment   permission = "delete_comment"
+	permBanUser         permission = "ban_user"
+	permManageRoles     permission = "manage_roles"
+	permViewStats       permission = "view_stats"
+	permManageTags      permission = "manage_tags"
+	permManageGenres    permission = "manage_genres"
+	permManageLanguages permission = "manage_languages"
+)
+
+// rolePermissions is what each role is allowed to do. A user can do anything
+// one of their roles allows.
+var rolePermissions = map[string][]permission{
+	"REGULAR": {permUploadBook, permReportContent},
+	// AUTHOR is given to users when they upload their first book
+	"AUTHOR":    {permViewBookStats},
+	"MODERATOR": {permApproveBook, permApproveChapter, permResolveReport, permDeleteComment},
+	"ADMIN": {
+		permUploadBook, permReportContent, permViewBookStats,
+		permApproveBook, permApproveChapter, permResolveReport, permDeleteComment,
+		permBanUser, permManageRoles, permViewStats,
+		permManageTags, permManageGenres, permManageLanguages,
+	},
+}
+
+// staffRoles are the roles that see other users' content before it is public
+// and act on it
+var staffRoles = []string{"ADMIN", "MODERATOR"}
+
+func (u *user) can(p permission) bool {
+	for _, role := range u.roles {
+		if slices.Contains(rolePermissions[role], p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *user) isStaff() bool {
+	return slices.ContainsFunc(u.roles, func(role string) bool {
+		return slices.Contains(staffRoles, role)
+	})
+}
+
+// rolesWith returns the roles allowed to do p, for queries picking users by
+// what they can do
+func rolesWith(p permission) []string {
+	var roles []string
+	for role, permissions := range rolePermissions {
+		if slices.Contains(permissions, p) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// bookAction is something done to a book other users may be trusted with.
+// The author can do all of them.
+type bookAction string
+
+const (
+	bookEdit          bookAction = "edit_book"
+	bookUploadChapter bookAction = "upload_chapter"
+	bookEditChapter   bookAction = "edit_chapter"
+	bookDeleteChapter bookAction = "delete_chapter"
+)
+
+// collaboratorActions is what each collaborator role can do on a book once
+// they've accepted the invitation
+var collaboratorActions = map[string][]bookAction{
+	"co_author":  {bookEdit, bookUploadChapter, bookEditChapter, bookDeleteChapter},
+	"editor":     {bookEditChapter},
+	"translator": {bookUploadChapter, bookEditChapter},
+}
+
+// collaboratorRolesWith returns the collaborator roles allowed to do a
+func collaboratorRolesWith(a bookAction) []string {
+	var roles []string
+	for role, actions := range collaboratorActions {
+		if slices.Contains(actions, a) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
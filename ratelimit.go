@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type rateLimit struct {
+	requests int
+	per      time.Duration
+}
+
+// refillRate is the number of tokens added back to a bucket per second
+func (l rateLimit) refillRate() float64 {
+	return float64(l.requests) / l.per.Seconds()
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills the bucket for the time elapsed since it was last touched and
+// consumes a token if one is available. When no token is available it returns
+// how long the caller has to wait for the next one.
+func (b *bucket) take(now time.Time, limit rateLimit) (bool, time.Duration) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.requests), b.tokens+elapsed*limit.refillRate())
+	}
+	b.updatedAt = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / limit.refillRate()
+	return false, time.Duration(math.Ceil(wait)) * time.Second
+}
+
+// fullAt is when the bucket is refilled completely, from then on it behaves
+// like a new one and can be dropped
+func (b *bucket) fullAt(limit rateLimit) time.Time {
+	return b.updatedAt.Add(time.Duration((float64(limit.requests) - b.tokens) / limit.refillRate() * float64(time.Second)))
+}
+
+type limiterStore interface {
+	allow(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error)
+	// prune drops the buckets that are full again
+	prune(ctx context.Context) error
+}
+
+type memoryBucket struct {
+	bucket
+	expiresAt time.Time
+}
+
+type memoryLimiterStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+type postgresLimiterStore struct {
+	db *sql.DB
+}
+
+func newMemoryLimiterStore() *memoryLimiterStore {
+	return &memoryLimiterStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func newPostgresLimiterStore(db *sql.DB) *postgresLimiterStore {
+	return &postgresLimiterStore{db}
+}
+
+func (m *memoryLimiterStore) allow(_ context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.requests), updatedAt: now}}
+		m.buckets[key] = b
+	}
+
+	allowed, retryAfter := b.take(now, limit)
+	b.expiresAt = b.fullAt(limit)
+	return allowed, retryAfter, nil
+}
+
+func (m *memoryLimiterStore) prune(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, b := range m.buckets {
+		if !b.expiresAt.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+
+	return nil
+}
+
+func (p *postgresLimiterStore) allow(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var b bucket
+	var now time.Time
+
+	// the no-op update locks the bucket, so it can't be pruned while it is used
+	query :=
+		`
+			INSERT INTO rate_limits (key, tokens) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+			RETURNING tokens, updated_at, NOW();
+		`
+
+	if err := tx.QueryRowContext(ctx, query, key, limit.requests).Scan(&b.tokens, &b.updatedAt, &now); err != nil {
+		return false, 0, fmt.Errorf("error getting rate limit bucket, %v", err)
+	}
+
+	allowed, retryAfter := b.take(now, limit)
+
+	query =
+		`
+			UPDATE rate_limits SET tokens = $1, updated_at = $2, expires_at = $3 WHERE key = $4;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, b.tokens, b.updatedAt, b.fullAt(limit), key); err != nil {
+		return false, 0, fmt.Errorf("error updating rate limit bucket, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return allowed, retryAfter, nil
+}
+
+func (p *postgresLimiterStore) prune(ctx context.Context) error {
+	query :=
+		`
+			DELETE FROM rate_limits WHERE key IN (
+				SELECT key FROM rate_limits WHERE expires_at <= NOW() FOR UPDATE SKIP LOCKED
+			);
+		`
+
+	if _, err := p.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error pruning rate limit buckets, %v", err)
+	}
+
+	return nil
+}
+
+// pruneRateLimits drops the buckets that are full again every interval
+func (s *server) pruneRateLimits(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := s.limiter.prune(ctx); err != nil {
+			s.logger.Error(err.Error())
+		}
+		cancel()
+	}
+}
+
+// parseRateLimit reads limits written as "<requests>/<duration>", e.g. "10/1m"
+func parseRateLimit(value string) (rateLimit, error) {
+	requests, per, ok := strings.Cut(value, "/")
+	if !ok {
+		return rateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<duration>", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return rateLimit{}, fmt.Errorf("invalid number of requests in rate limit %q", value)
+	}
+
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid duration in rate limit %q", value)
+	}
+
+	return rateLimit{requests: n, per: d}, nil
+}
+
+// rateLimitFromEnv lets every limited route be tuned per deployment, falling
+// back to the default when the variable is unset or malformed
+func rateLimitFromEnv(name string, fallback rateLimit) rateLimit {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	limit, err := parseRateLimit(value)
+	if err != nil {
+		return fallback
+	}
+
+	return limit
+}
+
+func loadRateLimits() map[string]rateLimit {
+	return map[string]rateLimit{
+		"login":         rateLimitFromEnv("RATE_LIMIT_LOGIN", rateLimit{requests: 10, per: time.Minute}),
+		"login_account": rateLimitFromEnv("RATE_LIMIT_LOGIN_ACCOUNT", rateLimit{requests: 5, per: time.Minute}),
+		"two_factor":    rateLimitFromEnv("RATE_LIMIT_TWO_FACTOR", rateLimit{requests: 5, per: time.Minute}),
+		// a challenge token lives for five minutes, so it gets about five tries
+		"two_factor_challenge": rateLimitFromEnv("RATE_LIMIT_TWO_FACTOR_CHALLENGE", rateLimit{requests: 5, per: 24 * time.Hour}),
+		"two_factor_account":   rateLimitFromEnv("RATE_LIMIT_TWO_FACTOR_ACCOUNT", rateLimit{requests: 10, per: 10 * time.Minute}),
+		"register":             rateLimitFromEnv("RATE_LIMIT_REGISTER", rateLimit{requests: 5, per: time.Minute}),
+		"upload_book":          rateLimitFromEnv("RATE_LIMIT_UPLOAD_BOOK", rateLimit{requests: 5, per: time.Hour}),
+		"upload_chapter":       rateLimitFromEnv("RATE_LIMIT_UPLOAD_CHAPTER", rateLimit{requests: 30, per: time.Hour}),
+		"edit_book":            rateLimitFromEnv("RATE_LIMIT_EDIT_BOOK", rateLimit{requests: 30, per: time.Hour}),
+		"data_export":          rateLimitFromEnv("RATE_LIMIT_DATA_EXPORT", rateLimit{requests: 2, per: 24 * time.Hour}),
+		"report":               rateLimitFromEnv("RATE_LIMIT_REPORT", rateLimit{requests: 20, per: time.Hour}),
+	}
+}
+
+func keyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func keyByUser(r *http.Request) string {
+	if id, ok := r.Context().Value("user").(string); ok {
+		return id
+	}
+	return keyByIP(r)
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	encode(w, http.StatusTooManyRequests, &errorResponse{Error: "too many requests, try again later"})
+}
+
+// rateLimited throttles a route using the limit registered under name in
+// s.limits, with a separate bucket for every key
+func (s *server) rateLimited(name string, key func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter, err := s.limiter.allow(r.Context(), fmt.Sprintf("%s:%s", name, key(r)), s.limits[name])
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if !allowed {
+			writeTooManyRequests(w, retryAfter)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	limit := rateLimit{requests: 2, per: 2 * time.Second}
+	now := time.Now()
+	b := &bucket{tokens: 2, updatedAt: now}
+
+	if ok, _ := b.take(now, limit); !ok {
+		t.Fatal("expected first request to be allowed")
+	}
+	if ok, _ := b.take(now, limit); !ok {
+		t.Fatal("expected second request to be allowed")
+	}
+
+	ok, retryAfter := b.take(now, limit)
+	if ok {
+		t.Fatal("expected third request to be limited")
+	}
+	if retryAfter != time.Second {
+		t.Fatalf("expected retry after 1s, got %v", retryAfter)
+	}
+
+	if ok, _ := b.take(now.Add(time.Second), limit); !ok {
+		t.Fatal("expected request to be allowed after refill")
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expected  rateLimit
+		expectErr bool
+	}{
+		{
+			name:     "valid limit",
+			value:    "10/1m",
+			expected: rateLimit{requests: 10, per: time.Minute},
+		},
+		{
+			name:      "missing duration",
+			value:     "10",
+			expectErr: true,
+		},
+		{
+			name:      "invalid requests",
+			value:     "ten/1m",
+			expectErr: true,
+		},
+		{
+			name:      "invalid duration",
+			value:     "10/forever",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, err := parseRateLimit(tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if limit != tc.expected {
+				t.Fatalf("expected %+v, got %+v", tc.expected, limit)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterStoreKeys(t *testing.T) {
+	store := newMemoryLimiterStore()
+	limit := rateLimit{requests: 1, per: time.Minute}
+
+	if ok, _, _ := store.allow(context.Background(), "a", limit); !ok {
+		t.Fatal("expected key a to be allowed")
+	}
+	if ok, _, _ := store.allow(context.Background(), "a", limit); ok {
+		t.Fatal("expected key a to be limited")
+	}
+	if ok, _, _ := store.allow(context.Background(), "b", limit); !ok {
+		t.Fatal("expected key b to have its own bucket")
+	}
+}
+
+func TestMemoryLimiterStorePrune(t *testing.T) {
+	store := newMemoryLimiterStore()
+
+	store.allow(context.Background(), "refilled", rateLimit{requests: 1, per: 10 * time.Millisecond})
+	store.allow(context.Background(), "limited", rateLimit{requests: 1, per: time.Minute})
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := store.prune(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, ok := store.buckets["refilled"]; ok {
+		t.Fatal("expected refilled bucket to be pruned")
+	}
+	if _, ok := store.buckets["limited"]; !ok {
+		t.Fatal("expected limited bucket to be kept")
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	svr := &server{limiter: newMemoryLimiterStore(), limits: map[string]rateLimit{"test": {requests: 1, per: time.Minute}}}
+	handler := svr.rateLimited("test", keyByIP, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	expectedCodes := []int{http.StatusNoContent, http.StatusTooManyRequests}
+
+	for i, expectedCode := range expectedCodes {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		rr := httptest.NewRecorder()
+
+		handler(rr, r)
+
+		if rr.Code != expectedCode {
+			t.Fatalf("request %d: expected %d, got %d", i+1, expectedCode, rr.Code)
+		}
+		if expectedCode == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+			t.Fatal("expected Retry-After header")
+		}
+	}
+}
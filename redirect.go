@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// frontendURL is where users are sent after signing in when no valid
+// returnTo was given
+func frontendURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return "/"
+}
+
+func allowedRedirectOrigins() []string {
+	origins := []string{}
+	if u, err := url.Parse(frontendURL()); err == nil && u.Host != "" {
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+
+	for _, origin := range strings.Split(os.Getenv("ALLOWED_REDIRECT_ORIGINS"), ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return origins
+}
+
+// validateReturnTo only accepts absolute urls on an allowlisted origin, or
+// paths which are resolved against the frontend url. Anything else would let
+// the sign in flow be used as an open redirect.
+func validateReturnTo(raw string) (string, bool) {
+	if raw == "" {
+		return "", false
+	}
+
+	if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") && !strings.HasPrefix(raw, "/\\") {
+		if frontendURL() == "/" {
+			return raw, true
+		}
+		return frontendURL() + raw, true
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return "", false
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	for _, allowed := range allowedRedirectOrigins() {
+		if strings.EqualFold(origin, allowed) {
+			return u.String(), true
+		}
+	}
+
+	return "", false
+}
+
+// frontendRedirect sends the user to path on the frontend, e.g. the
+// onboarding page
+func frontendRedirect(w http.ResponseWriter, r *http.Request, path string) {
+	target := frontendURL()
+	if target == "/" {
+		target = ""
+	}
+	target += path
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
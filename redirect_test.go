@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestValidateReturnTo(t *testing.T) {
+	t.Setenv("FRONTEND_URL", "https://pagesy.app/")
+	t.Setenv("ALLOWED_REDIRECT_ORIGINS", "https://admin.pagesy.app")
+
+	tests := []struct {
+		name     string
+		returnTo string
+		expected string
+		ok       bool
+	}{
+		{name: "empty", returnTo: "", ok: false},
+		{name: "relative path", returnTo: "/books/1", expected: "https://pagesy.app/books/1", ok: true},
+		{name: "frontend origin", returnTo: "https://pagesy.app/library", expected: "https://pagesy.app/library", ok: true},
+		{name: "allowlisted origin", returnTo: "https://admin.pagesy.app/queue", expected: "https://admin.pagesy.app/queue", ok: true},
+		{name: "protocol relative", returnTo: "//evil.com", ok: false},
+		{name: "backslash", returnTo: "/\\evil.com", ok: false},
+		{name: "other origin", returnTo: "https://evil.com/books", ok: false},
+		{name: "lookalike origin", returnTo: "https://pagesy.app.evil.com", ok: false},
+		{name: "userinfo", returnTo: "https://pagesy.app@evil.com", ok: false},
+		{name: "javascript scheme", returnTo: "javascript:alert(1)", ok: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := validateReturnTo(tc.returnTo)
+			if ok != tc.ok {
+				t.Fatalf("expected %v, got %v", tc.ok, ok)
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
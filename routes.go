@@ -17,31 +17,83 @@ func (s *server) routes() {
 
 	s.router.Get("/swagger/*", httpSwagger.WrapHandler)
 
-	s.router.Get("/api/v1/auth/google", s.handleAuthGoogle)
-	s.router.Get("/api/v1/auth/google/callback", s.handleAuthGoogleCallback)
+	s.router.Get("/api/v1/auth/{provider}", s.handleAuthProvider)
+	s.router.Get("/api/v1/auth/{provider}/callback", s.handleAuthProviderCallback)
+	s.router.Get("/api/v1/auth/{provider}/link", s.authenticatedUser(s.handleAuthLinkProvider))
+	s.router.Get("/api/v1/auth/{provider}/reauth", s.authenticatedUser(s.handleAuthReauthProvider))
 
 	s.router.Post("/api/v1/auth/onboarding", s.handleAuthOnboarding)
-	s.router.Post("/api/v1/auth/register", s.handleAuthRegister)
-	s.router.Post("/api/v1/auth/login", s.handleAuthLogin)
+	s.router.Get("/api/v1/auth/onboarding/status", s.handleAuthOnboardingStatus)
+	s.router.Get("/api/v1/auth/onboarding/display-name", s.handleAuthDisplayNameAvailable)
+	s.router.Post("/api/v1/auth/register", s.rateLimited("register", keyByIP, s.handleAuthRegister))
+	s.router.Post("/api/v1/auth/login", s.rateLimited("login", keyByIP, s.handleAuthLogin))
 	s.router.Post("/api/v1/auth/logout", s.handleAuthLogout)
 	s.router.Post("/api/v1/auth/refresh-token", s.handleAuthRefreshToken)
-
-	s.router.Post("/api/v1/books", authenticatedUser(s.handleUploadBook))
-	s.router.Get("/api/v1/books", s.handleGetBooks)
-	s.router.Get("/api/v1/books/stats", authenticatedUser(s.handleGetBooksStats))
-	s.router.Get("/api/v1/books/recently-read", authenticatedUser(s.handleGetRecentlyReadBooks))
-	s.router.Get("/api/v1/books/recently-uploaded", authenticatedUser(s.handleGetRecentlyUploadedBooks))
-
-	s.router.Get("/api/v1/books/{bookID}", s.handleGetBook)
-	s.router.Delete("/api/v1/books/{bookID}", authenticatedUser(s.handleDeleteBook))
-	s.router.Patch("/api/v1/books/{bookID}", authenticatedUser(s.handleEditBook))
-	s.router.Patch("/api/v1/books/{bookID}/approve", authenticatedUser(s.handleApproveBook))
-	s.router.Patch("/api/v1/books/{bookID}/complete", authenticatedUser(s.handleCompleteBook))
-
-	s.router.Post("/api/v1/books/{bookID}/chapters", authenticatedUser(s.handleUploadChapter))
-	s.router.Get("/api/v1/books/chapters/{chapterID}", authenticatedUser(s.handleGetChapter))
-	s.router.Delete("/api/v1/books/{bookID}/chapters/{chapterID}", authenticatedUser(s.handleDeleteChapter))
-	s.router.Patch("/api/v1/books/{bookID}/chapters/{chapterID}", authenticatedUser(s.handleEditChapter))
+	s.router.Post("/api/v1/auth/2fa/enroll", s.authenticatedUser(s.handleTwoFactorEnroll))
+	s.router.Post("/api/v1/auth/2fa/confirm", s.authenticatedUser(s.handleTwoFactorConfirm))
+	s.router.Post("/api/v1/auth/2fa/verify", s.rateLimited("two_factor", keyByIP, s.handleTwoFactorVerify))
+	s.router.Delete("/api/v1/auth/2fa", s.authenticatedUser(s.handleTwoFactorDisable))
+
+	s.router.Post("/api/v1/books", s.authenticatedUser(s.requirePermission(permUploadBook, s.rateLimited("upload_book", keyByUser, s.handleUploadBook))))
+	s.router.Get("/api/v1/books", s.identifiedUser(s.handleGetBooks))
+	s.router.Get("/api/v1/books/stats", s.authenticatedUser(s.requirePermission(permViewBookStats, s.handleGetBooksStats)))
+	s.router.Get("/api/v1/books/recently-read", s.authenticatedUser(s.handleGetRecentlyReadBooks))
+	s.router.Get("/api/v1/books/recently-uploaded", s.authenticatedUser(s.requirePermission(permViewStats, s.handleGetRecentlyUploadedBooks)))
+
+	s.router.Get("/api/v1/books/{bookID}", s.identifiedUser(s.handleGetBook))
+	s.router.Delete("/api/v1/books/{bookID}", s.authenticatedUser(s.handleDeleteBook))
+	s.router.Patch("/api/v1/books/{bookID}", s.authenticatedUser(s.rateLimited("edit_book", keyByUser, s.handleEditBook)))
+	s.router.Patch("/api/v1/books/{bookID}/complete", s.authenticatedUser(s.handleCompleteBook))
+	s.router.Get("/api/v1/books/{bookID}/translations", s.identifiedUser(s.handleGetTranslations))
+	s.router.Post("/api/v1/books/{bookID}/collaborators", s.authenticatedUser(s.handleInviteCollaborator))
+	s.router.Get("/api/v1/books/{bookID}/collaborators", s.authenticatedUser(s.handleGetCollaborators))
+	s.router.Delete("/api/v1/books/{bookID}/collaborators/{userID}", s.authenticatedUser(s.handleRemoveCollaborator))
+
+	s.router.Get("/api/v1/tags", s.handleSearchTags)
+	s.router.Post("/api/v1/admin/tags", s.authenticatedUser(s.requirePermission(permManageTags, s.handleCreateTag)))
+	s.router.Post("/api/v1/admin/tags/{tagID}/synonyms", s.authenticatedUser(s.requirePermission(permManageTags, s.handleAddTagSynonym)))
+	s.router.Delete("/api/v1/admin/tags/{tagID}/synonyms/{synonym}", s.authenticatedUser(s.requirePermission(permManageTags, s.handleRemoveTagSynonym)))
+	s.router.Get("/api/v1/genres", s.handleGetGenres)
+	s.router.Post("/api/v1/admin/genres", s.authenticatedUser(s.requirePermission(permManageGenres, s.handleCreateGenre)))
+	s.router.Get("/api/v1/languages", s.handleGetLanguages)
+	s.router.Post("/api/v1/admin/languages", s.authenticatedUser(s.requirePermission(permManageLanguages, s.handleCreateLanguage)))
+
+	s.router.Post("/api/v1/series", s.authenticatedUser(s.handleCreateSeries))
+	s.router.Get("/api/v1/series/{seriesID}", s.identifiedUser(s.handleGetSeries))
+	s.router.Patch("/api/v1/series/{seriesID}", s.authenticatedUser(s.handleUpdateSeries))
+	s.router.Delete("/api/v1/series/{seriesID}", s.authenticatedUser(s.handleDeleteSeries))
+
+	s.router.Post("/api/v1/lists", s.authenticatedUser(s.handleCreateList))
+	s.router.Get("/api/v1/lists/{listID}", s.identifiedUser(s.handleGetList))
+	s.router.Patch("/api/v1/lists/{listID}", s.authenticatedUser(s.handleUpdateList))
+	s.router.Delete("/api/v1/lists/{listID}", s.authenticatedUser(s.handleDeleteList))
+	s.router.Put("/api/v1/lists/{listID}/books/{bookID}", s.authenticatedUser(s.handleAddBookToList))
+	s.router.Delete("/api/v1/lists/{listID}/books/{bookID}", s.authenticatedUser(s.handleRemoveBookFromList))
+
+	s.router.Get("/api/v1/admin/books/pending", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleGetPendingBooks)))
+	s.router.Post("/api/v1/admin/books/{bookID}/claim", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleClaimBook)))
+	s.router.Delete("/api/v1/admin/books/{bookID}/claim", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleReleaseBook)))
+	s.router.Post("/api/v1/admin/books/{bookID}/approve", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleApproveBook)))
+	s.router.Post("/api/v1/admin/books/{bookID}/reject", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleRejectBook)))
+	s.router.Get("/api/v1/admin/books/{bookID}/moderation", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleGetModerationHistory)))
+	s.router.Get("/api/v1/admin/books/{bookID}/plagiarism", s.authenticatedUser(s.requirePermission(permApproveBook, s.handleGetPlagiarismReport)))
+
+	s.router.Get("/api/v1/admin/chapters/flagged", s.authenticatedUser(s.requirePermission(permApproveChapter, s.handleGetFlaggedChapters)))
+	s.router.Post("/api/v1/admin/chapters/{chapterID}/approve", s.authenticatedUser(s.requirePermission(permApproveChapter, s.handleApproveChapter)))
+	s.router.Post("/api/v1/admin/chapters/{chapterID}/reject", s.authenticatedUser(s.requirePermission(permApproveChapter, s.handleRejectChapter)))
+
+	s.router.Post("/api/v1/reports", s.authenticatedUser(s.requirePermission(permReportContent, s.rateLimited("report", keyByUser, s.handleCreateReport))))
+	s.router.Get("/api/v1/admin/users/{userID}/roles", s.authenticatedUser(s.requirePermission(permManageRoles, s.handleGetRoles)))
+	s.router.Post("/api/v1/admin/users/{userID}/roles", s.authenticatedUser(s.requirePermission(permManageRoles, s.handleGrantRole)))
+	s.router.Delete("/api/v1/admin/users/{userID}/roles/{role}", s.authenticatedUser(s.requirePermission(permManageRoles, s.handleRevokeRole)))
+
+	s.router.Get("/api/v1/admin/reports", s.authenticatedUser(s.requirePermission(permResolveReport, s.handleGetReports)))
+	s.router.Patch("/api/v1/admin/reports/{reportID}", s.authenticatedUser(s.requirePermission(permResolveReport, s.handleResolveReport)))
+
+	s.router.Post("/api/v1/books/{bookID}/chapters", s.authenticatedUser(s.rateLimited("upload_chapter", keyByUser, s.handleUploadChapter)))
+	s.router.Get("/api/v1/books/chapters/{chapterID}", s.authenticatedUser(s.handleGetChapter))
+	s.router.Delete("/api/v1/books/{bookID}/chapters/{chapterID}", s.authenticatedUser(s.handleDeleteChapter))
+	s.router.Patch("/api/v1/books/{bookID}/chapters/{chapterID}", s.authenticatedUser(s.handleEditChapter))
 
 	s.router.Post("/api/v1/books/{bookID}/comments", nil)
 	s.router.Get("/api/v1/books/{bookID}/comments", nil)
@@ -49,11 +101,28 @@ func (s *server) routes() {
 	s.router.Delete("/api/v1/books/{bookID}/comments/{commentID}", nil)
 	s.router.Patch("/api/v1/books/{bookID}/comments/{commentID}", nil)
 
-	s.router.Post("/api/v1/users/{userID}/follow", authenticatedUser(s.handleFollowUser))
-	s.router.Delete("/api/v1/users/{userID}/unfollow", authenticatedUser(s.handleUnfollowUser))
-	s.router.Get("/api/v1/users/{userID}/followers", authenticatedUser(s.handleGetUserFollowers))
-	s.router.Get("/api/v1/users/{userID}/following", authenticatedUser(s.handleGetUserFollowing))
-	s.router.Get("/api/v1/users/me", authenticatedUser(s.handleGetProfile))
+	s.router.Post("/api/v1/users/{userID}/follow", s.authenticatedUser(s.handleFollowUser))
+	s.router.Delete("/api/v1/users/{userID}/unfollow", s.authenticatedUser(s.handleUnfollowUser))
+	s.router.Get("/api/v1/users/{userID}/followers", s.authenticatedUser(s.handleGetUserFollowers))
+	s.router.Get("/api/v1/users/{userID}/following", s.authenticatedUser(s.handleGetUserFollowing))
+	s.router.Get("/api/v1/users/{userID}", s.handleGetUserProfile)
+	s.router.Get("/api/v1/users/{userID}/lists", s.identifiedUser(s.handleGetUserLists))
+	s.router.Get("/api/v1/users/me", s.authenticatedUser(s.handleGetProfile))
+	s.router.Patch("/api/v1/users/me", s.authenticatedUser(s.handleEditProfile))
+	s.router.Delete("/api/v1/users/me", s.authenticatedUser(s.handleDeleteAccount))
+	s.router.Put("/api/v1/users/me/password", s.authenticatedUser(s.handleChangePassword))
+	s.router.Get("/api/v1/users/me/privacy", s.authenticatedUser(s.handleGetPrivacySettings))
+	s.router.Patch("/api/v1/users/me/privacy", s.authenticatedUser(s.handleUpdatePrivacySettings))
+	s.router.Get("/api/v1/users/me/content-preferences", s.authenticatedUser(s.handleGetContentPreferences))
+	s.router.Patch("/api/v1/users/me/content-preferences", s.authenticatedUser(s.handleUpdateContentPreferences))
+	s.router.Post("/api/v1/users/me/exports", s.authenticatedUser(s.rateLimited("data_export", keyByUser, s.handleRequestDataExport)))
+	s.router.Get("/api/v1/users/me/exports", s.authenticatedUser(s.handleGetDataExports))
+	s.router.Get("/api/v1/users/me/exports/{exportID}/download", s.authenticatedUser(s.handleDownloadDataExport))
+	s.router.Get("/api/v1/users/me/lists", s.authenticatedUser(s.handleGetMyLists))
+	s.router.Get("/api/v1/users/me/invitations", s.authenticatedUser(s.handleGetInvitations))
+	s.router.Patch("/api/v1/users/me/invitations/{bookID}", s.authenticatedUser(s.handleRespondToInvitation))
+	s.router.Get("/api/v1/users/me/identities", s.authenticatedUser(s.handleGetIdentities))
+	s.router.Delete("/api/v1/users/me/identities/{provider}", s.authenticatedUser(s.handleUnlinkIdentity))
 	s.router.Get("/api/v1/users/me/following", nil)
 	s.router.Get("/api/v1/users/me/followers", nil)
 
@@ -66,13 +135,19 @@ func (s *server) routes() {
 
 	s.router.Post("/api/v1/coins", nil)
 
-	s.router.HandleFunc("/api/v1/ws", authenticatedUser(s.handleWS))
+	s.router.HandleFunc("/api/v1/ws", s.authenticatedUser(s.handleWS))
+	s.router.Get("/api/v1/ws/stats", s.authenticatedUser(s.requirePermission(permViewStats, s.handleGetWSStats)))
+	s.router.Get("/api/v1/events", s.authenticatedUser(s.handleEvents))
+	s.router.Get("/api/v1/events/schema", s.handleGetEventSchema)
 	s.router.Post("/webhook", nil)
 	s.router.Patch("/users/{userID}/ban", nil)
 	s.router.Get("/users/{userID}/notifications", nil)
 }
 
-func authenticatedUser(next http.HandlerFunc) http.HandlerFunc {
+// authenticatedUser lets through requests with a valid access token. Tokens
+// of deleted accounts or issued before the user's sessions were revoked are
+// refused, not only when they are refreshed.
+func (s *server) authenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("access_token")
 		if err != nil {
@@ -80,12 +155,80 @@ func authenticatedUser(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		id, err := decodeJWTToken(cookie.Value)
+		id, issuedAt, err := decodeSessionToken(cookie.Value)
 		if err != nil {
 			encode(w, http.StatusBadRequest, &errorResponse{Error: err.Error()})
 			return
 		}
 
+		revoked, err := s.sessionRevoked(r.Context(), id, issuedAt)
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if revoked {
+			clearAuthCookies(w)
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: "session revoked, sign in again"})
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), "user", id)))
+	}
+}
+
+// identifiedUser is authenticatedUser for routes anyone can call, the user is
+// only set when a valid access token of a live session was sent
+func (s *server) identifiedUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("access_token")
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		id, issuedAt, err := decodeSessionToken(cookie.Value)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		revoked, err := s.sessionRevoked(r.Context(), id, issuedAt)
+		if err != nil {
+			s.logger.Error(err.Error())
+		}
+
+		if err != nil || revoked {
+			next(w, r)
+			return
+		}
+
 		next(w, r.WithContext(context.WithValue(r.Context(), "user", id)))
 	}
 }
+
+// requirePermission lets through users with a role allowing p. Staff have to
+// set up two factor authentication first when it is required.
+func (s *server) requirePermission(p permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.getUser(r.Context(), r.Context().Value("user").(string))
+		if err != nil {
+			s.logger.Error(err.Error())
+			encode(w, http.StatusInternalServerError, &errorResponse{Error: "internal server error"})
+			return
+		}
+
+		if !user.can(p) {
+			encode(w, http.StatusUnauthorized, &errorResponse{Error: fmt.Sprintf("missing %s permission", p)})
+			return
+		}
+
+		if staffTwoFactorRequired(user) {
+			encode(w, http.StatusForbidden, &errorResponse{Error: "two factor authentication is required for staff"})
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -5,12 +5,23 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
-	errUserExists = errors.New("user exists")
+	errUserExists               = errors.New("user exists")
+	errDisplayNameTaken         = errors.New("display name already taken")
+	errInvalidPassword          = errors.New("invalid password")
+	errReauthenticationRequired = errors.New("sign in with your provider again or enter your two factor code")
 )
 
+// reauthenticationWindow is how long signing in with a provider again counts
+// as confirming a sensitive change for users without a password
+const reauthenticationWindow = 5 * time.Minute
+
 func (s *server) checkIfUserExists(ctx context.Context, email string) (string, error) {
 	var id string
 	query :=
@@ -39,13 +50,31 @@ func (s *server) createUser(ctx context.Context, user *user) (string, error) {
 			INSERT INTO users (display_name, email, password, about, image) VALUES ($1, $2, $3, $4, $5) RETURNING id;
 		`
 	if err := s.store.QueryRowContext(ctx, query, user.displayName, user.email, user.password, user.about, user.image).Scan(&id); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "idx_users_display_name_lower" {
+			return "", errDisplayNameTaken
+		}
 		return "", fmt.Errorf("error inserting into users table, %w", err)
 	}
 	return id, nil
 }
 
+// checkIfDisplayNameTaken ignores case like the unique index on display names
+func (s *server) checkIfDisplayNameTaken(ctx context.Context, displayName string) (bool, error) {
+	var taken bool
+	query :=
+		`
+			SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(display_name) = LOWER($1));
+		`
+	if err := s.store.QueryRowContext(ctx, query, displayName).Scan(&taken); err != nil {
+		return false, fmt.Errorf("error checking display name, %v", err)
+	}
+	return taken, nil
+}
+
+// getUserPassword returns an empty hash for users who signed up through an
+// oauth provider and never set a password
 func (s *server) getUserPassword(ctx context.Context, id string) (string, error) {
-	var password string
+	var password sql.NullString
 	query :=
 		`
 			SELECT password FROM users WHERE id = $1;
@@ -54,5 +83,154 @@ func (s *server) getUserPassword(ctx context.Context, id string) (string, error)
 		return "", fmt.Errorf("error retrieving password, %v", err)
 	}
 
-	return password, nil
+	return password.String, nil
+}
+
+const (
+	maxLoginFailures = 5
+	// loginFailureWindow is how long failures are remembered after the last
+	// one, the count starts over when the account isn't locked anymore
+	loginFailureWindow = 24 * time.Hour
+)
+
+// loginLockoutDuration doubles the lockout for every failure past the limit,
+// capped at a day
+func loginLockoutDuration(failures int) time.Duration {
+	if failures < maxLoginFailures {
+		return 0
+	}
+
+	shift := failures - maxLoginFailures
+	if shift > 10 {
+		return 24 * time.Hour
+	}
+
+	return min(time.Minute<<shift, 24*time.Hour)
+}
+
+func (s *server) getLoginLockout(ctx context.Context, email string) (time.Duration, error) {
+	var lockedUntil sql.NullTime
+	var now time.Time
+	query :=
+		`
+			SELECT locked_until, NOW() FROM login_attempts WHERE email = $1;
+		`
+	if err := s.store.QueryRowContext(ctx, query, strings.ToLower(email)).Scan(&lockedUntil, &now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting login lockout, %v", err)
+	}
+
+	if !lockedUntil.Valid || !lockedUntil.Time.After(now) {
+		return 0, nil
+	}
+
+	return lockedUntil.Time.Sub(now), nil
+}
+
+func (s *server) recordLoginFailure(ctx context.Context, email string) error {
+	var failures int
+	query :=
+		`
+			INSERT INTO login_attempts (email, failures) VALUES ($1, 1)
+			ON CONFLICT (email)
+			DO UPDATE SET
+				failures = CASE
+					WHEN login_attempts.updated_at < NOW() - make_interval(secs => $2)
+						AND (login_attempts.locked_until IS NULL OR login_attempts.locked_until <= NOW())
+					THEN 1
+					ELSE login_attempts.failures + 1
+				END,
+				updated_at = NOW()
+			RETURNING failures;
+		`
+	if err := s.store.QueryRowContext(ctx, query, strings.ToLower(email), loginFailureWindow.Seconds()).Scan(&failures); err != nil {
+		return fmt.Errorf("error recording login failure, %v", err)
+	}
+
+	lockout := loginLockoutDuration(failures)
+	if lockout == 0 {
+		return nil
+	}
+
+	query =
+		`
+			UPDATE login_attempts SET locked_until = NOW() + make_interval(secs => $1) WHERE email = $2;
+		`
+	if _, err := s.store.ExecContext(ctx, query, lockout.Seconds(), strings.ToLower(email)); err != nil {
+		return fmt.Errorf("error locking account, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) clearLoginFailures(ctx context.Context, email string) error {
+	query :=
+		`
+			DELETE FROM login_attempts WHERE email = $1;
+		`
+	if _, err := s.store.ExecContext(ctx, query, strings.ToLower(email)); err != nil {
+		return fmt.Errorf("error clearing login failures, %v", err)
+	}
+	return nil
+}
+
+// sessionRevoked reports whether a token issued at issuedAt can no longer be
+// used, because the account was deleted or its sessions were revoked since.
+// Tokens carry their issue time in whole seconds, so revocations are stored
+// rounded down and tokens issued in the same second are kept, letting the
+// session that revoked the others go on.
+func (s *server) sessionRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	query :=
+		`
+			SELECT deleted_at IS NOT NULL OR COALESCE(sessions_revoked_at > $2, FALSE) FROM users WHERE id = $1;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, userID, issuedAt).Scan(&revoked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
+		return false, fmt.Errorf("error checking sessions, %v", err)
+	}
+
+	return revoked, nil
+}
+
+// markReauthenticated records that the user just signed in with a provider
+// again to confirm a sensitive change
+func (s *server) markReauthenticated(ctx context.Context, userID string) error {
+	query :=
+		`
+			UPDATE users SET reauthenticated_at = NOW() WHERE id = $1 AND deleted_at IS NULL;
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error marking user reauthenticated, %v", err)
+	}
+
+	return nil
+}
+
+// consumeReauthentication reports whether the user signed in with a provider
+// again within maxAge, using it up so it only confirms one change
+func (s *server) consumeReauthentication(ctx context.Context, userID string, maxAge time.Duration) (bool, error) {
+	query :=
+		`
+			UPDATE users SET reauthenticated_at = NULL
+			WHERE id = $1 AND reauthenticated_at > NOW() - make_interval(secs => $2);
+		`
+
+	results, err := s.store.ExecContext(ctx, query, userID, maxAge.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("error checking reauthentication, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+
+	return rows > 0, nil
 }
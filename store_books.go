@@ -30,16 +30,38 @@ func (s *server) uploadBook(ctx context.Context, book *book) (string, error) {
 	defer tx.Rollback()
 
 	var id string
+	var sourceBookID string
+	var languageExists bool
 
 	query :=
 		`
-				INSERT INTO books (name, description, author_id, language) 
-				VALUES ($1, $2, $3, $4) 
+			SELECT EXISTS(SELECT 1 FROM languages WHERE code = $1);
+		`
+
+	if err := tx.QueryRowContext(ctx, query, book.language).Scan(&languageExists); err != nil {
+		return "", fmt.Errorf("error checking if language exists, %v", err)
+	}
+
+	if !languageExists {
+		return "", errLanguageNotFound
+	}
+
+	if book.sourceBookID != "" {
+		sourceBookID, err = originalBook(ctx, tx, book.sourceBookID, book.language)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	query =
+		`
+				INSERT INTO books (name, description, author_id, language, source_book_id, content_rating, content_warnings) 
+				VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7) 
 				ON CONFLICT (name) DO NOTHING
 				RETURNING id;
 			`
 
-	err = tx.QueryRowContext(ctx, query, &book.name, &book.description, &book.authorID, &book.language).Scan(&id)
+	err = tx.QueryRowContext(ctx, query, &book.name, &book.description, &book.authorID, &book.language, sourceBookID, book.contentRating, pq.Array(book.contentWarnings)).Scan(&id)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return "", errBookNameAlreadyTaken
@@ -78,9 +100,6 @@ func (s *server) uploadBook(ctx context.Context, book *book) (string, error) {
 	rows, err = tx.QueryContext(ctx, query, pq.Array(book.genres))
 
 	if err != nil {
-		if strings.Contains(err.Error(), "invalid input value for enum genre_type") {
-			return "", errGenresNotFound
-		}
 		return "", fmt.Errorf("error retrieving genre ids, %v", err)
 	}
 
@@ -94,6 +113,10 @@ func (s *server) uploadBook(ctx context.Context, book *book) (string, error) {
 		genreIDs = append(genreIDs, id)
 	}
 
+	if len(genreIDs) != len(book.genres) {
+		return "", errGenresNotFound
+	}
+
 	valStrings = []string{}
 	valArgs = []interface{}{}
 	position = 1
@@ -112,6 +135,10 @@ func (s *server) uploadBook(ctx context.Context, book *book) (string, error) {
 		return "", fmt.Errorf("error inserting into book_genres, %v", err)
 	}
 
+	if err := setBookTags(ctx, tx, id, book.tags); err != nil {
+		return "", err
+	}
+
 	query =
 		`
 				INSERT INTO chapters(chapter_no, title, content, book_id)
@@ -124,6 +151,15 @@ func (s *server) uploadBook(ctx context.Context, book *book) (string, error) {
 		return "", fmt.Errorf("error inserting draft chapter, %v", err)
 	}
 
+	query =
+		`
+			UPDATE users SET roles = array_append(roles, 'AUTHOR') WHERE id = $1 AND NOT 'AUTHOR' = ANY(roles);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, book.authorID); err != nil {
+		return "", fmt.Errorf("error making user an author, %v", err)
+	}
+
 	query =
 		`
 			INSERT INTO recently_uploaded_books(book_id) VALUES ($1);
@@ -247,8 +283,33 @@ func (s *server) helperGetBooks(ctx context.Context, query string, argErr error,
 		}
 	}
 
-	for _, b := range booksMap {
-		books = append(books, b)
+	tags, err := s.getBooksTags(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for bookID, names := range tags {
+		if b, ok := booksMap[bookID]; ok {
+			b.tags = names
+			booksMap[bookID] = b
+		}
+	}
+
+	volumes, err := s.getBooksSeries(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for bookID, sv := range volumes {
+		if b, ok := booksMap[bookID]; ok {
+			b.series = sv
+			booksMap[bookID] = b
+		}
+	}
+
+	// bookIDs are in the order the query returned them
+	for _, id := range bookIDs {
+		books = append(books, booksMap[id])
 	}
 
 	return books, nil
@@ -257,7 +318,7 @@ func (s *server) helperGetBooks(ctx context.Context, query string, argErr error,
 func helpersGetBooksRows(rows *sql.Rows, bookIDs *[]string, booksMap map[string]book) error {
 	for rows.Next() {
 		var book book
-		if err := rows.Scan(&book.id, &book.name, &book.description, &book.image, &book.views, &book.rating, &book.chapterCount); err != nil {
+		if err := rows.Scan(&book.id, &book.name, &book.description, &book.image, &book.views, &book.rating, &book.contentRating, pq.Array(&book.contentWarnings), &book.chapterCount); err != nil {
 			return fmt.Errorf("error scanning rows, %v", err)
 		}
 		*bookIDs = append(*bookIDs, book.id)
@@ -265,15 +326,18 @@ func helpersGetBooksRows(rows *sql.Rows, bookIDs *[]string, booksMap map[string]
 	}
 	return nil
 }
-func (s *server) getBooksByGenre(ctx context.Context, genre []string, offset, limit int, sort, order string) ([]book, error) {
+func (s *server) getBooksByGenre(ctx context.Context, genre []string, filter contentFilter, offset, limit int, sort, order string) ([]book, error) {
 	query :=
 		fmt.Sprintf(`
 			SELECT 
+				b.id, 
 				b.name, 
 				b.description, 
 				b.image, 
 				b.views, 
 				b.rating, 
+				b.content_rating,
+				b.content_warnings,
 				COUNT(c.id)
 			FROM books b
 			JOIN chapters c ON (b.id = c.book_id)
@@ -281,13 +345,14 @@ func (s *server) getBooksByGenre(ctx context.Context, genre []string, offset, li
 			JOIN genres g ON (g.id = bg.genre_id)
 			WHERE 
 				g.genre = ANY($1) 
-				AND b.approved = true
+				AND b.approved = true AND b.hidden = false
+				AND b.content_rating = ANY($2) AND NOT (b.content_warnings && $3)
 			GROUP BY b.id
 			ORDER BY %s %s
-			OFFSET $2 LIMIT $3;
+			OFFSET $4 LIMIT $5;
 		`, helperSortField(sort), order)
 
-	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderGenre, helpersGetBooksRows, pq.Array(genre), offset, limit)
+	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderGenre, helpersGetBooksRows, pq.Array(genre), pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings), offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -295,7 +360,7 @@ func (s *server) getBooksByGenre(ctx context.Context, genre []string, offset, li
 	return books, nil
 }
 
-func (s *server) getBooksByLanguage(ctx context.Context, language []string, offset, limit int, sort, order string) ([]book, error) {
+func (s *server) getBooksByLanguage(ctx context.Context, language []string, filter contentFilter, offset, limit int, sort, order string) ([]book, error) {
 	query :=
 		fmt.Sprintf(`
 			SELECT 
@@ -305,18 +370,21 @@ func (s *server) getBooksByLanguage(ctx context.Context, language []string, offs
 				b.image, 
 				b.views, 
 				b.rating,
+				b.content_rating,
+				b.content_warnings,
 				COUNT(c.id)
 			FROM books b
 			JOIN chapters c ON (b.id = c.book_id)
 			WHERE 
-				b.language = ANY($1::language_type[]) 
-				AND b.approved = true
+				b.language = ANY($1) 
+				AND b.approved = true AND b.hidden = false
+				AND b.content_rating = ANY($2) AND NOT (b.content_warnings && $3)
 			GROUP BY b.id
 			ORDER BY %s %s 
-			OFFSET $2 LIMIT $3;
+			OFFSET $4 LIMIT $5;
 		`, helperSortField(sort), order)
 
-	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderLanguage, helpersGetBooksRows, pq.Array(language), offset, limit)
+	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderLanguage, helpersGetBooksRows, pq.Array(language), pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings), offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -324,7 +392,7 @@ func (s *server) getBooksByLanguage(ctx context.Context, language []string, offs
 	return books, nil
 }
 
-func (s *server) getBooksByGenreAndLanguage(ctx context.Context, genre []string, language []string, offset, limit int, sort, order string) ([]book, error) {
+func (s *server) getBooksByGenreAndLanguage(ctx context.Context, genre []string, language []string, filter contentFilter, offset, limit int, sort, order string) ([]book, error) {
 	query :=
 		fmt.Sprintf(`
 			SELECT 
@@ -334,21 +402,24 @@ func (s *server) getBooksByGenreAndLanguage(ctx context.Context, genre []string,
 				b.image, 
 				b.views, 
 				b.rating,
+				b.content_rating,
+				b.content_warnings,
 				COUNT(c.id)
 			FROM books b
 			JOIN chapters c ON (b.id = c.book_id)
 			JOIN books_genres bg ON (bg.book_id = b.id)
 			JOIN genres g ON (g.id = bg.genre_id)
 			WHERE 
-				b.language = ANY($1::language_type[]) 
+				b.language = ANY($1) 
 				AND g.genre = ANY($2) 
-				AND b.approved = true
+				AND b.approved = true AND b.hidden = false
+				AND b.content_rating = ANY($3) AND NOT (b.content_warnings && $4)
 			GROUP BY b.id
 			ORDER BY %s %s
-			OFFSET $3 LIMIT $4;
+			OFFSET $5 LIMIT $6;
 		`, helperSortField(sort), order)
 
-	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderGenreAndLanguage, helpersGetBooksRows, pq.Array(language), pq.Array(genre), offset, limit)
+	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderGenreAndLanguage, helpersGetBooksRows, pq.Array(language), pq.Array(genre), pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings), offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -356,7 +427,7 @@ func (s *server) getBooksByGenreAndLanguage(ctx context.Context, genre []string,
 	return books, nil
 }
 
-func (s *server) getAllBooks(ctx context.Context, offset, limit int, sort, order string) ([]book, error) {
+func (s *server) getAllBooks(ctx context.Context, filter contentFilter, offset, limit int, sort, order string) ([]book, error) {
 	query :=
 		fmt.Sprintf(`
 			SELECT 
@@ -366,16 +437,19 @@ func (s *server) getAllBooks(ctx context.Context, offset, limit int, sort, order
 				b.image, 
 				b.views, 
 				b.rating,
+				b.content_rating,
+				b.content_warnings,
 				COUNT(c.id)
 			FROM books b
 			JOIN chapters c ON (b.id = c.book_id)
-			WHERE b.approved = true
+			WHERE b.approved = true AND b.hidden = false
+			AND b.content_rating = ANY($1) AND NOT (b.content_warnings && $2)
 			GROUP BY b.id
 			ORDER BY %s %s
-			OFFSET $1 LIMIT $2;
+			OFFSET $3 LIMIT $4;
 		`, helperSortField(sort), order)
 
-	books, err := s.helperGetBooks(ctx, query, nil, helpersGetBooksRows, offset, limit)
+	books, err := s.helperGetBooks(ctx, query, nil, helpersGetBooksRows, pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings), offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -492,6 +566,25 @@ func (s *server) getRecentlyUploadBooks(ctx context.Context, offset, limit int)
 	return books, nil
 }
 
+func (s *server) checkIfBookExists(ctx context.Context, bookID string) error {
+	var exists bool
+
+	query :=
+		`
+			SELECT EXISTS(SELECT 1 FROM books WHERE id = $1 AND approved = true AND hidden = false);
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking if book exists, %v", err)
+	}
+
+	if !exists {
+		return errBookNotFound
+	}
+
+	return nil
+}
+
 func (s *server) getBook(ctx context.Context, bookID string) (*book, error) {
 	var book book
 	query :=
@@ -505,17 +598,19 @@ func (s *server) getBook(ctx context.Context, bookID string) (*book, error) {
 				b.rating, 
 				b.language, 
 				b.completed, 
+				b.content_rating,
+				b.content_warnings,
 				b.created_at,
 				u.display_name,
 				COUNT (c.id)
 			FROM books b
 			JOIN users u ON (u.id = b.author_id)
-			JOIN chapters c ON (c.book_id = b.id)
+			LEFT JOIN chapters c ON (c.book_id = b.id AND c.hidden = false AND c.moderation_status = 'approved')
 			WHERE b.id = $1 
-			AND b.approved = true
+			AND b.approved = true AND b.hidden = false
 			GROUP BY b.id, u.display_name;
 		`
-	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&book.id, &book.name, &book.description, &book.image, &book.views, &book.rating, &book.language, &book.completed, &book.createdAt, &book.authorName, &book.chapterCount); err != nil {
+	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&book.id, &book.name, &book.description, &book.image, &book.views, &book.rating, &book.language, &book.completed, &book.contentRating, pq.Array(&book.contentWarnings), &book.createdAt, &book.authorName, &book.chapterCount); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errBookNotFound
 		}
@@ -574,7 +669,7 @@ func (s *server) getBook(ctx context.Context, bookID string) (*book, error) {
 
 	query =
 		`
-			SELECT title, chapter_no, created_at FROM chapters WHERE book_id = $1;
+			SELECT title, chapter_no, created_at FROM chapters WHERE book_id = $1 AND hidden = false AND moderation_status = 'approved';
 		`
 
 	chaptersRows, err := s.store.QueryContext(ctx, query, bookID)
@@ -595,6 +690,51 @@ func (s *server) getBook(ctx context.Context, bookID string) (*book, error) {
 		book.chapters = append(book.chapters, chapter)
 	}
 
+	query =
+		`
+			SELECT b.author_id, u.display_name, 'author', 100 - COALESCE((SELECT SUM(revenue_share) FROM book_collaborators WHERE book_id = b.id AND status = 'accepted'), 0), 0 AS position
+			FROM books b
+			JOIN users u ON (u.id = b.author_id)
+			WHERE b.id = $1
+			UNION ALL
+			SELECT bc.user_id, u.display_name, bc.role, bc.revenue_share, 1 AS position
+			FROM book_collaborators bc
+			JOIN users u ON (u.id = bc.user_id)
+			WHERE bc.book_id = $1 AND bc.status = 'accepted'
+			ORDER BY position, display_name;
+		`
+
+	creditRows, err := s.store.QueryContext(ctx, query, bookID)
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting credits, %v", err)
+	}
+
+	defer creditRows.Close()
+
+	for creditRows.Next() {
+		var credit credit
+		var position int
+
+		if err := creditRows.Scan(&credit.userID, &credit.displayName, &credit.role, &credit.revenueShare, &position); err != nil {
+			return nil, fmt.Errorf("error scanning credits, %v", err)
+		}
+
+		book.credits = append(book.credits, credit)
+	}
+
+	tags, err := s.getBooksTags(ctx, []string{book.id})
+	if err != nil {
+		return nil, err
+	}
+	book.tags = tags[book.id]
+
+	volumes, err := s.getBooksSeries(ctx, []string{book.id})
+	if err != nil {
+		return nil, err
+	}
+	book.series = volumes[book.id]
+
 	return &book, nil
 }
 
@@ -620,7 +760,13 @@ func (s *server) deleteBook(ctx context.Context, userID, bookID string) error {
 	return nil
 }
 
+// editBook updates the book as book.authorID, who has to be its author or a
+// collaborator allowed to edit it
 func (s *server) editBook(ctx context.Context, book *book) error {
+	if err := s.checkBookAccess(ctx, book.id, book.authorID, bookEdit); err != nil {
+		return err
+	}
+
 	index := 0
 	clauses := []string{}
 	arguments := []interface{}{}
@@ -643,7 +789,19 @@ func (s *server) editBook(ctx context.Context, book *book) error {
 		arguments = append(arguments, book.image.String)
 	}
 
-	arguments = append(arguments, book.id, book.authorID)
+	if book.contentRating != "" {
+		index++
+		clauses = append(clauses, fmt.Sprintf("content_rating=$%d", index))
+		arguments = append(arguments, book.contentRating)
+	}
+
+	if book.contentWarnings != nil {
+		index++
+		clauses = append(clauses, fmt.Sprintf("content_warnings=$%d", index))
+		arguments = append(arguments, pq.Array(book.contentWarnings))
+	}
+
+	arguments = append(arguments, book.id)
 
 	tx, err := s.store.Begin()
 
@@ -653,7 +811,7 @@ func (s *server) editBook(ctx context.Context, book *book) error {
 
 	defer tx.Rollback()
 
-	query := fmt.Sprintf(`UPDATE books SET %v WHERE id = $%d AND author_id = $%d;`, strings.Join(clauses, ","), index+1, index+2)
+	query := fmt.Sprintf(`UPDATE books SET %v WHERE id = $%d;`, strings.Join(clauses, ","), index+1)
 
 	if len(clauses) > 0 {
 		results, err := tx.ExecContext(ctx, query, arguments...)
@@ -732,7 +890,7 @@ func (s *server) editBook(ctx context.Context, book *book) error {
 
 		query :=
 			`
-				SELECT id FROM genres WHERE genres = ANY($1);
+				SELECT id FROM genres WHERE genre = ANY($1);
 			`
 
 		genreRows, err := tx.QueryContext(ctx, query, pq.Array(book.genres))
@@ -778,30 +936,14 @@ func (s *server) editBook(ctx context.Context, book *book) error {
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("error commititng transaction, %v", err)
-	}
-
-	return nil
-}
-
-func (s *server) approveBook(ctx context.Context, bookID string, approve bool) error {
-	query :=
-		`
-			UPDATE books SET approved = $1 WHERE id = $2;
-		`
-
-	results, err := s.store.ExecContext(ctx, query, approve, bookID)
-	if err != nil {
-		return fmt.Errorf("error approving book, %v", err)
+	if book.tags != nil {
+		if err := setBookTags(ctx, tx, book.id, book.tags); err != nil {
+			return err
+		}
 	}
 
-	rows, err := results.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error checking number of rows affected, %v", err)
-	}
-	if rows == 0 {
-		return errBookNotFound
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
 	}
 
 	return nil
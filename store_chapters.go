@@ -5,22 +5,31 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
 	errChapterNotFound = errors.New("chapter not found")
 )
 
-func (s *server) checkIfBookBelongsToUser(ctx context.Context, bookID, userID string) error {
+// checkBookAccess makes sure the user wrote the book or is a collaborator
+// allowed to do action on it. Books the user can't touch are reported as not
+// found.
+func (s *server) checkBookAccess(ctx context.Context, bookID, userID string, action bookAction) error {
 	var exists bool
 
 	query :=
 		`
-			SELECT EXISTS(SELECT 1 FROM books WHERE id = $1 AND author_id = $2);
+			SELECT
+				EXISTS(SELECT 1 FROM books WHERE id = $1 AND author_id = $2)
+				OR EXISTS(SELECT 1 FROM book_collaborators WHERE book_id = $1 AND user_id = $2 AND status = 'accepted' AND role = ANY($3));
 		`
 
-	if err := s.store.QueryRowContext(ctx, query, bookID, userID).Scan(&exists); err != nil {
+	if err := s.store.QueryRowContext(ctx, query, bookID, userID, pq.Array(collaboratorRolesWith(action))).Scan(&exists); err != nil {
 		return fmt.Errorf("error checking if books exist, %v", err)
 	}
 
@@ -34,25 +43,101 @@ func (s *server) checkIfBookBelongsToUser(ctx context.Context, bookID, userID st
 func (s *server) uploadChapter(ctx context.Context, userID string, ch *chapter) (string, error) {
 	var id string
 
-	if err := s.checkIfBookBelongsToUser(ctx, ch.bookID, userID); err != nil {
+	if err := s.checkBookAccess(ctx, ch.bookID, userID, bookUploadChapter); err != nil {
 		return "", err
 	}
 
 	query :=
 		`
-			INSERT INTO chapters (chapter_no, title, content, book_id)
-			VALUES ($1, $2, $3, $4) RETURNING id;
+			INSERT INTO chapters (chapter_no, title, content, book_id, moderation_status, source_chapter_id)
+			VALUES ($1, $2, $3, $4, 'pending', NULLIF($5, '')::uuid) RETURNING id;
 		`
 
-	if err := s.store.QueryRowContext(ctx, query, ch.chapterNo, ch.title, ch.content, ch.bookID).Scan(&id); err != nil {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	if ch.sourceChapterID != "" {
+		if err := checkSourceChapter(ctx, tx, ch.bookID, ch.sourceChapterID); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.QueryRowContext(ctx, query, ch.chapterNo, ch.title, ch.content, ch.bookID, ch.sourceChapterID).Scan(&id); err != nil {
+		if isChapterAlreadyTranslated(err) {
+			return "", errChapterAlreadyTranslated
+		}
 		return "", fmt.Errorf("error uploading chapter, %v", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error commititng transaction, %v", err)
+	}
+
 	return id, nil
 }
 
+// getPublishedChapter returns an approved chapter along with its book's name
+func (s *server) getPublishedChapter(ctx context.Context, chapterID string) (*chapter, string, error) {
+	var ch chapter
+	var bookName string
+
+	query :=
+		`
+			SELECT c.book_id, c.chapter_no, c.announced_at IS NOT NULL, c.notified_at IS NOT NULL, b.name
+			FROM chapters c
+			JOIN books b ON (c.book_id = b.id)
+			WHERE c.id = $1 AND c.moderation_status = 'approved';
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, chapterID).Scan(&ch.bookID, &ch.chapterNo, &ch.announced, &ch.notified, &bookName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", errChapterNotFound
+		}
+		return nil, "", fmt.Errorf("error scanning chapter, %v", err)
+	}
+
+	return &ch, bookName, nil
+}
+
+// markChapterAnnounced records that followers were told about the chapter so
+// a redelivered approval doesn't tell them again
+func (s *server) markChapterAnnounced(ctx context.Context, chapterID string) error {
+	query :=
+		`
+			UPDATE chapters SET announced_at = NOW() WHERE id = $1;
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, chapterID); err != nil {
+		return fmt.Errorf("error marking chapter announced, %v", err)
+	}
+
+	return nil
+}
+
+// markChapterNotified records that the chapter was sent to the notification
+// queue
+func (s *server) markChapterNotified(ctx context.Context, chapterID string) error {
+	query :=
+		`
+			UPDATE chapters SET notified_at = NOW() WHERE id = $1;
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, chapterID); err != nil {
+		return fmt.Errorf("error marking chapter notified, %v", err)
+	}
+
+	return nil
+}
+
+// getChapter returns the chapter for userID to read. Books rated above what
+// the reader is allowed to read are restricted, except to their author.
 func (s *server) getChapter(ctx context.Context, userID, bookID string) (*chapter, error) {
 	var ch chapter
+	var rating string
+	var isAuthor bool
 
 	query :=
 		`
@@ -60,19 +145,32 @@ func (s *server) getChapter(ctx context.Context, userID, bookID string) (*chapte
 				book_id,
 				chapter_no, 
 				title, 
-				content 
+				content,
+				b.content_rating,
+				b.author_id = $2
 			FROM chapters c
 			JOIN books b ON (c.book_id = b.id)
-			WHERE c.id = $1 AND b.approved = true;
+			WHERE c.id = $1 AND b.approved = true AND b.hidden = false AND c.hidden = false AND c.moderation_status = 'approved';
 		`
 
-	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&ch.bookID, &ch.chapterNo, &ch.title, &ch.content); err != nil {
+	if err := s.store.QueryRowContext(ctx, query, bookID, userID).Scan(&ch.bookID, &ch.chapterNo, &ch.title, &ch.content, &rating, &isAuthor); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errChapterNotFound
 		}
 		return nil, fmt.Errorf("error scanning chapter, %v", err)
 	}
 
+	if !isAuthor {
+		preferences, err := s.getContentPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(preferences.allowedRatings(time.Now()), rating) {
+			return nil, errContentRestricted
+		}
+	}
+
 	query =
 		`
 			INSERT INTO recent_books(user_id, book_id, chapter)
@@ -91,16 +189,16 @@ func (s *server) getChapter(ctx context.Context, userID, bookID string) (*chapte
 }
 
 func (s *server) deleteChapter(ctx context.Context, userID, bookID, chapterID string) error {
-	if err := s.checkIfBookBelongsToUser(ctx, bookID, userID); err != nil {
+	if err := s.checkBookAccess(ctx, bookID, userID, bookDeleteChapter); err != nil {
 		return err
 	}
 
 	query :=
 		`
-			DELETE FROM chapters WHERE id = $1;
+			DELETE FROM chapters WHERE id = $1 AND book_id = $2;
 		`
 
-	results, err := s.store.ExecContext(ctx, query, chapterID)
+	results, err := s.store.ExecContext(ctx, query, chapterID, bookID)
 	if err != nil {
 		return fmt.Errorf("error deleting chapter, %v", err)
 	}
@@ -117,7 +215,7 @@ func (s *server) deleteChapter(ctx context.Context, userID, bookID, chapterID st
 }
 
 func (s *server) editChapter(ctx context.Context, userID string, ch *chapter) error {
-	if err := s.checkIfBookBelongsToUser(ctx, ch.bookID, userID); err != nil {
+	if err := s.checkBookAccess(ctx, ch.bookID, userID, bookEditChapter); err != nil {
 		return err
 	}
 
@@ -125,8 +223,9 @@ func (s *server) editChapter(ctx context.Context, userID string, ch *chapter) er
 	var values []string
 	var args []any
 
+	// new content has to be fingerprinted again
 	if ch.content != "" {
-		values = append(values, fmt.Sprintf("content=$%v", index))
+		values = append(values, fmt.Sprintf("content=$%v", index), "fingerprinted_at=NULL")
 		args = append(args, ch.content)
 		index++
 	}
@@ -135,12 +234,37 @@ func (s *server) editChapter(ctx context.Context, userID string, ch *chapter) er
 		args = append(args, ch.title)
 		index++
 	}
+	// approved chapters with new text are held until they pass moderation
+	// again, flagged and rejected ones stay with the admins
+	if ch.content != "" || ch.title != "" {
+		values = append(values, "moderation_status=CASE WHEN moderation_status = 'approved' THEN 'pending' ELSE moderation_status END", "moderation_requested_at=NOW()", "moderation_attempts=0", "moderation_failed_at=NULL")
+	}
+	if ch.sourceChapterID != "" {
+		values = append(values, fmt.Sprintf("source_chapter_id=$%v", index))
+		args = append(args, ch.sourceChapterID)
+		index++
+	}
 
-	query := fmt.Sprintf("UPDATE chapters SET %v WHERE id = $%v;", strings.Join(values, ","), index)
-	args = append(args, ch.id)
+	query := fmt.Sprintf("UPDATE chapters SET %v WHERE id = $%v AND book_id = $%v;", strings.Join(values, ","), index, index+1)
+	args = append(args, ch.id, ch.bookID)
 
-	results, err := s.store.ExecContext(ctx, query, args...)
+	tx, err := s.store.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	if ch.sourceChapterID != "" {
+		if err := checkSourceChapter(ctx, tx, ch.bookID, ch.sourceChapterID); err != nil {
+			return err
+		}
+	}
+
+	results, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		if isChapterAlreadyTranslated(err) {
+			return errChapterAlreadyTranslated
+		}
 		return fmt.Errorf("error updating chapter chapter, %v", err)
 	}
 
@@ -152,5 +276,9 @@ func (s *server) editChapter(ctx context.Context, userID string, ch *chapter) er
 		return errChapterNotFound
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	errCollaboratorExists   = errors.New("user is already invited to this book")
+	errCollaboratorNotFound = errors.New("collaborator not found")
+	errInvitationNotFound   = errors.New("invitation not found")
+	errInviteAuthor         = errors.New("the author can't be invited to their own book")
+	errRevenueShareTooHigh  = errors.New("collaborators can't share more than 100% of the revenue")
+)
+
+// inviteCollaborator invites c.userID to work on a book written by authorID.
+// Declined invitations can be sent again. The shares promised to collaborators
+// who haven't declined can't add up to more than 100%, the author keeps what's
+// left.
+func (s *server) inviteCollaborator(ctx context.Context, authorID string, c *collaborator) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var bookAuthorID sql.NullString
+
+	query :=
+		`
+			SELECT author_id FROM books WHERE id = $1 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, c.bookID).Scan(&bookAuthorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errBookNotFound
+		}
+		return fmt.Errorf("error getting book, %v", err)
+	}
+
+	if bookAuthorID.String != authorID {
+		return errBookNotFound
+	}
+
+	if c.userID == authorID {
+		return errInviteAuthor
+	}
+
+	var exists bool
+
+	query =
+		`
+			SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL);
+		`
+
+	if err := tx.QueryRowContext(ctx, query, c.userID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking if user exists, %v", err)
+	}
+
+	if !exists {
+		return errUserNotFound
+	}
+
+	var shared int
+
+	query =
+		`
+			SELECT COALESCE(SUM(revenue_share), 0) FROM book_collaborators WHERE book_id = $1 AND user_id <> $2 AND status <> 'declined';
+		`
+
+	if err := tx.QueryRowContext(ctx, query, c.bookID, c.userID).Scan(&shared); err != nil {
+		return fmt.Errorf("error getting revenue shares, %v", err)
+	}
+
+	if shared+c.revenueShare > 100 {
+		return errRevenueShareTooHigh
+	}
+
+	query =
+		`
+			INSERT INTO book_collaborators (book_id, user_id, role, revenue_share, invited_by)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (book_id, user_id) DO UPDATE SET
+				role = EXCLUDED.role,
+				revenue_share = EXCLUDED.revenue_share,
+				invited_by = EXCLUDED.invited_by,
+				status = 'pending',
+				created_at = NOW(),
+				responded_at = NULL
+			WHERE book_collaborators.status = 'declined';
+		`
+
+	results, err := tx.ExecContext(ctx, query, c.bookID, c.userID, c.role, c.revenueShare, authorID)
+	if err != nil {
+		return fmt.Errorf("error inviting collaborator, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errCollaboratorExists
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// respondToInvitation accepts or declines the user's pending invitation to
+// work on a book
+func (s *server) respondToInvitation(ctx context.Context, userID, bookID string, accept bool) error {
+	status := "declined"
+	if accept {
+		status = "accepted"
+	}
+
+	query :=
+		`
+			UPDATE book_collaborators SET status = $3, responded_at = NOW()
+			WHERE book_id = $1 AND user_id = $2 AND status = 'pending';
+		`
+
+	results, err := s.store.ExecContext(ctx, query, bookID, userID, status)
+	if err != nil {
+		return fmt.Errorf("error responding to invitation, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errInvitationNotFound
+	}
+
+	return nil
+}
+
+// removeCollaborator takes userID off the book. The author can remove anyone,
+// collaborators can only leave.
+func (s *server) removeCollaborator(ctx context.Context, requesterID, bookID, userID string) error {
+	query :=
+		`
+			DELETE FROM book_collaborators
+			WHERE book_id = $1 AND user_id = $2
+			AND ($2 = $3 OR EXISTS(SELECT 1 FROM books WHERE id = $1 AND author_id = $3));
+		`
+
+	results, err := s.store.ExecContext(ctx, query, bookID, userID, requesterID)
+	if err != nil {
+		return fmt.Errorf("error removing collaborator, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errCollaboratorNotFound
+	}
+
+	return nil
+}
+
+// getCollaborators returns everyone invited to the book. Only the author and
+// the collaborators who accepted can see them.
+func (s *server) getCollaborators(ctx context.Context, requesterID, bookID string) ([]collaborator, error) {
+	var allowed bool
+
+	query :=
+		`
+			SELECT
+				EXISTS(SELECT 1 FROM books WHERE id = $1 AND author_id = $2)
+				OR EXISTS(SELECT 1 FROM book_collaborators WHERE book_id = $1 AND user_id = $2 AND status = 'accepted');
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, bookID, requesterID).Scan(&allowed); err != nil {
+		return nil, fmt.Errorf("error checking if books exist, %v", err)
+	}
+
+	if !allowed {
+		return nil, errBookNotFound
+	}
+
+	query =
+		`
+			SELECT bc.book_id, b.name, bc.user_id, u.display_name, bc.role, bc.revenue_share, bc.status, bc.invited_by, bc.created_at
+			FROM book_collaborators bc
+			JOIN books b ON (b.id = bc.book_id)
+			JOIN users u ON (u.id = bc.user_id)
+			WHERE bc.book_id = $1
+			ORDER BY bc.created_at;
+		`
+
+	return s.queryCollaborators(ctx, query, bookID)
+}
+
+// getInvitations returns the invitations the user hasn't answered yet
+func (s *server) getInvitations(ctx context.Context, userID string) ([]collaborator, error) {
+	query :=
+		`
+			SELECT bc.book_id, b.name, bc.user_id, u.display_name, bc.role, bc.revenue_share, bc.status, bc.invited_by, bc.created_at
+			FROM book_collaborators bc
+			JOIN books b ON (b.id = bc.book_id)
+			JOIN users u ON (u.id = bc.user_id)
+			WHERE bc.user_id = $1 AND bc.status = 'pending'
+			ORDER BY bc.created_at DESC;
+		`
+
+	return s.queryCollaborators(ctx, query, userID)
+}
+
+func (s *server) queryCollaborators(ctx context.Context, query string, args ...any) ([]collaborator, error) {
+	rows, err := s.store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting collaborators, %v", err)
+	}
+	defer rows.Close()
+
+	var collaborators []collaborator
+
+	for rows.Next() {
+		var c collaborator
+		if err := rows.Scan(&c.bookID, &c.bookName, &c.userID, &c.displayName, &c.role, &c.revenueShare, &c.status, &c.invitedBy, &c.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning collaborators, %v", err)
+		}
+		collaborators = append(collaborators, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting collaborators, %v", err)
+	}
+
+	return collaborators, nil
+}
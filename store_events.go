@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// eventRecipients selects the users an event is recorded for. It mirrors who
+// the hub delivers the event to, except that room members are the readers with
+// the book in their library rather than whoever is subscribed right now. Its
+// arguments are numbered from $6.
+func eventRecipients(e *event) (string, []any, error) {
+	switch e.Type {
+	case NEW_BOOK:
+		return `SELECT id FROM users WHERE roles && $6::role_type[] AND deleted_at IS NULL`, []any{pq.Array(rolesWith(permApproveBook))}, nil
+	case NEW_REPORT:
+		return `SELECT id FROM users WHERE roles && $6::role_type[] AND deleted_at IS NULL`, []any{pq.Array(rolesWith(permResolveReport))}, nil
+	case CHAPTER_UPLOADED:
+		return `SELECT user_id FROM library WHERE book_id = $6`, []any{e.Payload.(chapterUploadEvent).BookId}, nil
+	case NEW_FOLLOWER:
+		return `SELECT id FROM users WHERE id = $6 AND deleted_at IS NULL`, []any{e.Payload.(followerEvent).UserId}, nil
+	case BOOK_MODERATED:
+		return `SELECT id FROM users WHERE id = $6 AND deleted_at IS NULL`, []any{e.Payload.(bookModeratedEvent).AuthorId}, nil
+	}
+	return "", nil, fmt.Errorf("event %s is not recorded", e.Type)
+}
+
+// recordEvent stores the event once for every recipient, numbered with the next
+// value of that recipient's sequence, and forgets recipients' events older than
+// maxAge. It returns the sequence number the event got for every recipient.
+// Sequences are locked in user id order so events recorded at the same time for
+// overlapping recipients wait on each other instead of deadlocking.
+func (s *server) recordEvent(ctx context.Context, e *event, maxAge time.Duration) (map[string]int64, error) {
+	recipients, args, err := eventRecipients(e)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling event payload, %v", err)
+	}
+
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(
+		`
+			WITH seqs AS (
+				INSERT INTO user_event_sequences (user_id, seq)
+				SELECT recipients.id, 1 FROM (%s) AS recipients(id)
+				ORDER BY recipients.id
+				ON CONFLICT (user_id) DO UPDATE SET seq = user_event_sequences.seq + 1
+				RETURNING user_id, seq
+			)
+			INSERT INTO user_events (user_id, seq, event_id, type, version, occurred_at, payload)
+			SELECT user_id, seq, $1, $2, $3, $4, $5 FROM seqs
+			RETURNING user_id, seq;
+		`, recipients)
+
+	rows, err := tx.QueryContext(ctx, query, append([]any{e.ID, e.Type.String(), eventRegistry[e.Type].version, e.OccurredAt, payload}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error recording event, %v", err)
+	}
+	defer rows.Close()
+
+	seqs := map[string]int64{}
+	var userIDs []string
+
+	for rows.Next() {
+		var userID string
+		var seq int64
+
+		if err := rows.Scan(&userID, &seq); err != nil {
+			return nil, fmt.Errorf("error scanning event sequence, %v", err)
+		}
+
+		seqs[userID] = seq
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error recording event, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	// pruning is left out of the transaction so the sequences aren't held while
+	// it runs, rows another event is already pruning are skipped. The event is
+	// recorded either way, old events are pruned with the next one.
+	query =
+		`
+			DELETE FROM user_events WHERE (user_id, seq) IN (
+				SELECT user_id, seq FROM user_events
+				WHERE user_id = ANY($1) AND created_at < NOW() - make_interval(secs => $2)
+				ORDER BY user_id, seq
+				FOR UPDATE SKIP LOCKED
+			);
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, pq.Array(userIDs), maxAge.Seconds()); err != nil {
+		s.logger.Error(fmt.Sprintf("error pruning old events, %v", err))
+	}
+
+	return seqs, nil
+}
+
+// getMissedEvents returns, oldest first, at most limit of the events recorded for
+// the user after the given sequence number and within maxAge. It reports the
+// result as truncated when older missed events were left out.
+func (s *server) getMissedEvents(ctx context.Context, userID string, after int64, admin bool, limit int, maxAge time.Duration) ([]*envelope, bool, error) {
+	query :=
+		`
+			SELECT event_id, type, version, occurred_at, seq, payload FROM user_events
+			WHERE user_id = $1 AND seq > $2
+				AND created_at > NOW() - make_interval(secs => $3)
+				AND (type <> ALL($4) OR $5)
+			ORDER BY seq DESC
+			LIMIT $6;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, userID, after, maxAge.Seconds(), pq.Array(adminEventNames()), admin, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("error getting missed events, %v", err)
+	}
+	defer rows.Close()
+
+	var events []*envelope
+
+	for rows.Next() {
+		var e envelope
+
+		if err := rows.Scan(&e.ID, &e.Type, &e.Version, &e.OccurredAt, &e.Seq, &e.Payload); err != nil {
+			return nil, false, fmt.Errorf("error scanning missed events, %v", err)
+		}
+
+		events = append(events, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error getting missed events, %v", err)
+	}
+
+	truncated := len(events) > limit
+	if truncated {
+		events = events[:limit]
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+
+	// events past maxAge are gone, so a gap before the oldest one means the
+	// client missed more than what is replayed
+	if len(events) > 0 && events[0].Seq > after+1 {
+		truncated = true
+	}
+
+	return events, truncated, nil
+}
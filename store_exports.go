@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errExportNotFound = errors.New("export not found")
+	errExportPending  = errors.New("an export is already being prepared")
+)
+
+type dataExport struct {
+	id          string
+	status      string
+	objectKey   sql.NullString
+	expiresAt   sql.NullTime
+	completedAt sql.NullTime
+	createdAt   time.Time
+}
+
+func (s *server) createDataExport(ctx context.Context, userID string) (string, error) {
+	var id string
+	query :=
+		`
+			INSERT INTO data_exports (user_id)
+			SELECT $1
+			WHERE NOT EXISTS(SELECT 1 FROM data_exports WHERE user_id = $1 AND status = 'pending')
+			RETURNING id;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&id); err != nil {
+		// two requests racing past the check meet the unique index instead
+		var pqErr *pq.Error
+		if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "23505") {
+			return "", errExportPending
+		}
+		return "", fmt.Errorf("error inserting into data exports table, %v", err)
+	}
+
+	return id, nil
+}
+
+// failDataExport marks an export that never made it onto the queue as failed,
+// so it doesn't block the user from requesting another one
+func (s *server) failDataExport(ctx context.Context, exportID string) error {
+	query :=
+		`
+			UPDATE data_exports SET status = 'failed', completed_at = NOW() WHERE id = $1 AND status = 'pending';
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, exportID); err != nil {
+		return fmt.Errorf("error marking data export as failed, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) getDataExports(ctx context.Context, userID string) ([]dataExport, error) {
+	var exports []dataExport
+
+	query :=
+		`
+			SELECT id, status, object_key, expires_at, completed_at, created_at FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data exports, %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e dataExport
+		if err := rows.Scan(&e.id, &e.status, &e.objectKey, &e.expiresAt, &e.completedAt, &e.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning data export, %v", err)
+		}
+		exports = append(exports, e)
+	}
+
+	return exports, nil
+}
+
+func (s *server) getDataExport(ctx context.Context, userID, exportID string) (*dataExport, error) {
+	var e dataExport
+	query :=
+		`
+			SELECT id, status, object_key, expires_at, completed_at, created_at FROM data_exports WHERE id = $1 AND user_id = $2;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, exportID, userID).Scan(&e.id, &e.status, &e.objectKey, &e.expiresAt, &e.completedAt, &e.createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errExportNotFound
+		}
+		return nil, fmt.Errorf("error getting data export, %v", err)
+	}
+
+	return &e, nil
+}
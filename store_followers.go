@@ -16,7 +16,7 @@ func (s *server) checkIfUserExistsByID(ctx context.Context, userID string) error
 
 	query :=
 		`
-			SELECT EXISTS(SELECT 1 FROM users WHERE id = $1);
+			SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL);
 		`
 
 	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&exists); err != nil {
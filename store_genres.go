@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var errGenreExists = errors.New("genre already exists")
+
+func (s *server) createGenre(ctx context.Context, name string) (string, error) {
+	var id string
+
+	query :=
+		`
+			INSERT INTO genres (genre) VALUES ($1) RETURNING id;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, name).Scan(&id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return "", errGenreExists
+		}
+		return "", fmt.Errorf("error inserting genre, %v", err)
+	}
+
+	return id, nil
+}
+
+type genre struct {
+	id   string
+	name string
+}
+
+func (s *server) getGenres(ctx context.Context) ([]genre, error) {
+	query :=
+		`
+			SELECT id, genre FROM genres ORDER BY genre;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting genres, %v", err)
+	}
+	defer rows.Close()
+
+	var genres []genre
+
+	for rows.Next() {
+		var g genre
+		if err := rows.Scan(&g.id, &g.name); err != nil {
+			return nil, fmt.Errorf("error scanning genres, %v", err)
+		}
+		genres = append(genres, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting genres, %v", err)
+	}
+
+	return genres, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errIdentityNotFound      = errors.New("identity not found")
+	errIdentityAlreadyLinked = errors.New("identity already linked to another account")
+	errLastLoginMethod       = errors.New("cannot unlink the only way to sign in, set a password or link another provider first")
+)
+
+func (s *server) getUserByIdentity(ctx context.Context, provider, providerUserID string) (string, error) {
+	var id string
+	query :=
+		`
+			SELECT user_id FROM user_identities WHERE provider = $1 AND provider_user_id = $2;
+		`
+	if err := s.store.QueryRowContext(ctx, query, provider, providerUserID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errIdentityNotFound
+		}
+		return "", fmt.Errorf("error getting identity, %v", err)
+	}
+	return id, nil
+}
+
+func (s *server) linkIdentity(ctx context.Context, userID string, i *identity) error {
+	var email sql.NullString
+	if i.email != "" {
+		email = sql.NullString{String: i.email, Valid: true}
+	}
+
+	query :=
+		`
+			INSERT INTO user_identities (user_id, provider, provider_user_id, email)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, provider)
+			DO UPDATE SET
+				provider_user_id = EXCLUDED.provider_user_id,
+				email = EXCLUDED.email;
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, userID, i.provider, i.providerUserID, email); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errIdentityAlreadyLinked
+		}
+		return fmt.Errorf("error linking identity, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) getUserIdentities(ctx context.Context, userID string) ([]identity, error) {
+	var identities []identity
+
+	query :=
+		`
+			SELECT provider, provider_user_id, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting identities, %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i identity
+		var email sql.NullString
+		if err := rows.Scan(&i.provider, &i.providerUserID, &email, &i.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning identity, %v", err)
+		}
+		i.email = email.String
+		identities = append(identities, i)
+	}
+
+	return identities, nil
+}
+
+// unlinkIdentity refuses to remove the last identity of a user without a
+// password, since they would have no way left to sign in
+func (s *server) unlinkIdentity(ctx context.Context, userID, provider string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var hasPassword bool
+	var identities int
+
+	query :=
+		`
+			SELECT
+				u.password IS NOT NULL,
+				(SELECT COUNT(*) FROM user_identities ui WHERE ui.user_id = u.id)
+			FROM users u
+			WHERE u.id = $1
+			FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&hasPassword, &identities); err != nil {
+		return fmt.Errorf("error checking login methods, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM user_identities WHERE user_id = $1 AND provider = $2;
+		`
+
+	results, err := tx.ExecContext(ctx, query, userID, provider)
+	if err != nil {
+		return fmt.Errorf("error unlinking identity, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errIdentityNotFound
+	}
+
+	if !hasPassword && identities <= 1 {
+		return errLastLoginMethod
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errLanguageNotFound = errors.New("language not found")
+	errLanguageExists   = errors.New("language already exists")
+)
+
+// language is a language books can be written in, keyed by its BCP 47 code
+type language struct {
+	code       string
+	name       string
+	nativeName string
+}
+
+func (s *server) getLanguages(ctx context.Context) ([]language, error) {
+	query :=
+		`
+			SELECT code, name, native_name FROM languages ORDER BY name;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting languages, %v", err)
+	}
+	defer rows.Close()
+
+	var languages []language
+
+	for rows.Next() {
+		var l language
+		if err := rows.Scan(&l.code, &l.name, &l.nativeName); err != nil {
+			return nil, fmt.Errorf("error scanning languages, %v", err)
+		}
+		languages = append(languages, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting languages, %v", err)
+	}
+
+	return languages, nil
+}
+
+func (s *server) createLanguage(ctx context.Context, l *language) error {
+	query :=
+		`
+			INSERT INTO languages (code, name, native_name) VALUES ($1, $2, $3);
+		`
+
+	if _, err := s.store.ExecContext(ctx, query, l.code, l.name, l.nativeName); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return errLanguageExists
+		}
+		return fmt.Errorf("error inserting language, %v", err)
+	}
+
+	return nil
+}
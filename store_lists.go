@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errListNotFound     = errors.New("list not found")
+	errListBookNotFound = errors.New("book is not in this list")
+	errCollectionBook   = errors.New("collections can only hold your own books")
+)
+
+func (s *server) createList(ctx context.Context, l *bookList) (string, error) {
+	var id string
+
+	query :=
+		`
+			INSERT INTO book_lists (owner_id, kind, name, description, public) VALUES ($1, $2, $3, $4, $5) RETURNING id;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, l.ownerID, l.kind, l.name, l.description, l.public).Scan(&id); err != nil {
+		return "", fmt.Errorf("error inserting list, %v", err)
+	}
+
+	return id, nil
+}
+
+// updateList changes whichever of name, description and public are set
+func (s *server) updateList(ctx context.Context, ownerID, listID string, name, description *string, public *bool) error {
+	query :=
+		`
+			UPDATE book_lists SET
+				name = COALESCE($3, name),
+				description = COALESCE($4, description),
+				public = COALESCE($5, public),
+				updated_at = NOW()
+			WHERE id = $1 AND owner_id = $2;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, listID, ownerID, name, description, public)
+	if err != nil {
+		return fmt.Errorf("error updating list, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errListNotFound
+	}
+
+	return nil
+}
+
+func (s *server) deleteList(ctx context.Context, ownerID, listID string) error {
+	query :=
+		`
+			DELETE FROM book_lists WHERE id = $1 AND owner_id = $2;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, listID, ownerID)
+	if err != nil {
+		return fmt.Errorf("error deleting list, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errListNotFound
+	}
+
+	return nil
+}
+
+// addBookToList puts a published book at the end of the list. Collections only
+// take books written by the list's owner. Adding a book twice keeps its place.
+func (s *server) addBookToList(ctx context.Context, ownerID, listID, bookID string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var kind string
+
+	query :=
+		`
+			SELECT kind FROM book_lists WHERE id = $1 AND owner_id = $2 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, listID, ownerID).Scan(&kind); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errListNotFound
+		}
+		return fmt.Errorf("error getting list, %v", err)
+	}
+
+	var authorID sql.NullString
+
+	query =
+		`
+			SELECT author_id FROM books WHERE id = $1 AND approved = true AND hidden = false;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, bookID).Scan(&authorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errBookNotFound
+		}
+		return fmt.Errorf("error getting book, %v", err)
+	}
+
+	if kind == "collection" && authorID.String != ownerID {
+		return errCollectionBook
+	}
+
+	query =
+		`
+			INSERT INTO book_list_items (list_id, book_id, position)
+			SELECT $1, $2, COALESCE(MAX(position), 0) + 1 FROM book_list_items WHERE list_id = $1
+			ON CONFLICT (list_id, book_id) DO NOTHING;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, listID, bookID); err != nil {
+		return fmt.Errorf("error adding book to list, %v", err)
+	}
+
+	query =
+		`
+			UPDATE book_lists SET updated_at = NOW() WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, listID); err != nil {
+		return fmt.Errorf("error updating list, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) removeBookFromList(ctx context.Context, ownerID, listID, bookID string) error {
+	query :=
+		`
+			DELETE FROM book_list_items li
+			USING book_lists l
+			WHERE l.id = li.list_id AND li.list_id = $1 AND li.book_id = $2 AND l.owner_id = $3;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, listID, bookID, ownerID)
+	if err != nil {
+		return fmt.Errorf("error removing book from list, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errListBookNotFound
+	}
+
+	return nil
+}
+
+// getList returns a public list with the books the reader can see
+func (s *server) getList(ctx context.Context, listID string, filter contentFilter) (*bookList, error) {
+	lists, err := s.getLists(ctx, filter, []string{"l.id = $1", "l.public = true"}, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lists) == 0 {
+		return nil, errListNotFound
+	}
+
+	return &lists[0], nil
+}
+
+// getUserLists returns the user's lists, private ones included when they are
+// the one asking
+func (s *server) getUserLists(ctx context.Context, userID string, includePrivate bool, filter contentFilter) ([]bookList, error) {
+	where := []string{"l.owner_id = $1"}
+	if !includePrivate {
+		where = append(where, "l.public = true")
+	}
+
+	return s.getLists(ctx, filter, where, userID)
+}
+
+// getLists returns the lists matching where with the published books the
+// reader can see in order
+func (s *server) getLists(ctx context.Context, filter contentFilter, where []string, args ...any) ([]bookList, error) {
+	query := fmt.Sprintf(
+		`
+			SELECT l.id, l.owner_id, u.display_name, l.kind, l.name, l.description, l.public, l.created_at, l.updated_at
+			FROM book_lists l
+			JOIN users u ON (u.id = l.owner_id)
+			WHERE %s
+			ORDER BY l.updated_at DESC;
+		`, strings.Join(where, " AND "))
+
+	rows, err := s.store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting lists, %v", err)
+	}
+	defer rows.Close()
+
+	var lists []bookList
+	var listIDs []string
+
+	for rows.Next() {
+		var l bookList
+		if err := rows.Scan(&l.id, &l.ownerID, &l.ownerName, &l.kind, &l.name, &l.description, &l.public, &l.createdAt, &l.updatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning lists, %v", err)
+		}
+		lists = append(lists, l)
+		listIDs = append(listIDs, l.id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting lists, %v", err)
+	}
+
+	if len(lists) == 0 {
+		return lists, nil
+	}
+
+	query =
+		`
+			SELECT li.list_id, b.id, b.name, b.description, b.image, b.views, b.rating
+			FROM book_list_items li
+			JOIN books b ON (b.id = li.book_id)
+			WHERE li.list_id = ANY($1) AND b.approved = true AND b.hidden = false
+			AND b.content_rating = ANY($2) AND NOT (b.content_warnings && $3)
+			ORDER BY li.position;
+		`
+
+	bookRows, err := s.store.QueryContext(ctx, query, pq.Array(listIDs), pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings))
+	if err != nil {
+		return nil, fmt.Errorf("error getting list books, %v", err)
+	}
+	defer bookRows.Close()
+
+	books := make(map[string][]book)
+
+	for bookRows.Next() {
+		var listID string
+		var b book
+		if err := bookRows.Scan(&listID, &b.id, &b.name, &b.description, &b.image, &b.views, &b.rating); err != nil {
+			return nil, fmt.Errorf("error scanning list books, %v", err)
+		}
+		books[listID] = append(books[listID], b)
+	}
+
+	if err := bookRows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting list books, %v", err)
+	}
+
+	for i := range lists {
+		lists[i].books = books[lists[i].id]
+	}
+
+	return lists, nil
+}
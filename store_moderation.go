@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errBookNotPending = errors.New("book is not pending review")
+	errBookClaimed    = errors.New("book is claimed by another admin")
+	errNotModerator   = errors.New("user can't moderate books")
+
+	errChapterNotFlagged = errors.New("chapter is not waiting for review")
+)
+
+const (
+	moderationPending  = "pending"
+	moderationApproved = "approved"
+	moderationRejected = "rejected"
+)
+
+type pendingBook struct {
+	id            string
+	name          string
+	authorID      sql.NullString
+	authorName    sql.NullString
+	language      string
+	genres        []string
+	chapterCount  int
+	claimedBy     sql.NullString
+	claimedByName sql.NullString
+	claimedUntil  sql.NullTime
+	// plagiarismOverlap is the highest overlap with an earlier book by
+	// another author
+	plagiarismOverlap sql.NullFloat64
+	createdAt         time.Time
+}
+
+type pendingBooksFilter struct {
+	languages []string
+	genres    []string
+	// claim is "mine", "unclaimed" or empty for every pending book
+	claim       string
+	moderatorID string
+	newestFirst bool
+	offset      int
+	limit       int
+}
+
+type chapterVerdict struct {
+	check   string
+	flagged bool
+	score   float64
+	details sql.NullString
+}
+
+// flaggedChapter is a chapter the moderation worker held back from publication
+type flaggedChapter struct {
+	id        string
+	bookID    string
+	bookName  string
+	authorID  sql.NullString
+	chapterNo int
+	title     string
+	verdicts  []chapterVerdict
+	createdAt time.Time
+}
+
+type moderationAction struct {
+	id            string
+	chapterID     sql.NullString
+	moderatorID   sql.NullString
+	moderatorName sql.NullString
+	action        string
+	assigneeID    sql.NullString
+	reason        sql.NullString
+	createdAt     time.Time
+}
+
+// getPendingBooks returns the moderation queue, oldest book first unless asked
+// otherwise. A claim only holds until claimed_until, after that the book is
+// reported as unclaimed.
+func (s *server) getPendingBooks(ctx context.Context, filter pendingBooksFilter) ([]pendingBook, error) {
+	where := []string{"b.moderation_status = 'pending'"}
+	args := []any{}
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.languages) > 0 {
+		where = append(where, fmt.Sprintf("b.language = ANY(%s)", arg(pq.Array(filter.languages))))
+	}
+
+	if len(filter.genres) > 0 {
+		where = append(where, fmt.Sprintf("EXISTS(SELECT 1 FROM books_genres bg JOIN genres g ON (g.id = bg.genre_id) WHERE bg.book_id = b.id AND g.genre::text = ANY(%s))", arg(pq.Array(filter.genres))))
+	}
+
+	switch filter.claim {
+	case "mine":
+		where = append(where, fmt.Sprintf("b.claimed_by = %s AND b.claimed_until > NOW()", arg(filter.moderatorID)))
+	case "unclaimed":
+		where = append(where, "(b.claimed_by IS NULL OR b.claimed_until <= NOW())")
+	}
+
+	order := "ASC"
+	if filter.newestFirst {
+		order = "DESC"
+	}
+
+	query := fmt.Sprintf(
+		`
+			SELECT
+				b.id,
+				b.name,
+				b.author_id,
+				u.display_name,
+				b.language,
+				ARRAY(SELECT g.genre::text FROM books_genres bg JOIN genres g ON (g.id = bg.genre_id) WHERE bg.book_id = b.id),
+				(SELECT COUNT(*) FROM chapters c WHERE c.book_id = b.id),
+				CASE WHEN b.claimed_until > NOW() THEN b.claimed_by END,
+				CASE WHEN b.claimed_until > NOW() THEN m.display_name END,
+				CASE WHEN b.claimed_until > NOW() THEN b.claimed_until END,
+				(SELECT MAX(pm.overlap) FROM plagiarism_matches pm WHERE pm.book_id = b.id),
+				b.created_at
+			FROM books b
+			LEFT JOIN users u ON (u.id = b.author_id)
+			LEFT JOIN users m ON (m.id = b.claimed_by)
+			WHERE %s
+			ORDER BY b.created_at %s
+			OFFSET %s LIMIT %s;
+		`, strings.Join(where, " AND "), order, arg(filter.offset), arg(filter.limit))
+
+	rows, err := s.store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pending books, %v", err)
+	}
+	defer rows.Close()
+
+	var books []pendingBook
+
+	for rows.Next() {
+		var book pendingBook
+
+		if err := rows.Scan(&book.id, &book.name, &book.authorID, &book.authorName, &book.language, pq.Array(&book.genres), &book.chapterCount, &book.claimedBy, &book.claimedByName, &book.claimedUntil, &book.plagiarismOverlap, &book.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning pending books, %v", err)
+		}
+
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// lockPendingBook locks the book for the rest of the transaction and returns
+// who holds a live claim on it, if anyone
+func lockPendingBook(ctx context.Context, tx *sql.Tx, bookID string) (string, error) {
+	var status string
+	var claimedBy sql.NullString
+
+	query :=
+		`
+			SELECT moderation_status, CASE WHEN claimed_until > NOW() THEN claimed_by END FROM books WHERE id = $1 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, bookID).Scan(&status, &claimedBy); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errBookNotFound
+		}
+		return "", fmt.Errorf("error getting book, %v", err)
+	}
+
+	if status != moderationPending {
+		return "", errBookNotPending
+	}
+
+	return claimedBy.String, nil
+}
+
+func recordModerationAction(ctx context.Context, tx *sql.Tx, bookID, moderatorID, action string, assigneeID, reason *string) error {
+	query :=
+		`
+			INSERT INTO moderation_actions (book_id, moderator_id, action, assignee_id, reason) VALUES ($1, $2, $3, $4, $5);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID, moderatorID, action, assigneeID, reason); err != nil {
+		return fmt.Errorf("error recording moderation action, %v", err)
+	}
+
+	return nil
+}
+
+// claimBook assigns the book to assigneeID for ttl. A moderator can take over
+// a book only when nobody else holds a live claim on it, but can hand their
+// own claim over to another moderator.
+func (s *server) claimBook(ctx context.Context, moderatorID, assigneeID, bookID string, ttl time.Duration) (time.Time, error) {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	if assigneeID != moderatorID {
+		var canModerate bool
+
+		query :=
+			`
+				SELECT roles && $2::role_type[] FROM users WHERE id = $1 AND deleted_at IS NULL;
+			`
+
+		if err := tx.QueryRowContext(ctx, query, assigneeID, pq.Array(rolesWith(permApproveBook))).Scan(&canModerate); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("error getting assignee, %v", err)
+		}
+
+		if !canModerate {
+			return time.Time{}, errNotModerator
+		}
+	}
+
+	claimedBy, err := lockPendingBook(ctx, tx, bookID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if claimedBy != "" && claimedBy != moderatorID && claimedBy != assigneeID {
+		return time.Time{}, errBookClaimed
+	}
+
+	var claimedUntil time.Time
+
+	query :=
+		`
+			UPDATE books SET claimed_by = $1, claimed_until = NOW() + make_interval(secs => $2) WHERE id = $3 RETURNING claimed_until;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, assigneeID, ttl.Seconds(), bookID).Scan(&claimedUntil); err != nil {
+		return time.Time{}, fmt.Errorf("error claiming book, %v", err)
+	}
+
+	if err := recordModerationAction(ctx, tx, bookID, moderatorID, "claim", &assigneeID, nil); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return claimedUntil, nil
+}
+
+func (s *server) releaseBook(ctx context.Context, moderatorID, bookID string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	claimedBy, err := lockPendingBook(ctx, tx, bookID)
+	if err != nil {
+		return err
+	}
+
+	if claimedBy != "" && claimedBy != moderatorID {
+		return errBookClaimed
+	}
+
+	query :=
+		`
+			UPDATE books SET claimed_by = NULL, claimed_until = NULL WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID); err != nil {
+		return fmt.Errorf("error releasing book, %v", err)
+	}
+
+	if err := recordModerationAction(ctx, tx, bookID, moderatorID, "release", nil, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// moderateBook approves or rejects a pending book, records why and leaves the
+// author a notification. It returns the author, who is empty when the author's
+// account is gone.
+func (s *server) moderateBook(ctx context.Context, moderatorID, bookID string, approve bool, reason string) (string, error) {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	claimedBy, err := lockPendingBook(ctx, tx, bookID)
+	if err != nil {
+		return "", err
+	}
+
+	if claimedBy != "" && claimedBy != moderatorID {
+		return "", errBookClaimed
+	}
+
+	status, action, verb := moderationApproved, "approve", "approved"
+	if !approve {
+		status, action, verb = moderationRejected, "reject", "rejected"
+	}
+
+	var name string
+	var authorID sql.NullString
+
+	query :=
+		`
+			UPDATE books SET approved = $1, moderation_status = $2, claimed_by = NULL, claimed_until = NULL WHERE id = $3 RETURNING name, author_id;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, approve, status, bookID).Scan(&name, &authorID); err != nil {
+		return "", fmt.Errorf("error moderating book, %v", err)
+	}
+
+	if err := recordModerationAction(ctx, tx, bookID, moderatorID, action, nil, &reason); err != nil {
+		return "", err
+	}
+
+	if authorID.Valid {
+		query =
+			`
+				INSERT INTO notifications (user_id, book_id, message) VALUES ($1, $2, $3);
+			`
+
+		if _, err := tx.ExecContext(ctx, query, authorID.String, bookID, fmt.Sprintf("%v was %s: %v", name, verb, reason)); err != nil {
+			return "", fmt.Errorf("error notifying author, %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return authorID.String, nil
+}
+
+func (s *server) getModerationHistory(ctx context.Context, bookID string) ([]moderationAction, error) {
+	var exists bool
+
+	query :=
+		`
+			SELECT EXISTS(SELECT 1 FROM books WHERE id = $1);
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking if book exists, %v", err)
+	}
+
+	if !exists {
+		return nil, errBookNotFound
+	}
+
+	query =
+		`
+			SELECT ma.id, ma.chapter_id, ma.moderator_id, u.display_name, ma.action, ma.assignee_id, ma.reason, ma.created_at
+			FROM moderation_actions ma
+			LEFT JOIN users u ON (u.id = ma.moderator_id)
+			WHERE ma.book_id = $1
+			ORDER BY ma.created_at DESC;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting moderation history, %v", err)
+	}
+	defer rows.Close()
+
+	var actions []moderationAction
+
+	for rows.Next() {
+		var action moderationAction
+
+		if err := rows.Scan(&action.id, &action.chapterID, &action.moderatorID, &action.moderatorName, &action.action, &action.assigneeID, &action.reason, &action.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning moderation history, %v", err)
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// getFlaggedChapters returns the chapters held for review, oldest first, along
+// with the verdicts of every check they went through
+func (s *server) getFlaggedChapters(ctx context.Context, offset, limit int) ([]flaggedChapter, error) {
+	query :=
+		`
+			SELECT c.id, c.book_id, b.name, b.author_id, c.chapter_no, c.title, c.created_at
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE c.moderation_status = 'flagged'
+			ORDER BY c.created_at
+			OFFSET $1 LIMIT $2;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting flagged chapters, %v", err)
+	}
+	defer rows.Close()
+
+	var chapters []flaggedChapter
+	var ids []string
+
+	for rows.Next() {
+		var ch flaggedChapter
+
+		if err := rows.Scan(&ch.id, &ch.bookID, &ch.bookName, &ch.authorID, &ch.chapterNo, &ch.title, &ch.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning flagged chapters, %v", err)
+		}
+
+		chapters = append(chapters, ch)
+		ids = append(ids, ch.id)
+	}
+
+	if len(ids) == 0 {
+		return chapters, nil
+	}
+
+	query =
+		`
+			SELECT chapter_id, check_name, flagged, score, details FROM chapter_verdicts WHERE chapter_id = ANY($1) ORDER BY created_at;
+		`
+
+	verdictRows, err := s.store.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error getting chapter verdicts, %v", err)
+	}
+	defer verdictRows.Close()
+
+	verdicts := map[string][]chapterVerdict{}
+
+	for verdictRows.Next() {
+		var chapterID string
+		var v chapterVerdict
+
+		if err := verdictRows.Scan(&chapterID, &v.check, &v.flagged, &v.score, &v.details); err != nil {
+			return nil, fmt.Errorf("error scanning chapter verdicts, %v", err)
+		}
+
+		verdicts[chapterID] = append(verdicts[chapterID], v)
+	}
+
+	for i := range chapters {
+		chapters[i].verdicts = verdicts[chapters[i].id]
+	}
+
+	return chapters, nil
+}
+
+// moderateChapter approves or rejects a chapter held for review and leaves the
+// author a notification
+func (s *server) moderateChapter(ctx context.Context, moderatorID, chapterID string, approve bool, reason string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var status, bookID, bookName string
+	var chapterNo int
+	var authorID sql.NullString
+
+	query :=
+		`
+			SELECT c.moderation_status, c.book_id, c.chapter_no, b.name, b.author_id
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE c.id = $1
+			FOR UPDATE OF c;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, chapterID).Scan(&status, &bookID, &chapterNo, &bookName, &authorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errChapterNotFound
+		}
+		return fmt.Errorf("error getting chapter, %v", err)
+	}
+
+	if status != "flagged" {
+		return errChapterNotFlagged
+	}
+
+	status, action, verb := moderationApproved, "approve", "approved"
+	if !approve {
+		status, action, verb = moderationRejected, "reject", "rejected"
+	}
+
+	query =
+		`
+			UPDATE chapters SET moderation_status = $1 WHERE id = $2;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, status, chapterID); err != nil {
+		return fmt.Errorf("error moderating chapter, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO moderation_actions (book_id, chapter_id, moderator_id, action, reason) VALUES ($1, $2, $3, $4, $5);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID, chapterID, moderatorID, action, reason); err != nil {
+		return fmt.Errorf("error recording moderation action, %v", err)
+	}
+
+	if authorID.Valid {
+		query =
+			`
+				INSERT INTO notifications (user_id, book_id, message) VALUES ($1, $2, $3);
+			`
+
+		if _, err := tx.ExecContext(ctx, query, authorID.String, bookID, fmt.Sprintf("%v chapter %v was %s: %v", bookName, chapterNo, verb, reason)); err != nil {
+			return fmt.Errorf("error notifying author, %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
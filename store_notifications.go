@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var errNotificationNotFound = errors.New("notification not found")
+
+func (s *server) markNotificationRead(ctx context.Context, userID, notificationID string) error {
+	query :=
+		`
+			UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("error marking notification as read, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errNotificationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type plagiarismMatch struct {
+	sourceBookID     string
+	sourceBookName   string
+	sourceAuthorID   sql.NullString
+	sourceAuthorName sql.NullString
+	shared           int
+	total            int
+	overlap          float64
+	chapters         []plagiarismChapterMatch
+	updatedAt        time.Time
+}
+
+// plagiarismChapterMatch is a pair of chapters sharing fingerprints
+type plagiarismChapterMatch struct {
+	chapterID       string
+	chapterNo       int
+	sourceChapterID string
+	sourceChapterNo int
+	shared          int
+}
+
+// getPlagiarismMatches returns the earlier books by other authors the book
+// overlaps with, most overlapping first, with the chapters that overlap
+func (s *server) getPlagiarismMatches(ctx context.Context, bookID string) ([]plagiarismMatch, error) {
+	query :=
+		`
+			SELECT m.source_book_id, b.name, b.author_id, u.display_name, m.shared, m.total, m.overlap, m.updated_at
+			FROM plagiarism_matches m
+			JOIN books b ON (b.id = m.source_book_id)
+			LEFT JOIN users u ON (u.id = b.author_id)
+			WHERE m.book_id = $1
+			ORDER BY m.overlap DESC;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting plagiarism matches, %v", err)
+	}
+	defer rows.Close()
+
+	var matches []plagiarismMatch
+	index := map[string]int{}
+
+	for rows.Next() {
+		var m plagiarismMatch
+		if err := rows.Scan(&m.sourceBookID, &m.sourceBookName, &m.sourceAuthorID, &m.sourceAuthorName, &m.shared, &m.total, &m.overlap, &m.updatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning plagiarism matches, %v", err)
+		}
+		index[m.sourceBookID] = len(matches)
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting plagiarism matches, %v", err)
+	}
+
+	if len(matches) == 0 {
+		return matches, nil
+	}
+
+	query =
+		`
+			SELECT mc.id, mc.chapter_no, sc.book_id, sc.id, sc.chapter_no, COUNT(DISTINCT mf.hash) AS shared
+			FROM chapter_fingerprints mf
+			JOIN chapters mc ON (mc.id = mf.chapter_id)
+			JOIN chapter_fingerprints sf ON (sf.hash = mf.hash)
+			JOIN chapters sc ON (sc.id = sf.chapter_id AND sc.created_at < mc.created_at)
+			JOIN plagiarism_matches m ON (m.book_id = mc.book_id AND m.source_book_id = sc.book_id)
+			WHERE mc.book_id = $1
+			GROUP BY mc.id, mc.chapter_no, sc.book_id, sc.id, sc.chapter_no
+			ORDER BY shared DESC, mc.chapter_no;
+		`
+
+	chapterRows, err := s.store.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting plagiarised chapters, %v", err)
+	}
+	defer chapterRows.Close()
+
+	for chapterRows.Next() {
+		var c plagiarismChapterMatch
+		var sourceBookID string
+		if err := chapterRows.Scan(&c.chapterID, &c.chapterNo, &sourceBookID, &c.sourceChapterID, &c.sourceChapterNo, &c.shared); err != nil {
+			return nil, fmt.Errorf("error scanning plagiarised chapters, %v", err)
+		}
+		if i, ok := index[sourceBookID]; ok {
+			matches[i].chapters = append(matches[i].chapters, c)
+		}
+	}
+
+	if err := chapterRows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting plagiarised chapters, %v", err)
+	}
+
+	return matches, nil
+}
+
+// getPlagiarismOverlap returns the highest overlap of the book with an earlier
+// book by another author, it isn't valid when the book doesn't overlap any
+func (s *server) getPlagiarismOverlap(ctx context.Context, bookID string) (sql.NullFloat64, error) {
+	var overlap sql.NullFloat64
+
+	query :=
+		`
+			SELECT MAX(overlap) FROM plagiarism_matches WHERE book_id = $1;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, bookID).Scan(&overlap); err != nil {
+		return overlap, fmt.Errorf("error getting plagiarism overlap, %v", err)
+	}
+
+	return overlap, nil
+}
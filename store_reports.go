@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	errReportTargetNotFound = errors.New("reported content not found")
+	errAlreadyReported      = errors.New("content already reported")
+	errReportNotFound       = errors.New("report not found")
+	errReportResolved       = errors.New("report is already resolved")
+)
+
+const (
+	reportOpen      = "open"
+	reportDismissed = "dismissed"
+	reportUpheld    = "upheld"
+)
+
+// reportTargets maps what can be reported to the table holding it. Every one
+// of these tables has a hidden column. Comments are next, they need a row in
+// report_target_types as well.
+var reportTargets = map[string]string{
+	"book":    "books",
+	"chapter": "chapters",
+	"user":    "users",
+}
+
+type report struct {
+	id           string
+	reporterID   sql.NullString
+	reporterName sql.NullString
+	targetType   string
+	targetID     string
+	category     string
+	details      sql.NullString
+	status       string
+	resolvedBy   sql.NullString
+	resolution   sql.NullString
+	resolvedAt   sql.NullTime
+	createdAt    time.Time
+	// openReports and targetHidden describe the reported content rather than
+	// the report itself
+	openReports  int
+	targetHidden bool
+}
+
+type reportsFilter struct {
+	status     string
+	targetType string
+	offset     int
+	limit      int
+}
+
+// createReport files a report and hides the reported content once threshold
+// reporters whose accounts are at least minAccountAge old have open reports on
+// it. A reporter can only have one open report on the same content. It returns
+// the number of open reports on the content and whether it is hidden.
+func (s *server) createReport(ctx context.Context, r *report, threshold int, minAccountAge time.Duration) (int, bool, error) {
+	table := reportTargets[r.targetType]
+
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+
+	query := fmt.Sprintf(
+		`
+			SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1);
+		`, table)
+
+	if err := tx.QueryRowContext(ctx, query, r.targetID).Scan(&exists); err != nil {
+		return 0, false, fmt.Errorf("error checking if reported content exists, %v", err)
+	}
+
+	if !exists {
+		return 0, false, errReportTargetNotFound
+	}
+
+	query =
+		`
+			INSERT INTO reports (reporter_id, target_type, target_id, category, details) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (reporter_id, target_type, target_id) WHERE status = 'open' DO NOTHING
+			RETURNING id, created_at;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, r.reporterID, r.targetType, r.targetID, r.category, r.details).Scan(&r.id, &r.createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, errAlreadyReported
+		}
+		return 0, false, fmt.Errorf("error inserting into reports table, %v", err)
+	}
+
+	var openReports, trustedReports int
+
+	query =
+		`
+			SELECT COUNT(*), COUNT(*) FILTER (WHERE u.created_at <= NOW() - make_interval(secs => $3))
+			FROM reports r
+			LEFT JOIN users u ON (u.id = r.reporter_id)
+			WHERE r.target_type = $1 AND r.target_id = $2 AND r.status = 'open';
+		`
+
+	if err := tx.QueryRowContext(ctx, query, r.targetType, r.targetID, minAccountAge.Seconds()).Scan(&openReports, &trustedReports); err != nil {
+		return 0, false, fmt.Errorf("error counting reports, %v", err)
+	}
+
+	var hidden bool
+
+	query = fmt.Sprintf(
+		`
+			UPDATE %s SET hidden = hidden OR $1 WHERE id = $2 RETURNING hidden;
+		`, table)
+
+	if err := tx.QueryRowContext(ctx, query, trustedReports >= threshold, r.targetID).Scan(&hidden); err != nil {
+		return 0, false, fmt.Errorf("error hiding reported content, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return openReports, hidden, nil
+}
+
+// getReports returns reports for triage, the most reported content first
+func (s *server) getReports(ctx context.Context, filter reportsFilter) ([]report, error) {
+	where := []string{"r.status = $1"}
+	args := []any{filter.status}
+
+	if filter.targetType != "" {
+		args = append(args, filter.targetType)
+		where = append(where, fmt.Sprintf("r.target_type = $%d", len(args)))
+	}
+
+	query := fmt.Sprintf(
+		`
+			SELECT
+				r.id,
+				r.reporter_id,
+				u.display_name,
+				r.target_type,
+				r.target_id,
+				r.category,
+				r.details,
+				r.status,
+				r.resolved_by,
+				r.resolution,
+				r.resolved_at,
+				r.created_at,
+				(SELECT COUNT(*) FROM reports o WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status = 'open') AS open_reports,
+				CASE r.target_type
+					WHEN 'book' THEN (SELECT hidden FROM books WHERE id = r.target_id)
+					WHEN 'chapter' THEN (SELECT hidden FROM chapters WHERE id = r.target_id)
+					WHEN 'user' THEN (SELECT hidden FROM users WHERE id = r.target_id)
+				END
+			FROM reports r
+			LEFT JOIN users u ON (u.id = r.reporter_id)
+			WHERE %s
+			ORDER BY open_reports DESC, r.target_id, r.created_at
+			OFFSET $%d LIMIT $%d;
+		`, strings.Join(where, " AND "), len(args)+1, len(args)+2)
+
+	rows, err := s.store.QueryContext(ctx, query, append(args, filter.offset, filter.limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting reports, %v", err)
+	}
+	defer rows.Close()
+
+	var reports []report
+
+	for rows.Next() {
+		var r report
+		var hidden sql.NullBool
+
+		if err := rows.Scan(&r.id, &r.reporterID, &r.reporterName, &r.targetType, &r.targetID, &r.category, &r.details, &r.status, &r.resolvedBy, &r.resolution, &r.resolvedAt, &r.createdAt, &r.openReports, &hidden); err != nil {
+			return nil, fmt.Errorf("error scanning reports, %v", err)
+		}
+
+		r.targetHidden = hidden.Bool
+		reports = append(reports, r)
+	}
+
+	return reports, nil
+}
+
+// resolveReport settles every open report on the same content as the report.
+// Upholding keeps the content hidden, dismissing puts back content that was
+// hidden while it waited for review. It returns how many reports were resolved.
+func (s *server) resolveReport(ctx context.Context, moderatorID, reportID, status, resolution string) (int, error) {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var targetType, targetID, currentStatus string
+
+	query :=
+		`
+			SELECT target_type, target_id, status FROM reports WHERE id = $1 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, reportID).Scan(&targetType, &targetID, &currentStatus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errReportNotFound
+		}
+		return 0, fmt.Errorf("error getting report, %v", err)
+	}
+
+	if currentStatus != reportOpen {
+		return 0, errReportResolved
+	}
+
+	query =
+		`
+			UPDATE reports SET status = $1, resolved_by = $2, resolution = $3, resolved_at = NOW()
+			WHERE target_type = $4 AND target_id = $5 AND status = 'open';
+		`
+
+	result, err := tx.ExecContext(ctx, query, status, moderatorID, resolution, targetType, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("error resolving reports, %v", err)
+	}
+
+	resolved, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error resolving reports, %v", err)
+	}
+
+	query = fmt.Sprintf(
+		`
+			UPDATE %s SET hidden = $1 WHERE id = $2;
+		`, reportTargets[targetType])
+
+	if _, err := tx.ExecContext(ctx, query, status == reportUpheld, targetID); err != nil {
+		return 0, fmt.Errorf("error updating reported content, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return int(resolved), nil
+}
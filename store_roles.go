@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	errRoleAlreadyGranted = errors.New("user already has this role")
+	errRoleNotGranted     = errors.New("user doesn't have this role")
+	errRevokeOwnAdmin     = errors.New("admins can't revoke their own admin role")
+)
+
+type roleChange struct {
+	id            string
+	role          string
+	action        string
+	changedBy     sql.NullString
+	changedByName sql.NullString
+	reason        sql.NullString
+	createdAt     time.Time
+}
+
+// changeRole grants or revokes role and records who did it. Granting a role the
+// user has, or revoking one they don't, is an error so the audit trail only
+// holds real changes.
+func (s *server) changeRole(ctx context.Context, adminID, userID, role string, grant bool, reason string) error {
+	if !grant && role == "ADMIN" && adminID == userID {
+		return errRevokeOwnAdmin
+	}
+
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var has bool
+
+	query :=
+		`
+			SELECT $2::role_type = ANY(roles) FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, userID, role).Scan(&has); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errUserNotFound
+		}
+		return fmt.Errorf("error getting user roles, %v", err)
+	}
+
+	action := "grant"
+	query =
+		`
+			UPDATE users SET roles = array_append(roles, $2::role_type) WHERE id = $1;
+		`
+
+	switch {
+	case grant && has:
+		return errRoleAlreadyGranted
+	case !grant && !has:
+		return errRoleNotGranted
+	case !grant:
+		action = "revoke"
+		query =
+			`
+				UPDATE users SET roles = array_remove(roles, $2::role_type) WHERE id = $1;
+			`
+	}
+
+	if _, err := tx.ExecContext(ctx, query, userID, role); err != nil {
+		return fmt.Errorf("error updating user roles, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO role_changes (user_id, role, action, changed_by, reason) VALUES ($1, $2, $3, $4, NULLIF($5, ''));
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID, role, action, adminID, reason); err != nil {
+		return fmt.Errorf("error inserting role change, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// getRoleChanges returns every role granted to or revoked from the user,
+// newest first
+func (s *server) getRoleChanges(ctx context.Context, userID string) ([]roleChange, error) {
+	query :=
+		`
+			SELECT rc.id, rc.role, rc.action, rc.changed_by, u.display_name, rc.reason, rc.created_at
+			FROM role_changes rc
+			LEFT JOIN users u ON (u.id = rc.changed_by)
+			WHERE rc.user_id = $1
+			ORDER BY rc.created_at DESC;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting role changes, %v", err)
+	}
+	defer rows.Close()
+
+	var changes []roleChange
+
+	for rows.Next() {
+		var c roleChange
+		if err := rows.Scan(&c.id, &c.role, &c.action, &c.changedBy, &c.changedByName, &c.reason, &c.createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning role changes, %v", err)
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting role changes, %v", err)
+	}
+
+	return changes, nil
+}
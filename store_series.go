@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errSeriesNotFound = errors.New("series not found")
+	errSeriesExists   = errors.New("you already have a series with this name")
+	errBookInSeries   = errors.New("a book can only be in one series")
+)
+
+// setSeriesVolumes makes bookIDs the volumes of the series, in order. Every
+// book has to be written by authorID.
+func setSeriesVolumes(ctx context.Context, tx *sql.Tx, authorID, seriesID string, bookIDs []string) error {
+	var owned int
+
+	query :=
+		`
+			SELECT COUNT(*) FROM books WHERE id = ANY($1) AND author_id = $2;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, pq.Array(bookIDs), authorID).Scan(&owned); err != nil {
+		return fmt.Errorf("error checking if books exist, %v", err)
+	}
+
+	if owned != len(bookIDs) {
+		return errBookNotFound
+	}
+
+	query =
+		`
+			DELETE FROM series_volumes WHERE series_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, seriesID); err != nil {
+		return fmt.Errorf("error removing series volumes, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO series_volumes (series_id, book_id, volume)
+			SELECT $1, v.book_id, v.volume
+			FROM unnest($2::uuid[]) WITH ORDINALITY AS v(book_id, volume);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, seriesID, pq.Array(bookIDs)); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return errBookInSeries
+		}
+		return fmt.Errorf("error inserting series volumes, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) createSeries(ctx context.Context, sr *series, bookIDs []string) (string, error) {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var id string
+
+	query :=
+		`
+			INSERT INTO series (author_id, name, description) VALUES ($1, $2, $3) RETURNING id;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, sr.authorID, sr.name, sr.description).Scan(&id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return "", errSeriesExists
+		}
+		return "", fmt.Errorf("error inserting series, %v", err)
+	}
+
+	if err := setSeriesVolumes(ctx, tx, sr.authorID, id, bookIDs); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return id, nil
+}
+
+// updateSeries renames the series or changes its description when they are
+// set, and replaces its volumes when bookIDs isn't nil
+func (s *server) updateSeries(ctx context.Context, authorID, seriesID string, name, description *string, bookIDs []string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+			UPDATE series SET
+				name = COALESCE($3, name),
+				description = COALESCE($4, description),
+				updated_at = NOW()
+			WHERE id = $1 AND author_id = $2;
+		`
+
+	results, err := tx.ExecContext(ctx, query, seriesID, authorID, name, description)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return errSeriesExists
+		}
+		return fmt.Errorf("error updating series, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errSeriesNotFound
+	}
+
+	if bookIDs != nil {
+		if err := setSeriesVolumes(ctx, tx, authorID, seriesID, bookIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) deleteSeries(ctx context.Context, authorID, seriesID string) error {
+	query :=
+		`
+			DELETE FROM series WHERE id = $1 AND author_id = $2;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, seriesID, authorID)
+	if err != nil {
+		return fmt.Errorf("error deleting series, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errSeriesNotFound
+	}
+
+	return nil
+}
+
+// getSeries returns the series with its published volumes the reader can see
+// in order
+func (s *server) getSeries(ctx context.Context, seriesID string, filter contentFilter) (*series, error) {
+	var sr series
+
+	query :=
+		`
+			SELECT s.id, s.author_id, u.display_name, s.name, s.description, s.created_at
+			FROM series s
+			JOIN users u ON (u.id = s.author_id)
+			WHERE s.id = $1;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, seriesID).Scan(&sr.id, &sr.authorID, &sr.authorName, &sr.name, &sr.description, &sr.createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errSeriesNotFound
+		}
+		return nil, fmt.Errorf("error getting series, %v", err)
+	}
+
+	query =
+		`
+			SELECT b.id, b.name, b.description, b.image, b.views, b.rating, sv.volume
+			FROM series_volumes sv
+			JOIN books b ON (b.id = sv.book_id)
+			WHERE sv.series_id = $1 AND b.approved = true AND b.hidden = false
+			AND b.content_rating = ANY($2) AND NOT (b.content_warnings && $3)
+			ORDER BY sv.volume;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, seriesID, pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings))
+	if err != nil {
+		return nil, fmt.Errorf("error getting series volumes, %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b := book{series: &seriesVolume{seriesID: sr.id, seriesName: sr.name}}
+		if err := rows.Scan(&b.id, &b.name, &b.description, &b.image, &b.views, &b.rating, &b.series.volume); err != nil {
+			return nil, fmt.Errorf("error scanning series volumes, %v", err)
+		}
+		sr.books = append(sr.books, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting series volumes, %v", err)
+	}
+
+	for _, b := range sr.books {
+		b.series.volumes = len(sr.books)
+	}
+
+	return &sr, nil
+}
+
+// getBooksSeries returns the series each of the books is a volume of. Only
+// published volumes are counted.
+func (s *server) getBooksSeries(ctx context.Context, bookIDs []string) (map[string]*seriesVolume, error) {
+	query :=
+		`
+			SELECT
+				sv.book_id,
+				s.id,
+				s.name,
+				sv.volume,
+				(
+					SELECT COUNT(*) FROM series_volumes v
+					JOIN books b ON (b.id = v.book_id)
+					WHERE v.series_id = s.id AND b.approved = true AND b.hidden = false
+				)
+			FROM series_volumes sv
+			JOIN series s ON (s.id = sv.series_id)
+			WHERE sv.book_id = ANY($1);
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error getting series, %v", err)
+	}
+	defer rows.Close()
+
+	volumes := make(map[string]*seriesVolume)
+
+	for rows.Next() {
+		var bookID string
+		var sv seriesVolume
+		if err := rows.Scan(&bookID, &sv.seriesID, &sv.seriesName, &sv.volume, &sv.volumes); err != nil {
+			return nil, fmt.Errorf("error scanning series, %v", err)
+		}
+		volumes[bookID] = &sv
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting series, %v", err)
+	}
+
+	return volumes, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errTagNotFound        = errors.New("tag not found")
+	errTagIsSynonym       = errors.New("tag is a synonym of another tag")
+	errTagSynonymOfItself = errors.New("a tag can't be a synonym of itself")
+	errNoBooksUnderTags   = errors.New("no books under tags")
+)
+
+// setBookTags replaces the book's tags with the normalized tags given. Tags
+// nobody used before are created, synonyms are swapped for their canonical tag.
+func setBookTags(ctx context.Context, tx *sql.Tx, bookID string, tags []string) error {
+	query :=
+		`
+			DELETE FROM books_tags WHERE book_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID); err != nil {
+		return fmt.Errorf("error deleting book tags, %v", err)
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query =
+		`
+			INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, pq.Array(tags)); err != nil {
+		return fmt.Errorf("error inserting tags, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO books_tags (book_id, tag_id)
+			SELECT $1, COALESCE(canonical_id, id) FROM tags WHERE name = ANY($2)
+			ON CONFLICT DO NOTHING;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("error inserting book tags, %v", err)
+	}
+
+	return nil
+}
+
+// getBooksTags returns the names of each book's tags
+func (s *server) getBooksTags(ctx context.Context, bookIDs []string) (map[string][]string, error) {
+	query :=
+		`
+			SELECT bt.book_id, t.name
+			FROM books_tags bt
+			JOIN tags t ON (t.id = bt.tag_id)
+			WHERE bt.book_id = ANY($1)
+			ORDER BY t.canonical DESC, t.name;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error getting tags, %v", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+
+	for rows.Next() {
+		var bookID, name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return nil, fmt.Errorf("error scanning tags, %v", err)
+		}
+		tags[bookID] = append(tags[bookID], name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting tags, %v", err)
+	}
+
+	return tags, nil
+}
+
+// searchTags returns the tags starting with prefix, or with a synonym starting
+// with it. Canonical tags come first, then the most used.
+func (s *server) searchTags(ctx context.Context, prefix string, limit int) ([]tag, error) {
+	query :=
+		`
+			SELECT
+				t.id,
+				t.name,
+				t.canonical,
+				ARRAY(SELECT name FROM tags WHERE canonical_id = t.id ORDER BY name),
+				(SELECT COUNT(*) FROM books_tags WHERE tag_id = t.id) AS books
+			FROM tags t
+			WHERE t.canonical_id IS NULL
+			AND (t.name LIKE $1 OR EXISTS(SELECT 1 FROM tags s WHERE s.canonical_id = t.id AND s.name LIKE $1))
+			ORDER BY t.canonical DESC, books DESC, t.name
+			LIMIT $2;
+		`
+
+	rows, err := s.store.QueryContext(ctx, query, prefix+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error searching tags, %v", err)
+	}
+	defer rows.Close()
+
+	var tags []tag
+
+	for rows.Next() {
+		var t tag
+		if err := rows.Scan(&t.id, &t.name, &t.canonical, pq.Array(&t.synonyms), &t.books); err != nil {
+			return nil, fmt.Errorf("error scanning tags, %v", err)
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error searching tags, %v", err)
+	}
+
+	return tags, nil
+}
+
+// createCanonicalTag creates the tag, or marks it canonical when it already
+// exists
+func (s *server) createCanonicalTag(ctx context.Context, name string) (string, error) {
+	var id string
+
+	query :=
+		`
+			INSERT INTO tags (name, canonical) VALUES ($1, true)
+			ON CONFLICT (name) DO UPDATE SET canonical = true WHERE tags.canonical_id IS NULL
+			RETURNING id;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, name).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errTagIsSynonym
+		}
+		return "", fmt.Errorf("error inserting tag, %v", err)
+	}
+
+	return id, nil
+}
+
+// addTagSynonym makes name a synonym of the tag. Books tagged with name, or
+// with its own synonyms, get the tag instead.
+func (s *server) addTagSynonym(ctx context.Context, tagID, name string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var canonicalID sql.NullString
+
+	query :=
+		`
+			SELECT canonical_id FROM tags WHERE id = $1 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, tagID).Scan(&canonicalID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errTagNotFound
+		}
+		return fmt.Errorf("error getting tag, %v", err)
+	}
+
+	if canonicalID.Valid {
+		return errTagIsSynonym
+	}
+
+	var synonymID string
+
+	query =
+		`
+			INSERT INTO tags (name) VALUES ($1)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, name).Scan(&synonymID); err != nil {
+		return fmt.Errorf("error inserting tag, %v", err)
+	}
+
+	if synonymID == tagID {
+		return errTagSynonymOfItself
+	}
+
+	query =
+		`
+			INSERT INTO books_tags (book_id, tag_id)
+			SELECT book_id, $1 FROM books_tags WHERE tag_id = $2
+			ON CONFLICT DO NOTHING;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, tagID, synonymID); err != nil {
+		return fmt.Errorf("error moving book tags, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM books_tags WHERE tag_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, synonymID); err != nil {
+		return fmt.Errorf("error deleting book tags, %v", err)
+	}
+
+	query =
+		`
+			UPDATE tags SET canonical_id = $1, canonical = false WHERE id = $2 OR canonical_id = $2;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, tagID, synonymID); err != nil {
+		return fmt.Errorf("error updating synonyms, %v", err)
+	}
+
+	query =
+		`
+			UPDATE tags SET canonical = true WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, tagID); err != nil {
+		return fmt.Errorf("error updating tag, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// removeTagSynonym makes the synonym a tag of its own again. Books already
+// moved to the canonical tag keep it.
+func (s *server) removeTagSynonym(ctx context.Context, tagID, synonym string) error {
+	query :=
+		`
+			UPDATE tags SET canonical_id = NULL WHERE name = $1 AND canonical_id = $2;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, synonym, tagID)
+	if err != nil {
+		return fmt.Errorf("error removing synonym, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errTagNotFound
+	}
+
+	return nil
+}
+
+// getBooksByTags returns the books with every one of the tags the filter lets
+// through, narrowed down by genre and language when they aren't empty
+func (s *server) getBooksByTags(ctx context.Context, tags, genres, languages []string, filter contentFilter, offset, limit int, sort, order string) ([]book, error) {
+	where := []string{"b.approved = true", "b.hidden = false"}
+	args := []any{}
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where = append(where, fmt.Sprintf("b.content_rating = ANY(%s)", arg(pq.Array(filter.ratings))), fmt.Sprintf("NOT (b.content_warnings && %s)", arg(pq.Array(filter.hiddenWarnings))))
+
+	for _, t := range tags {
+		where = append(where, fmt.Sprintf("EXISTS(SELECT 1 FROM books_tags bt JOIN tags t ON (bt.tag_id = COALESCE(t.canonical_id, t.id)) WHERE bt.book_id = b.id AND t.name = %s)", arg(t)))
+	}
+
+	if len(genres) > 0 {
+		where = append(where, fmt.Sprintf("EXISTS(SELECT 1 FROM books_genres bg JOIN genres g ON (g.id = bg.genre_id) WHERE bg.book_id = b.id AND g.genre = ANY(%s))", arg(pq.Array(genres))))
+	}
+
+	if len(languages) > 0 {
+		where = append(where, fmt.Sprintf("b.language = ANY(%s)", arg(pq.Array(languages))))
+	}
+
+	query := fmt.Sprintf(
+		`
+			SELECT
+				b.id,
+				b.name,
+				b.description,
+				b.image,
+				b.views,
+				b.rating,
+				b.content_rating,
+				b.content_warnings,
+				COUNT(c.id)
+			FROM books b
+			JOIN chapters c ON (b.id = c.book_id)
+			WHERE %s
+			GROUP BY b.id
+			ORDER BY %s %s
+			OFFSET %s LIMIT %s;
+		`, strings.Join(where, " AND "), helperSortField(sort), order, arg(offset), arg(limit))
+
+	books, err := s.helperGetBooks(ctx, query, errNoBooksUnderTags, helpersGetBooksRows, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
@@ -47,11 +47,19 @@ func createBook(t *testing.T, author_id string, db *sql.DB) string {
 	var id string
 	query :=
 		`
-			INSERT INTO books(name, description, author_id, approved) VALUES ('test book taken', 'test book description', $1, 'true') RETURNING id;
+			INSERT INTO books(name, description, author_id, approved, moderation_status) VALUES ('test book taken', 'test book description', $1, 'true', 'approved') RETURNING id;
 		`
 	if err := db.QueryRowContext(context.Background(), query, author_id).Scan(&id); err != nil {
 		t.Errorf("error creating new book, %v", err)
 	}
 
+	query =
+		`
+			UPDATE users SET roles = array_append(roles, 'AUTHOR') WHERE id = $1 AND NOT 'AUTHOR' = ANY(roles);
+		`
+	if _, err := db.ExecContext(context.Background(), query, author_id); err != nil {
+		t.Errorf("error making user an author, %v", err)
+	}
+
 	return id
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errSourceBookNotFound       = errors.New("source book not found")
+	errTranslationLanguage      = errors.New("a translation has to be in another language than its original")
+	errSourceChapterNotFound    = errors.New("source chapter not found")
+	errChapterAlreadyTranslated = errors.New("source chapter is already translated in this book")
+)
+
+// translation is a book in the same family as another, either the original
+// or one of its translations
+type translation struct {
+	id                 string
+	name               string
+	language           string
+	original           bool
+	translatedChapters int
+	originalChapters   int
+}
+
+// originalBook returns the original a translation in language should point
+// to. Translating a translation points at its original, so every translation
+// of a book shares the same source.
+func originalBook(ctx context.Context, tx *sql.Tx, sourceBookID, language string) (string, error) {
+	var originalID, originalLanguage string
+
+	query :=
+		`
+			SELECT o.id, o.language
+			FROM books b
+			JOIN books o ON (o.id = COALESCE(b.source_book_id, b.id))
+			WHERE b.id = $1 AND b.approved = true AND b.hidden = false;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, sourceBookID).Scan(&originalID, &originalLanguage); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errSourceBookNotFound
+		}
+		return "", fmt.Errorf("error getting source book, %v", err)
+	}
+
+	if originalLanguage == language {
+		return "", errTranslationLanguage
+	}
+
+	return originalID, nil
+}
+
+// checkSourceChapter makes sure sourceChapterID is a chapter of the book the
+// translation bookID was made from
+func checkSourceChapter(ctx context.Context, tx *sql.Tx, bookID, sourceChapterID string) error {
+	var exists bool
+
+	query :=
+		`
+			SELECT EXISTS(
+				SELECT 1 FROM chapters sc
+				JOIN books b ON (b.source_book_id = sc.book_id)
+				WHERE b.id = $1 AND sc.id = $2
+			);
+		`
+
+	if err := tx.QueryRowContext(ctx, query, bookID, sourceChapterID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking source chapter, %v", err)
+	}
+
+	if !exists {
+		return errSourceChapterNotFound
+	}
+
+	return nil
+}
+
+// isChapterAlreadyTranslated reports whether err is the translation already
+// having a chapter mapped to the same source chapter
+func isChapterAlreadyTranslated(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_chapters_source_chapter_id"
+}
+
+// getTranslations returns the other published books in the book's family the
+// reader can see, the original first, with how many of the original's
+// chapters each one has translated. languages narrows them down when it isn't
+// empty.
+func (s *server) getTranslations(ctx context.Context, bookID string, languages []string, filter contentFilter) ([]translation, error) {
+	where := []string{"(b.id = o.id OR b.source_book_id = o.id)", "b.id <> $1", "b.approved = true", "b.hidden = false", "b.content_rating = ANY($2)", "NOT (b.content_warnings && $3)"}
+	args := []any{bookID, pq.Array(filter.ratings), pq.Array(filter.hiddenWarnings)}
+
+	if len(languages) > 0 {
+		args = append(args, pq.Array(languages))
+		where = append(where, fmt.Sprintf("b.language = ANY($%d)", len(args)))
+	}
+
+	query := fmt.Sprintf(
+		`
+			WITH o AS (
+				SELECT COALESCE(source_book_id, id) AS id FROM books WHERE id = $1
+			), published AS (
+				SELECT c.id FROM chapters c, o
+				WHERE c.book_id = o.id AND c.hidden = false AND c.moderation_status = 'approved'
+			)
+			SELECT
+				b.id,
+				b.name,
+				b.language,
+				b.id = o.id,
+				CASE WHEN b.id = o.id THEN (SELECT COUNT(*) FROM published)
+				ELSE (
+					SELECT COUNT(DISTINCT c.source_chapter_id) FROM chapters c
+					WHERE c.book_id = b.id AND c.hidden = false AND c.moderation_status = 'approved'
+					AND c.source_chapter_id IN (SELECT id FROM published)
+				) END,
+				(SELECT COUNT(*) FROM published)
+			FROM books b, o
+			WHERE %s
+			ORDER BY b.id = o.id DESC, b.language;
+		`, strings.Join(where, " AND "))
+
+	rows, err := s.store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting translations, %v", err)
+	}
+	defer rows.Close()
+
+	var translations []translation
+
+	for rows.Next() {
+		var t translation
+		if err := rows.Scan(&t.id, &t.name, &t.language, &t.original, &t.translatedChapters, &t.originalChapters); err != nil {
+			return nil, fmt.Errorf("error scanning translations, %v", err)
+		}
+		translations = append(translations, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting translations, %v", err)
+	}
+
+	return translations, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	errTwoFactorEnabled     = errors.New("two factor authentication already enabled")
+	errTwoFactorNotEnabled  = errors.New("two factor authentication not enabled")
+	errTwoFactorNotEnrolled = errors.New("two factor authentication enrollment not started")
+	errInvalidTwoFactorCode = errors.New("invalid two factor code")
+)
+
+type twoFactor struct {
+	secret  sql.NullString
+	enabled bool
+}
+
+func (s *server) getTwoFactor(ctx context.Context, userID string) (*twoFactor, error) {
+	var tf twoFactor
+	query :=
+		`
+			SELECT totp_secret, totp_enabled FROM users WHERE id = $1;
+		`
+	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&tf.secret, &tf.enabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("error getting two factor settings, %v", err)
+	}
+	return &tf, nil
+}
+
+func (s *server) setTOTPSecret(ctx context.Context, userID, secret string) error {
+	query :=
+		`
+			UPDATE users SET totp_secret = $1, totp_last_step = NULL WHERE id = $2 AND totp_enabled = false;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("error setting totp secret, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errTwoFactorEnabled
+	}
+
+	return nil
+}
+
+// useTOTPStep records the time step of an accepted code, failing if that
+// step, or a later one, has already been used
+func (s *server) useTOTPStep(ctx context.Context, userID string, step int64) error {
+	query :=
+		`
+			UPDATE users SET totp_last_step = $1 WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1);
+		`
+
+	results, err := s.store.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return fmt.Errorf("error updating totp step, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (s *server) enableTwoFactor(ctx context.Context, userID string, recoveryCodes []string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+			UPDATE users SET totp_enabled = true WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error enabling two factor authentication, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM recovery_codes WHERE user_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes, %v", err)
+	}
+
+	var values []string
+	var args []any
+	index := 1
+
+	for _, code := range recoveryCodes {
+		values = append(values, fmt.Sprintf("($%d, $%d)", index, index+1))
+		args = append(args, userID, hashRecoveryCode(code))
+		index += 2
+	}
+
+	query = fmt.Sprintf("INSERT INTO recovery_codes (user_id, code_hash) VALUES %s;", strings.Join(values, ","))
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error inserting recovery codes, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) disableTwoFactor(ctx context.Context, userID string) error {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+			UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = NULL WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error disabling two factor authentication, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM recovery_codes WHERE user_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+func (s *server) useRecoveryCode(ctx context.Context, userID, code string) error {
+	query :=
+		`
+			UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("error using recovery code, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// verifySecondFactor accepts either a current totp code or an unused recovery code
+func (s *server) verifySecondFactor(ctx context.Context, userID string, tf *twoFactor, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		return s.useRecoveryCode(ctx, userID, recoveryCode)
+	}
+
+	step, ok := validateTOTP(tf.secret.String, code, time.Now())
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	return s.useTOTPStep(ctx, userID, step)
+}
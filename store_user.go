@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
 
+var errAccountDeleted = errors.New("account already scheduled for deletion")
+
 func (s *server) getUser(ctx context.Context, id string) (*user, error) {
 	var user user
 	query :=
@@ -16,14 +22,408 @@ func (s *server) getUser(ctx context.Context, id string) (*user, error) {
 				display_name, 
 				image, 
 				about, 
-				roles 
+				roles,
+				totp_enabled
 			FROM users 
 			WHERE id = $1;
 		`
 
-	if err := s.store.QueryRowContext(ctx, query, id).Scan(&user.email, &user.displayName, &user.image, &user.about, pq.Array(&user.roles)); err != nil {
+	if err := s.store.QueryRowContext(ctx, query, id).Scan(&user.email, &user.displayName, &user.image, &user.about, pq.Array(&user.roles), &user.totpEnabled); err != nil {
 		return nil, fmt.Errorf("error getting user. %v", err)
 	}
 
 	return &user, nil
 }
+
+func (s *server) updateUser(ctx context.Context, id string, user *user) error {
+	index := 0
+	clauses := []string{}
+	arguments := []interface{}{}
+
+	if user.displayName != "" {
+		index++
+		clauses = append(clauses, fmt.Sprintf("display_name=$%d", index))
+		arguments = append(arguments, user.displayName)
+	}
+
+	if user.about.Valid {
+		index++
+		clauses = append(clauses, fmt.Sprintf("about=$%d", index))
+		arguments = append(arguments, user.about.String)
+	}
+
+	if user.image.Valid {
+		index++
+		clauses = append(clauses, fmt.Sprintf("image=$%d", index))
+		arguments = append(arguments, user.image.String)
+	}
+
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	arguments = append(arguments, id)
+
+	query := fmt.Sprintf(`UPDATE users SET %v WHERE id = $%d AND deleted_at IS NULL;`, strings.Join(clauses, ","), index+1)
+
+	results, err := s.store.ExecContext(ctx, query, arguments...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "idx_users_display_name_lower" {
+			return errDisplayNameTaken
+		}
+		return fmt.Errorf("error updating user, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errUserNotFound
+	}
+
+	return nil
+}
+
+// updateUserPassword sets the new password and revokes the sessions issued
+// before revokedAt
+func (s *server) updateUserPassword(ctx context.Context, id, password string, revokedAt time.Time) error {
+	query :=
+		`
+			UPDATE users SET password = $1, sessions_revoked_at = $3 WHERE id = $2 AND deleted_at IS NULL;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, password, id, revokedAt)
+	if err != nil {
+		return fmt.Errorf("error updating password, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errUserNotFound
+	}
+
+	return nil
+}
+
+// requestAccountDeletion scrubs everything identifying the user straight away
+// and revokes their sessions so the account can no longer be signed into, and
+// records what should happen to their books. The account deletion worker does the rest.
+func (s *server) requestAccountDeletion(ctx context.Context, userID, booksAction, transferTo string) (string, error) {
+	tx, err := s.store.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var email string
+	var deletedAt sql.NullTime
+
+	query :=
+		`
+			SELECT email, deleted_at FROM users WHERE id = $1 FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&email, &deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errUserNotFound
+		}
+		return "", fmt.Errorf("error getting user, %v", err)
+	}
+
+	if deletedAt.Valid {
+		return "", errAccountDeleted
+	}
+
+	var target sql.NullString
+	if booksAction == "transfer" {
+		var exists bool
+
+		query =
+			`
+				SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND id != $2 AND deleted_at IS NULL);
+			`
+
+		if err := tx.QueryRowContext(ctx, query, transferTo, userID).Scan(&exists); err != nil {
+			return "", fmt.Errorf("error checking transfer user, %v", err)
+		}
+
+		if !exists {
+			return "", errUserNotFound
+		}
+
+		target = sql.NullString{String: transferTo, Valid: true}
+	}
+
+	var id string
+
+	query =
+		`
+			INSERT INTO account_deletions (user_id, books_action, transfer_to) VALUES ($1, $2, $3) RETURNING id;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, userID, booksAction, target).Scan(&id); err != nil {
+		return "", fmt.Errorf("error inserting into account deletions table, %v", err)
+	}
+
+	query =
+		`
+			UPDATE users SET
+				email = 'deleted+' || id || '@pagesy.invalid',
+				display_name = 'deleted_' || id,
+				password = NULL,
+				about = NULL,
+				image = NULL,
+				totp_secret = NULL,
+				totp_enabled = FALSE,
+				sessions_revoked_at = NOW(),
+				deleted_at = NOW()
+			WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return "", fmt.Errorf("error anonymizing user, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM user_identities WHERE user_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return "", fmt.Errorf("error deleting identities, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM recovery_codes WHERE user_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return "", fmt.Errorf("error deleting recovery codes, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM login_attempts WHERE email = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, strings.ToLower(email)); err != nil {
+		return "", fmt.Errorf("error deleting login attempts, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return id, nil
+}
+
+func (s *server) getPublicProfile(ctx context.Context, id string) (*user, error) {
+	user := user{id: id}
+	query :=
+		`
+			SELECT
+				display_name,
+				image,
+				about,
+				followers,
+				following,
+				library_public,
+				reading_history_public,
+				reading_stats_public,
+				created_at
+			FROM users
+			WHERE id = $1 AND deleted_at IS NULL AND hidden = false;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, id).Scan(
+		&user.displayName,
+		&user.image,
+		&user.about,
+		&user.followers,
+		&user.following,
+		&user.privacy.libraryPublic,
+		&user.privacy.readingHistoryPublic,
+		&user.privacy.readingStatsPublic,
+		&user.createdAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("error getting user profile, %v", err)
+	}
+
+	return &user, nil
+}
+
+func (s *server) getAuthoredBooks(ctx context.Context, userID string) ([]book, error) {
+	query :=
+		`
+			SELECT 
+				b.id, 
+				b.name, 
+				b.description, 
+				b.image, 
+				b.views, 
+				b.rating,
+				b.content_rating,
+				b.content_warnings,
+				COUNT(c.id)
+			FROM books b
+			LEFT JOIN chapters c ON (b.id = c.book_id)
+			WHERE b.author_id = $1 AND b.approved = true AND b.hidden = false
+			GROUP BY b.id
+			ORDER BY b.created_at DESC;
+		`
+
+	return s.helperGetBooks(ctx, query, nil, helpersGetBooksRows, userID)
+}
+
+func (s *server) getLibraryBooks(ctx context.Context, userID string) ([]book, error) {
+	query :=
+		`
+			SELECT 
+				b.id, 
+				b.name, 
+				b.description, 
+				b.image, 
+				b.views, 
+				b.rating,
+				b.content_rating,
+				b.content_warnings,
+				COUNT(c.id)
+			FROM library l
+			JOIN books b ON (b.id = l.book_id)
+			LEFT JOIN chapters c ON (b.id = c.book_id)
+			WHERE l.user_id = $1 AND b.approved = true AND b.hidden = false
+			GROUP BY b.id
+			ORDER BY b.name;
+		`
+
+	return s.helperGetBooks(ctx, query, nil, helpersGetBooksRows, userID)
+}
+
+func (s *server) getReadingStats(ctx context.Context, userID string) (*readingStats, error) {
+	var stats readingStats
+	query :=
+		`
+			SELECT
+				(SELECT COUNT(*) FROM recent_books WHERE user_id = $1),
+				(SELECT COALESCE(SUM(chapter), 0) FROM recent_books WHERE user_id = $1),
+				(SELECT COUNT(*) FROM library WHERE user_id = $1);
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&stats.booksRead, &stats.chaptersRead, &stats.libraryBooks); err != nil {
+		return nil, fmt.Errorf("error getting reading stats, %v", err)
+	}
+
+	return &stats, nil
+}
+
+func (s *server) getPrivacySettings(ctx context.Context, userID string) (*privacySettings, error) {
+	var settings privacySettings
+	query :=
+		`
+			SELECT library_public, reading_history_public, reading_stats_public FROM users WHERE id = $1 AND deleted_at IS NULL;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&settings.libraryPublic, &settings.readingHistoryPublic, &settings.readingStatsPublic); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("error getting privacy settings, %v", err)
+	}
+
+	return &settings, nil
+}
+
+func (s *server) updatePrivacySettings(ctx context.Context, userID string, settings *privacySettings) error {
+	query :=
+		`
+			UPDATE users SET
+				library_public = $1,
+				reading_history_public = $2,
+				reading_stats_public = $3
+			WHERE id = $4 AND deleted_at IS NULL;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, settings.libraryPublic, settings.readingHistoryPublic, settings.readingStatsPublic, userID)
+	if err != nil {
+		return fmt.Errorf("error updating privacy settings, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errUserNotFound
+	}
+
+	return nil
+}
+
+func (s *server) getContentPreferences(ctx context.Context, userID string) (*contentPreferences, error) {
+	var preferences contentPreferences
+	query :=
+		`
+			SELECT birth_date, show_mature_content, hidden_content_warnings FROM users WHERE id = $1 AND deleted_at IS NULL;
+		`
+
+	if err := s.store.QueryRowContext(ctx, query, userID).Scan(&preferences.birthDate, &preferences.showMature, pq.Array(&preferences.hiddenWarnings)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("error getting content preferences, %v", err)
+	}
+
+	return &preferences, nil
+}
+
+func (s *server) updateContentPreferences(ctx context.Context, userID string, preferences *contentPreferences) error {
+	query :=
+		`
+			UPDATE users SET
+				birth_date = $1,
+				show_mature_content = $2,
+				hidden_content_warnings = $3
+			WHERE id = $4 AND deleted_at IS NULL;
+		`
+
+	results, err := s.store.ExecContext(ctx, query, preferences.birthDate, preferences.showMature, pq.Array(preferences.hiddenWarnings), userID)
+	if err != nil {
+		return fmt.Errorf("error updating content preferences, %v", err)
+	}
+
+	rows, err := results.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking number of rows affected, %v", err)
+	}
+	if rows == 0 {
+		return errUserNotFound
+	}
+
+	return nil
+}
+
+// readerFilter returns the filter to list books for userID with, signed out
+// readers and ones whose account is gone get the default one
+func (s *server) readerFilter(ctx context.Context, userID string) (contentFilter, error) {
+	if userID == "" {
+		return defaultContentFilter, nil
+	}
+
+	preferences, err := s.getContentPreferences(ctx, userID)
+	if errors.Is(err, errUserNotFound) {
+		return defaultContentFilter, nil
+	}
+	if err != nil {
+		return contentFilter{}, err
+	}
+
+	return preferences.filter(time.Now()), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxBookTags  = 10
+	maxTagLength = 32
+)
+
+var (
+	errTooManyTags = fmt.Errorf("a book can't have more than %d tags", maxBookTags)
+	errInvalidTag  = errors.New("tags have to be made of letters or numbers and be at most 32 characters long")
+)
+
+// normalizeTag lowercases a tag and keeps only letters, numbers and single
+// spaces between words, so "Slow-Burn" and "slow  burn" are the same tag
+func normalizeTag(tag string) string {
+	var b strings.Builder
+	space := false
+
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r), r == '-', r == '_', r == '/':
+			space = true
+		}
+	}
+
+	return b.String()
+}
+
+// normalizeTags normalizes the tags an author gave a book, dropping empty ones
+// and duplicates
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+
+		tag = normalizeTag(tag)
+		if tag == "" || len([]rune(tag)) > maxTagLength {
+			return nil, errInvalidTag
+		}
+
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxBookTags {
+		return nil, errTooManyTags
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []string
+		expected    []string
+		expectedErr error
+	}{
+		{
+			name:     "case, spaces and separators",
+			tags:     []string{"Slow-Burn", "  slow   burn ", "Enemies_to_Lovers", "it's complicated"},
+			expected: []string{"slow burn", "enemies to lovers", "its complicated"},
+		},
+		{
+			name:     "empty tags are dropped",
+			tags:     []string{"", " ", "cultivation"},
+			expected: []string{"cultivation"},
+		},
+		{
+			name:     "other scripts",
+			tags:     []string{"修仙", "Isekai"},
+			expected: []string{"修仙", "isekai"},
+		},
+		{
+			name:        "punctuation only",
+			tags:        []string{"!!!"},
+			expectedErr: errInvalidTag,
+		},
+		{
+			name:        "too long",
+			tags:        []string{strings.Repeat("a", maxTagLength+1)},
+			expectedErr: errInvalidTag,
+		},
+		{
+			name:        "too many",
+			tags:        strings.Split("a,b,c,d,e,f,g,h,i,j,k", ","),
+			expectedErr: errTooManyTags,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := normalizeTags(tc.tags)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if tc.expectedErr == nil && !slices.Equal(tags, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, tags)
+			}
+		})
+	}
+}
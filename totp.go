@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// number of periods either side of now a code is still accepted in, to
+	// allow for clock drift between the server and the authenticator app
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generating totp secret, %v", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpURI builds the otpauth:// uri authenticator apps read from a qr code
+func totpURI(secret, email string) string {
+	label := url.PathEscape(fmt.Sprintf("Pagesy:%s", email))
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", "Pagesy")
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// hotp implements the truncation from RFC 4226 section 5.3
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+func totpCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("error decoding totp secret, %v", err)
+	}
+	return hotp(key, uint64(totpStep(t)), totpDigits), nil
+}
+
+// validateTOTP returns the time step the code matched so callers can refuse
+// a code that has already been used
+func validateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	step := totpStep(now)
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		expected := hotp(key, uint64(step+i), totpDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+func generateRecoveryCodes() ([]string, error) {
+	var codes []string
+
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("error generating recovery code, %v", err)
+		}
+		code := hex.EncodeToString(b)
+		codes = append(codes, fmt.Sprintf("%s-%s", code[:5], code[5:]))
+	}
+
+	return codes, nil
+}
+
+// recovery codes are random enough that a fast hash is fine, unlike passwords
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
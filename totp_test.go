@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+	// test vectors from RFC 6238 appendix B, truncated to 8 digits
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "94287082"},
+		{unix: 1111111109, expected: "07081804"},
+		{unix: 1234567890, expected: "89005924"},
+		{unix: 2000000000, expected: "69279037"},
+	}
+
+	for _, tc := range tests {
+		if got := hotp(key, uint64(tc.unix/totpPeriod), 8); got != tc.expected {
+			t.Fatalf("at %d: expected %v, got %v", tc.unix, tc.expected, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1234567890, 0)
+
+	code, err := totpCode(secret, now)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		name   string
+		code   string
+		at     time.Time
+		expect bool
+	}{
+		{
+			name:   "current code",
+			code:   code,
+			at:     now,
+			expect: true,
+		},
+		{
+			name:   "code from previous period",
+			code:   code,
+			at:     now.Add(totpPeriod * time.Second),
+			expect: true,
+		},
+		{
+			name:   "expired code",
+			code:   code,
+			at:     now.Add(3 * totpPeriod * time.Second),
+			expect: false,
+		},
+		{
+			name:   "wrong length",
+			code:   "123",
+			at:     now,
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := validateTOTP(secret, tc.code, tc.at); ok != tc.expect {
+				t.Fatalf("expected %v, got %v", tc.expect, ok)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("SECRET", "test@test.com")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Pagesy:test@test.com?") {
+		t.Fatalf("unexpected uri %v", uri)
+	}
+	if !strings.Contains(uri, "secret=SECRET") || !strings.Contains(uri, "issuer=Pagesy") {
+		t.Fatalf("expected secret and issuer in uri %v", uri)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %v", code)
+		}
+		seen[code] = true
+	}
+
+	if hashRecoveryCode(codes[0]) != hashRecoveryCode(" "+strings.ToUpper(codes[0])+" ") {
+		t.Fatal("expected recovery code hash to ignore case and whitespace")
+	}
+}
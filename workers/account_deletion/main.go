@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type message struct {
+	DeletionID string
+}
+
+const (
+	queueAccountDeleted = "user.account_deleted"
+	// failed deletions wait in the retry queue until their message expires and
+	// is dead lettered back onto queueAccountDeleted
+	queueAccountDeletedRetry = "user.account_deleted.retry"
+	// deletions that failed maxAttempts times are parked here to be looked at
+	queueAccountDeletedDead = "user.account_deleted.dead"
+
+	maxAttempts = 5
+	retryDelay  = 30 * time.Second
+)
+
+func main() {
+	godotenv.Load()
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	logger.Info("connecting to db...")
+	db, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting db, %v", err))
+		os.Exit(1)
+	}
+
+	if err := db.Ping(); err != nil {
+		logger.Error(fmt.Sprintf("error pinging db, %v", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+	logger.Info("db connected")
+
+	logger.Info("connecting to queue...")
+	conn, err := amqp.Dial(os.Getenv("RABBIT_MQ_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting to rabbitmq, %v", err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+	logger.Info("queue connected")
+
+	logger.Info("opening channel...")
+	ch, err := conn.Channel()
+	if err != nil {
+		logger.Error(fmt.Sprintf("error opening channel, %v", err))
+		os.Exit(1)
+	}
+	defer ch.Close()
+	logger.Info("channel opened")
+
+	queue, err := ch.QueueDeclare(queueAccountDeleted, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueAccountDeletedRetry, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queueAccountDeleted,
+	}); err != nil {
+		logger.Error(fmt.Sprintf("error declaring retry queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueAccountDeletedDead, true, false, false, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error declaring dead letter queue, %v", err))
+		os.Exit(1)
+	}
+
+	// deletions whose message never made it onto the queue are still pending
+	if err := requeuePendingDeletions(db, ch); err != nil {
+		logger.Error(err.Error())
+	}
+
+	msg, err := ch.ConsumeWithContext(context.Background(), queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming messages from queue, %v", err))
+		os.Exit(1)
+	}
+
+	for d := range msg {
+		var newMsg message
+		if err := json.Unmarshal(d.Body, &newMsg); err != nil {
+			d.Nack(false, false)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := processDeletion(ctx, db, newMsg.DeletionID)
+		cancel()
+
+		if err != nil {
+			logger.Error(err.Error())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			err := retryDeletion(ctx, db, ch, newMsg.DeletionID, d.Body, err)
+			cancel()
+
+			// the deletion is still pending in the table, it is queued again
+			// when the worker restarts
+			if err != nil {
+				logger.Error(err.Error())
+			}
+		}
+
+		if err := d.Ack(false); err != nil {
+			logger.Error(fmt.Sprintf("error acknowledging message, %v", err))
+			continue
+		}
+	}
+}
+
+// retryDeletion records the failed attempt and sends the message to the retry
+// queue, waiting twice as long after every attempt. After maxAttempts the
+// deletion is marked as failed and the message goes to the dead letter queue.
+func retryDeletion(ctx context.Context, db *sql.DB, ch *amqp.Channel, deletionID string, body []byte, cause error) error {
+	var attempts int
+
+	query :=
+		`
+			UPDATE account_deletions SET
+				attempts = attempts + 1,
+				last_error = $2,
+				failed_at = CASE WHEN attempts + 1 >= $3 THEN NOW() END
+			WHERE id = $1 AND completed_at IS NULL
+			RETURNING attempts;
+		`
+
+	if err := db.QueryRowContext(ctx, query, deletionID, cause.Error(), maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error recording failed deletion, %v", err)
+	}
+
+	msg := amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}
+	queue := queueAccountDeletedDead
+
+	if attempts < maxAttempts {
+		msg.Expiration = strconv.FormatInt((retryDelay << (attempts - 1)).Milliseconds(), 10)
+		queue = queueAccountDeletedRetry
+	}
+
+	if err := ch.PublishWithContext(ctx, "", queue, false, false, msg); err != nil {
+		return fmt.Errorf("error publishing message to %s, %v", queue, err)
+	}
+
+	return nil
+}
+
+// requeuePendingDeletions puts the deletions that are neither done nor given up
+// on back onto the queue
+func requeuePendingDeletions(db *sql.DB, ch *amqp.Channel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	query :=
+		`
+			SELECT id FROM account_deletions WHERE completed_at IS NULL AND failed_at IS NULL;
+		`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error querying pending deletions, %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning deletion id, %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		body, err := json.Marshal(message{DeletionID: id})
+		if err != nil {
+			return fmt.Errorf("error marshalling message, %v", err)
+		}
+
+		if err := ch.PublishWithContext(ctx, "", queueAccountDeleted, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}); err != nil {
+			return fmt.Errorf("error publishing message to queue, %v", err)
+		}
+	}
+
+	return nil
+}
+
+// processDeletion hands the books over or removes them, fixes the follower
+// counts of everyone the user was connected to and then deletes the user,
+// which cascades to their library, notifications and everything else
+// referencing them
+func processDeletion(ctx context.Context, db *sql.DB, deletionID string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var userID, booksAction string
+	var transferTo sql.NullString
+
+	query :=
+		`
+			SELECT user_id, books_action, transfer_to FROM account_deletions WHERE id = $1 AND completed_at IS NULL AND failed_at IS NULL FOR UPDATE;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, deletionID).Scan(&userID, &booksAction, &transferTo); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error getting account deletion, %v", err)
+	}
+
+	// the new owner deleted their own account in the meantime, the books are
+	// kept and the deletion held until someone decides what to do with them
+	if booksAction == "transfer" && !transferTo.Valid {
+		query =
+			`
+				UPDATE account_deletions SET failed_at = NOW(), last_error = 'the account the books were to be transferred to was deleted' WHERE id = $1;
+			`
+
+		if _, err := tx.ExecContext(ctx, query, deletionID); err != nil {
+			return fmt.Errorf("error holding account deletion, %v", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error commititng transaction, %v", err)
+		}
+
+		return nil
+	}
+
+	if booksAction == "transfer" {
+		query =
+			`
+				UPDATE books SET author_id = $1, updated_at = NOW() WHERE author_id = $2;
+			`
+
+		results, err := tx.ExecContext(ctx, query, transferTo.String, userID)
+		if err != nil {
+			return fmt.Errorf("error transferring books, %v", err)
+		}
+
+		rows, err := results.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error checking number of rows affected, %v", err)
+		}
+
+		if rows > 0 {
+			query =
+				`
+					INSERT INTO notifications (user_id, message) VALUES ($1, $2);
+				`
+
+			if _, err := tx.ExecContext(ctx, query, transferTo.String, fmt.Sprintf("%d books were transferred to you", rows)); err != nil {
+				return fmt.Errorf("error inserting into notifications table, %v", err)
+			}
+		}
+	} else {
+		query =
+			`
+				DELETE FROM books WHERE author_id = $1;
+			`
+
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return fmt.Errorf("error deleting books, %v", err)
+		}
+	}
+
+	query =
+		`
+			UPDATE users SET followers = followers - 1 WHERE id IN (SELECT user_id FROM followers WHERE follower_id = $1);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error updating user followers count, %v", err)
+	}
+
+	query =
+		`
+			UPDATE users SET following = following - 1 WHERE id IN (SELECT follower_id FROM followers WHERE user_id = $1);
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error updating user following count, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM users WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("error deleting user, %v", err)
+	}
+
+	query =
+		`
+			UPDATE account_deletions SET completed_at = NOW() WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, deletionID); err != nil {
+		return fmt.Errorf("error completing account deletion, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+type verdict struct {
+	flagged bool
+	score   float64
+	details string
+}
+
+// check is one automated test a chapter goes through before it is published.
+// Checks run inside the moderation transaction.
+type check interface {
+	name() string
+	run(ctx context.Context, tx *sql.Tx, c *chapter) (verdict, error)
+}
+
+// unsegmented languages don't put spaces between words, so their text is
+// compared character by character. They are keyed by the primary subtag of
+// the language code.
+var unsegmented = map[string]bool{
+	"zh": true,
+	"ja": true,
+	"th": true,
+}
+
+// isUnsegmented reports whether language, a BCP 47 code, is written without
+// spaces between words
+func isUnsegmented(language string) bool {
+	primary, _, _ := strings.Cut(language, "-")
+	return unsegmented[strings.ToLower(primary)]
+}
+
+// tokens splits text into lowercase words, or into characters for languages
+// without word boundaries
+func tokens(language, text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	if !isUnsegmented(language) {
+		return words
+	}
+
+	var chars []string
+	for _, w := range words {
+		for _, r := range w {
+			chars = append(chars, string(r))
+		}
+	}
+	return chars
+}
+
+// bannedWords flags chapters using any of the words banned for the book's
+// language
+type bannedWords struct{}
+
+func (bannedWords) name() string { return "banned_words" }
+
+func (bannedWords) run(ctx context.Context, tx *sql.Tx, c *chapter) (verdict, error) {
+	query :=
+		`
+			SELECT word FROM banned_words WHERE language = $1 ORDER BY word;
+		`
+
+	rows, err := tx.QueryContext(ctx, query, c.language)
+	if err != nil {
+		return verdict{}, fmt.Errorf("error getting banned words, %v", err)
+	}
+	defer rows.Close()
+
+	var banned []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return verdict{}, fmt.Errorf("error scanning banned words, %v", err)
+		}
+		banned = append(banned, strings.ToLower(word))
+	}
+
+	if err := rows.Err(); err != nil {
+		return verdict{}, fmt.Errorf("error getting banned words, %v", err)
+	}
+
+	text := c.title + "\n" + c.content
+	words := map[string]bool{}
+	for _, w := range tokens(c.language, text) {
+		words[w] = true
+	}
+
+	var found []string
+	for _, b := range banned {
+		// phrases and words in unsegmented languages can't be looked up as a
+		// single token
+		if isUnsegmented(c.language) || strings.ContainsFunc(b, unicode.IsSpace) {
+			if strings.Contains(strings.ToLower(text), b) {
+				found = append(found, b)
+			}
+			continue
+		}
+		if words[b] {
+			found = append(found, b)
+		}
+	}
+
+	if len(found) == 0 {
+		return verdict{}, nil
+	}
+
+	return verdict{flagged: true, score: float64(len(found)), details: "banned words: " + strings.Join(found, ", ")}, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// links flags chapters with more links than max, which is mostly spam
+type links struct {
+	max int
+}
+
+func (links) name() string { return "links" }
+
+func (l links) run(_ context.Context, _ *sql.Tx, c *chapter) (verdict, error) {
+	count := len(linkPattern.FindAllString(c.content, -1))
+
+	if count <= l.max {
+		return verdict{score: float64(count)}, nil
+	}
+
+	return verdict{flagged: true, score: float64(count), details: fmt.Sprintf("%d links, at most %d are allowed", count, l.max)}, nil
+}
+
+const (
+	shingleSize  = 5
+	minhashSize  = 128
+	minhashBands = 32
+	minhashRows  = minhashSize / minhashBands
+)
+
+// splitmix64 scrambles x, it is what turns one shingle hash into the many
+// independent hashes MinHash needs
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+var minhashSeeds = func() [minhashSize]uint64 {
+	var seeds [minhashSize]uint64
+	for i := range seeds {
+		seeds[i] = splitmix64(uint64(i) + 1)
+	}
+	return seeds
+}()
+
+// shingles hashes every run of shingleSize consecutive tokens. Texts shorter
+// than that are a single shingle.
+func shingles(toks []string) map[uint64]bool {
+	set := map[uint64]bool{}
+
+	hash := func(toks []string) {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(toks, " ")))
+		set[h.Sum64()] = true
+	}
+
+	if len(toks) == 0 {
+		return set
+	}
+
+	if len(toks) < shingleSize {
+		hash(toks)
+		return set
+	}
+
+	for i := 0; i+shingleSize <= len(toks); i++ {
+		hash(toks[i : i+shingleSize])
+	}
+
+	return set
+}
+
+// minhash keeps, for each seed, the smallest hash of any shingle. The share of
+// positions two signatures agree on estimates how similar the texts are.
+func minhash(set map[uint64]bool) []int64 {
+	sig := make([]uint64, minhashSize)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	for sh := range set {
+		for i, seed := range minhashSeeds {
+			if h := splitmix64(sh ^ seed); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+
+	out := make([]int64, minhashSize)
+	for i, h := range sig {
+		out[i] = int64(h)
+	}
+	return out
+}
+
+// bands hashes each band of minhashRows signature positions into a bucket.
+// Chapters sharing a bucket are candidates for a full comparison.
+func bands(sig []int64) ([]int64, []int64) {
+	var ids, buckets []int64
+
+	for b := range minhashBands {
+		h := fnv.New64a()
+		for _, v := range sig[b*minhashRows : (b+1)*minhashRows] {
+			h.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		}
+		ids = append(ids, int64(b))
+		buckets = append(buckets, int64(h.Sum64()))
+	}
+
+	return ids, buckets
+}
+
+func similarity(a, b []int64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// duplicates flags chapters that mostly repeat a chapter of another book. Every
+// chapter it sees is indexed, so later uploads are compared against it too.
+type duplicates struct {
+	similarity float64
+}
+
+func (duplicates) name() string { return "duplicates" }
+
+func (d duplicates) run(ctx context.Context, tx *sql.Tx, c *chapter) (verdict, error) {
+	set := shingles(tokens(c.language, c.content))
+	if len(set) == 0 {
+		return verdict{}, nil
+	}
+
+	sig := minhash(set)
+	bandIDs, buckets := bands(sig)
+
+	query :=
+		`
+			SELECT m.chapter_id, m.signature
+			FROM chapter_minhashes m
+			JOIN chapters c ON (c.id = m.chapter_id)
+			WHERE c.book_id <> $1 AND m.chapter_id IN (
+				SELECT chapter_id FROM chapter_minhash_bands
+				WHERE (band, bucket) IN (SELECT * FROM unnest($2::smallint[], $3::bigint[]))
+			);
+		`
+
+	rows, err := tx.QueryContext(ctx, query, c.bookID, pq.Array(bandIDs), pq.Array(buckets))
+	if err != nil {
+		return verdict{}, fmt.Errorf("error getting similar chapters, %v", err)
+	}
+	defer rows.Close()
+
+	var best float64
+	var bestID string
+
+	for rows.Next() {
+		var id string
+		var other []int64
+		if err := rows.Scan(&id, pq.Array(&other)); err != nil {
+			return verdict{}, fmt.Errorf("error scanning similar chapters, %v", err)
+		}
+		if s := similarity(sig, other); s > best {
+			best, bestID = s, id
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return verdict{}, fmt.Errorf("error getting similar chapters, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO chapter_minhashes (chapter_id, signature) VALUES ($1, $2)
+			ON CONFLICT (chapter_id) DO UPDATE SET signature = EXCLUDED.signature;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, c.id, pq.Array(sig)); err != nil {
+		return verdict{}, fmt.Errorf("error storing minhash, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO chapter_minhash_bands (band, bucket, chapter_id)
+			SELECT band, bucket, $1 FROM unnest($2::smallint[], $3::bigint[]) AS b(band, bucket)
+			ON CONFLICT DO NOTHING;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, c.id, pq.Array(bandIDs), pq.Array(buckets)); err != nil {
+		return verdict{}, fmt.Errorf("error storing minhash bands, %v", err)
+	}
+
+	if best < d.similarity {
+		return verdict{score: best}, nil
+	}
+
+	return verdict{flagged: true, score: best, details: fmt.Sprintf("%.0f%% similar to chapter %s", best*100, bestID)}, nil
+}
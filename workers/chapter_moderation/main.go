@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type message struct {
+	ChapterID string
+}
+
+// fingerprintMessage is sent whenever a chapter of the book is written, edited
+// or deleted
+type fingerprintMessage struct {
+	BookID string
+}
+
+const (
+	queueChapterModeration = "book.chapter_moderation"
+	// chapters that couldn't be moderated wait in the retry queue until their
+	// message expires and is dead lettered back onto queueChapterModeration
+	queueChapterModerationRetry = "book.chapter_moderation.retry"
+	// chapters that failed maxAttempts times are parked here to be looked at
+	queueChapterModerationDead = "book.chapter_moderation.dead"
+	queueChapterPublished      = "book.chapter_published"
+	queueChapterFingerprint    = "book.chapter_fingerprint"
+
+	maxAttempts = 5
+	retryDelay  = 30 * time.Second
+
+	// chapters still pending this long after they were sent to moderation are
+	// sent again, their message was lost or never published
+	pendingTimeout = 10 * time.Minute
+)
+
+type chapter struct {
+	id       string
+	bookID   string
+	language string
+	title    string
+	content  string
+}
+
+type moderator struct {
+	logger *slog.Logger
+	db     *sql.DB
+	ch     *amqp.Channel
+	checks []check
+}
+
+func main() {
+	backfill := flag.Bool("backfill", false, "fingerprint the chapters that weren't fingerprinted yet and exit")
+	flag.Parse()
+
+	godotenv.Load()
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	logger.Info("connecting to db...")
+	db, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting db, %v", err))
+		os.Exit(1)
+	}
+
+	if err := db.Ping(); err != nil {
+		logger.Error(fmt.Sprintf("error pinging db, %v", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+	logger.Info("db connected")
+
+	if *backfill {
+		m := &moderator{logger: logger, db: db}
+		if err := m.backfill(context.Background()); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger.Info("connecting to queue...")
+	conn, err := amqp.Dial(os.Getenv("RABBIT_MQ_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting to rabbitmq, %v", err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+	logger.Info("queue connected")
+
+	logger.Info("opening channel...")
+	ch, err := conn.Channel()
+	if err != nil {
+		logger.Error(fmt.Sprintf("error opening channel, %v", err))
+		os.Exit(1)
+	}
+	defer ch.Close()
+	logger.Info("channel opened")
+
+	queue, err := ch.QueueDeclare(queueChapterModeration, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueChapterModerationRetry, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queueChapterModeration,
+	}); err != nil {
+		logger.Error(fmt.Sprintf("error declaring retry queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueChapterModerationDead, true, false, false, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error declaring dead letter queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueChapterPublished, true, false, false, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	if _, err := ch.QueueDeclare(queueChapterFingerprint, true, false, false, false, nil); err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	maxLinks := 3
+	if n, err := strconv.Atoi(os.Getenv("MODERATION_MAX_LINKS")); err == nil && n >= 0 {
+		maxLinks = n
+	}
+
+	similarity := 0.8
+	if f, err := strconv.ParseFloat(os.Getenv("MODERATION_DUPLICATE_SIMILARITY"), 64); err == nil && f > 0 && f <= 1 {
+		similarity = f
+	}
+
+	m := &moderator{
+		logger: logger,
+		db:     db,
+		ch:     ch,
+		checks: []check{
+			bannedWords{},
+			links{max: maxLinks},
+			duplicates{similarity: similarity},
+		},
+	}
+
+	msg, err := ch.ConsumeWithContext(context.Background(), queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming messages from queue, %v", err))
+		os.Exit(1)
+	}
+
+	fingerprints, err := ch.ConsumeWithContext(context.Background(), queueChapterFingerprint, "", false, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming messages from queue, %v", err))
+		os.Exit(1)
+	}
+
+	go m.consumeFingerprints(fingerprints)
+	go m.requeuePending(pendingTimeout)
+
+	for d := range msg {
+		var newMsg message
+		if err := json.Unmarshal(d.Body, &newMsg); err != nil {
+			d.Nack(false, false)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := m.moderate(ctx, newMsg.ChapterID)
+		cancel()
+
+		if err != nil {
+			logger.Error(err.Error())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			err := m.retryModeration(ctx, newMsg.ChapterID, d.Body, err)
+			cancel()
+
+			// the chapter is still pending, requeuePending sends it again
+			if err != nil {
+				logger.Error(err.Error())
+			}
+		}
+
+		if err := d.Ack(false); err != nil {
+			logger.Error(fmt.Sprintf("error acknowledging message, %v", err))
+		}
+	}
+}
+
+// consumeFingerprints fingerprints books as their chapters are written, edited
+// or deleted. Failed messages aren't retried, the chapters stay without
+// fingerprinted_at and the backfill picks them up.
+func (m *moderator) consumeFingerprints(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		var msg fingerprintMessage
+		if err := json.Unmarshal(d.Body, &msg); err != nil {
+			d.Nack(false, false)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := m.fingerprint(ctx, msg.BookID)
+		cancel()
+
+		if err != nil {
+			m.logger.Error(err.Error())
+		}
+
+		if err := d.Ack(false); err != nil {
+			m.logger.Error(fmt.Sprintf("error acknowledging message, %v", err))
+		}
+	}
+}
+
+// retryModeration records the failed attempt and sends the message to the
+// retry queue, waiting twice as long after every attempt. After maxAttempts the
+// chapter is marked as failed, requeuePending leaves it alone and the message
+// goes to the dead letter queue.
+func (m *moderator) retryModeration(ctx context.Context, chapterID string, body []byte, cause error) error {
+	var attempts int
+
+	query :=
+		`
+			UPDATE chapters SET
+				moderation_attempts = moderation_attempts + 1,
+				moderation_error = $2,
+				moderation_failed_at = CASE WHEN moderation_attempts + 1 >= $3 THEN NOW() END
+			WHERE id = $1 AND moderation_status = 'pending'
+			RETURNING moderation_attempts;
+		`
+
+	if err := m.db.QueryRowContext(ctx, query, chapterID, cause.Error(), maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error recording failed moderation, %v", err)
+	}
+
+	msg := amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}
+	queue := queueChapterModerationDead
+
+	if attempts < maxAttempts {
+		msg.Expiration = strconv.FormatInt((retryDelay << (attempts - 1)).Milliseconds(), 10)
+		queue = queueChapterModerationRetry
+	}
+
+	if err := m.ch.PublishWithContext(ctx, "", queue, false, false, msg); err != nil {
+		return fmt.Errorf("error publishing message to %s, %v", queue, err)
+	}
+
+	return nil
+}
+
+// requeuePending sends the chapters left pending for longer than timeout to
+// moderation again, now and then every timeout. A chapter moderated before
+// its second message arrives is skipped by moderate.
+func (m *moderator) requeuePending(timeout time.Duration) {
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := m.requeueStale(ctx, timeout); err != nil {
+			m.logger.Error(err.Error())
+		}
+		cancel()
+
+		<-ticker.C
+	}
+}
+
+// requeueStale sends up to 1000 chapters pending for longer than timeout to
+// moderation again, marking them as just requested
+func (m *moderator) requeueStale(ctx context.Context, timeout time.Duration) error {
+	query :=
+		`
+			UPDATE chapters SET moderation_requested_at = NOW()
+			WHERE id IN (
+				SELECT id FROM chapters
+				WHERE moderation_status = 'pending' AND moderation_failed_at IS NULL
+				AND moderation_requested_at < NOW() - make_interval(secs => $1)
+				ORDER BY moderation_requested_at
+				LIMIT 1000
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id;
+		`
+
+	rows, err := m.db.QueryContext(ctx, query, timeout.Seconds())
+	if err != nil {
+		return fmt.Errorf("error getting pending chapters, %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning chapter id, %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error getting pending chapters, %v", err)
+	}
+
+	// chapters that fail to publish are picked up again after timeout
+	for _, id := range ids {
+		body, err := json.Marshal(&message{ChapterID: id})
+		if err != nil {
+			return fmt.Errorf("error marshalling message, %v", err)
+		}
+
+		if err := m.ch.PublishWithContext(ctx, "", queueChapterModeration, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}); err != nil {
+			return fmt.Errorf("error publishing message to queue, %v", err)
+		}
+	}
+
+	return nil
+}
+
+// moderate runs every check on a pending chapter and stores their verdicts. A
+// chapter that passes all of them is published, the rest are held for an admin.
+// Chapters that are gone or were already moderated are skipped, so redelivered
+// messages are harmless.
+func (m *moderator) moderate(ctx context.Context, chapterID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var c chapter
+	var status string
+
+	query :=
+		`
+			SELECT c.id, c.book_id, b.language, c.title, c.content, c.moderation_status
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE c.id = $1
+			FOR UPDATE OF c;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, chapterID).Scan(&c.id, &c.bookID, &c.language, &c.title, &c.content, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error getting chapter, %v", err)
+	}
+
+	if status != "pending" {
+		return nil
+	}
+
+	flagged := false
+
+	for _, chk := range m.checks {
+		v, err := chk.run(ctx, tx, &c)
+		if err != nil {
+			return fmt.Errorf("error running %s check, %v", chk.name(), err)
+		}
+
+		query =
+			`
+				INSERT INTO chapter_verdicts (chapter_id, check_name, flagged, score, details) VALUES ($1, $2, $3, $4, $5);
+			`
+
+		if _, err := tx.ExecContext(ctx, query, c.id, chk.name(), v.flagged, v.score, sql.NullString{String: v.details, Valid: v.details != ""}); err != nil {
+			return fmt.Errorf("error storing verdict, %v", err)
+		}
+
+		flagged = flagged || v.flagged
+	}
+
+	status = "approved"
+	if flagged {
+		status = "flagged"
+	}
+
+	query =
+		`
+			UPDATE chapters SET moderation_status = $1 WHERE id = $2;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, status, c.id); err != nil {
+		return fmt.Errorf("error updating chapter, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	if flagged {
+		return nil
+	}
+
+	body, err := json.Marshal(&message{ChapterID: c.id})
+	if err != nil {
+		return fmt.Errorf("error marshalling message, %v", err)
+	}
+
+	// the chapter is already approved, so a failure here can't be retried by
+	// requeueing the moderation message
+	if err := m.ch.PublishWithContext(ctx, "", queueChapterPublished, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}); err != nil {
+		m.logger.Error(fmt.Sprintf("error publishing chapter %s, %v", c.id, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// fingerprintGram is how many tokens go into each hashed k-gram
+	fingerprintGram = 5
+	// fingerprintWindow is the winnowing window. Any run of
+	// fingerprintGram+fingerprintWindow-1 tokens shared by two texts is
+	// guaranteed to produce a shared fingerprint.
+	fingerprintWindow = 4
+)
+
+// plagiarismOverlap is the share of a book's fingerprints that must appear in
+// an earlier book by another author before the two are reported as a match
+func plagiarismOverlap() float64 {
+	if f, err := strconv.ParseFloat(os.Getenv("PLAGIARISM_OVERLAP"), 64); err == nil && f > 0 && f <= 1 {
+		return f
+	}
+	return 0.3
+}
+
+// winnow returns the fingerprints of the text, keyed by hash with the token
+// position each was first seen at. It hashes every k-gram and keeps the
+// smallest hash of each window, which keeps the set small while still
+// catching every long enough shared passage.
+func winnow(tokens []string) map[int64]int {
+	fingerprints := map[int64]int{}
+
+	if len(tokens) < fingerprintGram {
+		return fingerprints
+	}
+
+	hashes := make([]int64, len(tokens)-fingerprintGram+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+fingerprintGram], " ")))
+		hashes[i] = int64(h.Sum64())
+	}
+
+	window := min(fingerprintWindow, len(hashes))
+
+	for start := 0; start+window <= len(hashes); start++ {
+		// the rightmost minimum is picked so consecutive windows agree on it
+		picked := start
+		for i := start; i < start+window; i++ {
+			if hashes[i] <= hashes[picked] {
+				picked = i
+			}
+		}
+
+		if _, ok := fingerprints[hashes[picked]]; !ok {
+			fingerprints[hashes[picked]] = picked
+		}
+	}
+
+	return fingerprints
+}
+
+// fingerprint fingerprints the chapters of the book that were written or
+// edited since they were last fingerprinted and matches every book that may be
+// affected again. Deleted chapters leave nothing to fingerprint, the book and
+// the books that matched it are only matched again.
+func (m *moderator) fingerprint(ctx context.Context, bookID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	query :=
+		`
+			SELECT id FROM chapters WHERE book_id = $1 AND fingerprinted_at IS NULL ORDER BY id FOR UPDATE;
+		`
+
+	rows, err := tx.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return fmt.Errorf("error getting chapters to fingerprint, %v", err)
+	}
+	defer rows.Close()
+
+	var chapters []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("error scanning chapter, %v", err)
+		}
+		chapters = append(chapters, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error getting chapters to fingerprint, %v", err)
+	}
+
+	for _, id := range chapters {
+		if err := fingerprintChapter(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := rematchBook(ctx, tx, bookID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// backfill fingerprints the books with chapters that weren't fingerprinted,
+// written before fingerprinting existed or whose message failed. The order
+// doesn't matter, fingerprinting a book matches the books sharing its
+// fingerprints again.
+func (m *moderator) backfill(ctx context.Context) error {
+	query :=
+		`
+			SELECT book_id FROM chapters WHERE fingerprinted_at IS NULL GROUP BY book_id ORDER BY MIN(created_at);
+		`
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error getting books to fingerprint, %v", err)
+	}
+	defer rows.Close()
+
+	var books []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("error scanning book, %v", err)
+		}
+		books = append(books, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error getting books to fingerprint, %v", err)
+	}
+
+	for _, id := range books {
+		if err := m.fingerprint(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	m.logger.Info(fmt.Sprintf("fingerprinted %d books", len(books)))
+
+	return nil
+}
+
+// fingerprintChapter replaces the fingerprints of a chapter with those of its
+// current content
+func fingerprintChapter(ctx context.Context, tx *sql.Tx, chapterID string) error {
+	var language, content string
+
+	query :=
+		`
+			SELECT b.language, c.content
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE c.id = $1;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, chapterID).Scan(&language, &content); err != nil {
+		return fmt.Errorf("error getting chapter content, %v", err)
+	}
+
+	query =
+		`
+			DELETE FROM chapter_fingerprints WHERE chapter_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, chapterID); err != nil {
+		return fmt.Errorf("error deleting chapter fingerprints, %v", err)
+	}
+
+	fingerprints := winnow(tokens(language, content))
+
+	if len(fingerprints) > 0 {
+		var hashes []int64
+		var positions []int64
+		for h, p := range fingerprints {
+			hashes = append(hashes, h)
+			positions = append(positions, int64(p))
+		}
+
+		query =
+			`
+				INSERT INTO chapter_fingerprints (chapter_id, hash, position)
+				SELECT $1, hash, position FROM unnest($2::bigint[], $3::int[]) AS f(hash, position);
+			`
+
+		if _, err := tx.ExecContext(ctx, query, chapterID, pq.Array(hashes), pq.Array(positions)); err != nil {
+			return fmt.Errorf("error inserting chapter fingerprints, %v", err)
+		}
+	}
+
+	query =
+		`
+			UPDATE chapters SET fingerprinted_at = NOW() WHERE id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, chapterID); err != nil {
+		return fmt.Errorf("error updating chapter, %v", err)
+	}
+
+	return nil
+}
+
+// rematchBook matches the book again, along with the books that matched it or
+// share fingerprints with it, since a changed or deleted chapter changes their
+// overlap too
+func rematchBook(ctx context.Context, tx *sql.Tx, bookID string) error {
+	query :=
+		`
+			SELECT $1::uuid
+			UNION
+			SELECT book_id FROM plagiarism_matches WHERE source_book_id = $1
+			UNION
+			SELECT c.book_id
+			FROM chapter_fingerprints mf
+			JOIN chapters mc ON (mc.id = mf.chapter_id)
+			JOIN chapter_fingerprints f ON (f.hash = mf.hash)
+			JOIN chapters c ON (c.id = f.chapter_id)
+			WHERE mc.book_id = $1
+			ORDER BY 1;
+		`
+
+	rows, err := tx.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return fmt.Errorf("error getting books to match, %v", err)
+	}
+	defer rows.Close()
+
+	var books []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("error scanning book, %v", err)
+		}
+		books = append(books, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error getting books to match, %v", err)
+	}
+
+	for _, id := range books {
+		if err := matchBookFingerprints(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchBookFingerprints looks up every fingerprint of the book in chapters
+// written earlier by other authors and keeps the source books it overlaps
+// with by at least plagiarismOverlap
+func matchBookFingerprints(ctx context.Context, tx *sql.Tx, bookID string) error {
+	query :=
+		`
+			DELETE FROM plagiarism_matches WHERE book_id = $1;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID); err != nil {
+		return fmt.Errorf("error deleting plagiarism matches, %v", err)
+	}
+
+	query =
+		`
+			WITH mine AS (
+				SELECT f.hash, MIN(c.created_at) AS first_seen
+				FROM chapter_fingerprints f
+				JOIN chapters c ON (c.id = f.chapter_id)
+				WHERE c.book_id = $1
+				GROUP BY f.hash
+			), total AS (
+				SELECT COUNT(*) AS n FROM mine
+			)
+			INSERT INTO plagiarism_matches (book_id, source_book_id, shared, total, overlap)
+			SELECT $1, c.book_id, COUNT(DISTINCT mine.hash), total.n, COUNT(DISTINCT mine.hash)::float / total.n
+			FROM mine
+			JOIN chapter_fingerprints f ON (f.hash = mine.hash)
+			JOIN chapters c ON (c.id = f.chapter_id AND c.created_at < mine.first_seen)
+			JOIN books b ON (b.id = c.book_id)
+			CROSS JOIN total
+			WHERE c.book_id <> $1 AND b.author_id IS DISTINCT FROM (SELECT author_id FROM books WHERE id = $1)
+			GROUP BY c.book_id, total.n
+			HAVING COUNT(DISTINCT mine.hash)::float / total.n >= $2;
+		`
+
+	if _, err := tx.ExecContext(ctx, query, bookID, plagiarismOverlap()); err != nil {
+		return fmt.Errorf("error matching book fingerprints, %v", err)
+	}
+
+	return nil
+}
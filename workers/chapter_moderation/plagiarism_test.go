@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWinnow(t *testing.T) {
+	original := "The rain had not stopped for three days, and the river at the edge of the village was already climbing over the old stone wall that nobody had repaired since the war."
+
+	shared := func(a, b string) int {
+		fa := winnow(tokens("en", a))
+		fb := winnow(tokens("en", b))
+
+		count := 0
+		for h := range fa {
+			if _, ok := fb[h]; ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		shared bool
+	}{
+		{
+			name:   "same text",
+			text:   original,
+			shared: true,
+		},
+		{
+			name:   "different case and punctuation",
+			text:   strings.ToUpper(strings.ReplaceAll(original, ",", ";")),
+			shared: true,
+		},
+		{
+			name:   "copied passage",
+			text:   "Chapter one. " + original[:120] + " Then everything changed.",
+			shared: true,
+		},
+		{
+			name:   "unrelated text",
+			text:   "A merchant from the capital arrived at dawn with three carts of silk and a letter sealed in red wax for the governor.",
+			shared: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shared(original, tc.text) > 0; got != tc.shared {
+				t.Fatalf("expected shared fingerprints to be %v", tc.shared)
+			}
+		})
+	}
+}
+
+func TestWinnowShortText(t *testing.T) {
+	if fingerprints := winnow(tokens("en", "too short")); len(fingerprints) != 0 {
+		t.Fatalf("expected no fingerprints, got %d", len(fingerprints))
+	}
+
+	if fingerprints := winnow(tokens("ja", "雨は三日間止まなかった")); len(fingerprints) == 0 {
+		t.Fatal("expected japanese text to be fingerprinted by character")
+	}
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type message struct {
+	ExportID string
+}
+
+const (
+	queueDataExport = "user.data_export"
+
+	// sweepInterval is how often files of expired exports are deleted
+	sweepInterval = time.Hour
+)
+
+type exporter struct {
+	db         *sql.DB
+	cloudinary *cloudinary.Cloudinary
+	ttl        time.Duration
+}
+
+func main() {
+	godotenv.Load()
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	logger.Info("connecting to db...")
+	db, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting db, %v", err))
+		os.Exit(1)
+	}
+
+	if err := db.Ping(); err != nil {
+		logger.Error(fmt.Sprintf("error pinging db, %v", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+	logger.Info("db connected")
+
+	cld, err := cloudinary.NewFromParams(os.Getenv("CLOUDINARY_CLOUD"), os.Getenv("CLOUDINARY_KEY"), os.Getenv("CLOUDINARY_SECRET"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting to cloudinary, %v", err))
+		os.Exit(1)
+	}
+
+	ttl := 7 * 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("DATA_EXPORT_TTL")); err == nil && d > 0 {
+		ttl = d
+	}
+
+	e := &exporter{db: db, cloudinary: cld, ttl: ttl}
+
+	go e.sweep(logger)
+
+	logger.Info("connecting to queue...")
+	conn, err := amqp.Dial(os.Getenv("RABBIT_MQ_CONN"))
+	if err != nil {
+		logger.Error(fmt.Sprintf("error connecting to rabbitmq, %v", err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+	logger.Info("queue connected")
+
+	logger.Info("opening channel...")
+	ch, err := conn.Channel()
+	if err != nil {
+		logger.Error(fmt.Sprintf("error opening channel, %v", err))
+		os.Exit(1)
+	}
+	defer ch.Close()
+	logger.Info("channel opened")
+
+	queue, err := ch.QueueDeclare(queueDataExport, true, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error declaring queue, %v", err))
+		os.Exit(1)
+	}
+
+	// exports whose message never made it onto the queue are still pending
+	if err := requeuePendingExports(db, ch); err != nil {
+		logger.Error(err.Error())
+	}
+
+	msg, err := ch.ConsumeWithContext(context.Background(), queue.Name, "", false, false, false, false, nil)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error consuming messages from queue, %v", err))
+		os.Exit(1)
+	}
+
+	for d := range msg {
+		var newMsg message
+		if err := json.Unmarshal(d.Body, &newMsg); err != nil {
+			d.Nack(false, false)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		err := e.export(ctx, newMsg.ExportID)
+		cancel()
+
+		// a failed export is marked as such instead of being retried forever,
+		// the user can request a new one
+		if err != nil {
+			logger.Error(err.Error())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			if _, err := db.ExecContext(ctx, `UPDATE data_exports SET status = 'failed', completed_at = NOW() WHERE id = $1;`, newMsg.ExportID); err != nil {
+				logger.Error(fmt.Sprintf("error marking export as failed, %v", err))
+			}
+			cancel()
+		}
+
+		if err := d.Ack(false); err != nil {
+			logger.Error(fmt.Sprintf("error acknowledging message, %v", err))
+			continue
+		}
+	}
+}
+
+// requeuePendingExports puts the exports that are still pending back onto the
+// queue, the ones already on it are skipped once they are ready
+func requeuePendingExports(db *sql.DB, ch *amqp.Channel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	query :=
+		`
+			SELECT id FROM data_exports WHERE status = 'pending';
+		`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error querying pending exports, %v", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning export id, %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		body, err := json.Marshal(message{ExportID: id})
+		if err != nil {
+			return fmt.Errorf("error marshalling message, %v", err)
+		}
+
+		if err := ch.PublishWithContext(ctx, "", queueDataExport, false, false, amqp.Publishing{ContentType: "application/json", DeliveryMode: amqp.Persistent, Body: body}); err != nil {
+			return fmt.Errorf("error publishing message to queue, %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (e *exporter) export(ctx context.Context, exportID string) error {
+	var userID string
+
+	query :=
+		`
+			SELECT user_id FROM data_exports WHERE id = $1 AND status = 'pending';
+		`
+
+	if err := e.db.QueryRowContext(ctx, query, exportID).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error getting data export, %v", err)
+	}
+
+	archive, err := e.buildArchive(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Errorf("error generating export key, %v", err)
+	}
+
+	// the archive is an authenticated asset, it can only be downloaded through
+	// the short lived urls the api signs
+	key := fmt.Sprintf("exports/%s_%s.zip", userID, hex.EncodeToString(token))
+
+	if _, err := e.cloudinary.Upload.Upload(ctx, bytes.NewReader(archive), uploader.UploadParams{
+		PublicID:     "pagesy/" + key,
+		ResourceType: api.File,
+		Type:         api.Authenticated,
+	}); err != nil {
+		return fmt.Errorf("error uploading export, %v", err)
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction, %v", err)
+	}
+	defer tx.Rollback()
+
+	var expiresAt time.Time
+
+	query =
+		`
+			UPDATE data_exports SET
+				status = 'ready',
+				object_key = $1,
+				expires_at = NOW() + make_interval(secs => $2),
+				completed_at = NOW()
+			WHERE id = $3
+			RETURNING expires_at;
+		`
+
+	if err := tx.QueryRowContext(ctx, query, key, e.ttl.Seconds(), exportID).Scan(&expiresAt); err != nil {
+		return fmt.Errorf("error updating data export, %v", err)
+	}
+
+	query =
+		`
+			INSERT INTO notifications (user_id, message) VALUES ($1, $2);
+		`
+
+	link := fmt.Sprintf("%s/api/v1/users/me/exports/%s/download", os.Getenv("HOST"), exportID)
+
+	if _, err := tx.ExecContext(ctx, query, userID, fmt.Sprintf("Your data export is ready, download it before %s: %s", expiresAt.Format("Jan 2, 2006"), link)); err != nil {
+		return fmt.Errorf("error inserting into notifications table, %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commititng transaction, %v", err)
+	}
+
+	return nil
+}
+
+// sweep deletes the files of exports past their expiry every sweepInterval
+func (e *exporter) sweep(logger *slog.Logger) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		if err := e.deleteExpired(ctx); err != nil {
+			logger.Error(err.Error())
+		}
+		cancel()
+
+		<-ticker.C
+	}
+}
+
+func (e *exporter) deleteExpired(ctx context.Context) error {
+	query :=
+		`
+			SELECT id, object_key, url FROM data_exports
+			WHERE expires_at <= NOW() AND (object_key IS NOT NULL OR url IS NOT NULL);
+		`
+
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("error getting expired exports, %v", err)
+	}
+	defer rows.Close()
+
+	type expired struct {
+		id  string
+		key sql.NullString
+		url sql.NullString
+	}
+
+	exports := []expired{}
+
+	for rows.Next() {
+		var x expired
+		if err := rows.Scan(&x.id, &x.key, &x.url); err != nil {
+			return fmt.Errorf("error scanning expired export, %v", err)
+		}
+		exports = append(exports, x)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error getting expired exports, %v", err)
+	}
+
+	for _, x := range exports {
+		// exports from before files were private were public uploads
+		publicID, deliveryType := "pagesy/"+x.key.String, string(api.Authenticated)
+		if !x.key.Valid {
+			publicID, deliveryType = publicIDFromURL(x.url.String), string(api.Upload)
+		}
+
+		resp, err := e.cloudinary.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: publicID, Type: deliveryType, ResourceType: api.File})
+		if err != nil {
+			return fmt.Errorf("error deleting export file, %v", err)
+		}
+
+		if resp.Error.Message != "" {
+			return fmt.Errorf("error deleting export file, %v", resp.Error.Message)
+		}
+
+		query =
+			`
+				UPDATE data_exports SET status = 'expired', object_key = NULL, url = NULL WHERE id = $1;
+			`
+
+		if _, err := e.db.ExecContext(ctx, query, x.id); err != nil {
+			return fmt.Errorf("error expiring data export, %v", err)
+		}
+	}
+
+	return nil
+}
+
+var versionPrefix = regexp.MustCompile(`^v[0-9]+/`)
+
+// publicIDFromURL returns the public id of a raw file from its delivery url,
+// the part after /upload/ without the version
+func publicIDFromURL(url string) string {
+	_, path, _ := strings.Cut(url, "/upload/")
+	return versionPrefix.ReplaceAllString(path, "")
+}
+
+// exportQueries are written to the archive as <name>.json, one object per row
+var exportQueries = []struct {
+	name  string
+	query string
+}{
+	{
+		name: "profile",
+		query: `
+			SELECT
+				id,
+				email,
+				display_name,
+				about,
+				image,
+				array_to_string(roles, ',') AS roles,
+				followers,
+				following,
+				totp_enabled,
+				library_public,
+				reading_history_public,
+				reading_stats_public,
+				created_at
+			FROM users
+			WHERE id = $1;
+		`,
+	},
+	{
+		name: "identities",
+		query: `
+			SELECT provider, email, created_at FROM user_identities WHERE user_id = $1;
+		`,
+	},
+	{
+		name: "books",
+		query: `
+			SELECT
+				b.id,
+				b.name,
+				b.description,
+				b.image,
+				b.views,
+				b.language,
+				b.rating,
+				b.completed,
+				b.approved,
+				array_to_string(ARRAY(SELECT g.genre::TEXT FROM books_genres bg JOIN genres g ON (g.id = bg.genre_id) WHERE bg.book_id = b.id), ',') AS genres,
+				b.created_at,
+				b.updated_at
+			FROM books b
+			WHERE b.author_id = $1;
+		`,
+	},
+	{
+		name: "chapters",
+		query: `
+			SELECT c.id, c.book_id, b.name AS book, c.chapter_no, c.title, c.created_at
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE b.author_id = $1
+			ORDER BY b.name, c.chapter_no;
+		`,
+	},
+	{
+		name: "library",
+		query: `
+			SELECT b.id AS book_id, b.name AS book FROM library l JOIN books b ON (b.id = l.book_id) WHERE l.user_id = $1;
+		`,
+	},
+	{
+		name: "recent_books",
+		query: `
+			SELECT b.id AS book_id, b.name AS book, rb.chapter, rb.created_at, rb.updated_at
+			FROM recent_books rb
+			JOIN books b ON (b.id = rb.book_id)
+			WHERE rb.user_id = $1;
+		`,
+	},
+	{
+		name: "notifications",
+		query: `
+			SELECT message, book_id, created_at FROM notifications WHERE user_id = $1 ORDER BY created_at;
+		`,
+	},
+	{
+		name: "followers",
+		query: `
+			SELECT u.id, u.display_name FROM followers f JOIN users u ON (u.id = f.follower_id) WHERE f.user_id = $1;
+		`,
+	},
+	{
+		name: "following",
+		query: `
+			SELECT u.id, u.display_name FROM followers f JOIN users u ON (u.id = f.user_id) WHERE f.follower_id = $1;
+		`,
+	},
+}
+
+func (e *exporter) buildArchive(ctx context.Context, userID string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, q := range exportQueries {
+		rows, err := e.queryRows(ctx, q.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("error exporting %s, %v", q.name, err)
+		}
+
+		var data any = rows
+		if q.name == "profile" && len(rows) == 1 {
+			data = rows[0]
+		}
+
+		f, err := zw.Create(q.name + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s.json, %v", q.name, err)
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return nil, fmt.Errorf("error writing %s.json, %v", q.name, err)
+		}
+	}
+
+	if err := e.writeChapters(ctx, zw, userID); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error closing archive, %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// writeChapters adds the content of every chapter the user wrote as markdown,
+// under chapters/<book id>_<book name>/<chapter no>.md. The name is only there
+// to make the folders readable, names that are all non latin characters are
+// left out and books with the same name still get their own folder.
+func (e *exporter) writeChapters(ctx context.Context, zw *zip.Writer, userID string) error {
+	query :=
+		`
+			SELECT b.id, b.name, c.chapter_no, c.title, c.content
+			FROM chapters c
+			JOIN books b ON (b.id = c.book_id)
+			WHERE b.author_id = $1
+			ORDER BY b.name, b.id, c.chapter_no;
+		`
+
+	rows, err := e.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("error getting chapters, %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID, book, title, content string
+		var chapterNo int
+
+		if err := rows.Scan(&bookID, &book, &chapterNo, &title, &content); err != nil {
+			return fmt.Errorf("error scanning chapter, %v", err)
+		}
+
+		dir := bookID
+		if name := strings.Trim(unsafeFileChars.ReplaceAllString(book, "_"), "_"); name != "" {
+			dir += "_" + name
+		}
+		f, err := zw.Create(fmt.Sprintf("chapters/%s/%03d.md", dir, chapterNo))
+		if err != nil {
+			return fmt.Errorf("error creating chapter file, %v", err)
+		}
+
+		if _, err := fmt.Fprintf(f, "# %s\n\n%s\n", title, content); err != nil {
+			return fmt.Errorf("error writing chapter file, %v", err)
+		}
+	}
+
+	return rows.Err()
+}
+
+func (e *exporter) queryRows(ctx context.Context, query string, args ...any) ([]map[string]any, error) {
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]any{}
+
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+				continue
+			}
+			row[column] = values[i]
+		}
+		results = append(results, row)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The websocket at /api/v1/ws speaks JSON in both directions.
+//
+// Every message from the client is a command with an id chosen by the client:
+//
+//	{"id": "1", "type": "subscribe", "payload": {"bookId": "<uuid>"}}
+//
+// and is answered with either an ack or an error carrying the same id:
+//
+//	{"type": "ack", "id": "1"}
+//	{"type": "error", "id": "1", "error": "book not found"}
+//
+// Commands:
+//
+//	subscribe               {"bookId"}           receive events for a book
+//	unsubscribe             {"bookId"}           stop receiving events for a book
+//	mark_notification_read  {"notificationId"}   mark a notification as read
+//	typing                  {"bookId"}           tell the book room the user is typing a comment, the book has to be subscribed to
+//
+// Events pushed by the server have no id of their own and carry the event's
+// envelope:
+//
+//	{"type": "event", "event": {"id": "<hex>", "type": "chapter.uploaded", "version": 1, "occurredAt": "<RFC 3339>", "seq": 42, "payload": {...}}}
+//
+// The event types, their versions and payloads are described by the JSON schema
+// served at /api/v1/events/schema.
+//
+// Every event except book.typing is recorded with the next number of a sequence
+// kept per user. A client reconnecting with /api/v1/ws?lastSeenSeq=42 first gets the
+// events it missed, oldest first, followed by
+//
+//	{"type": "replayed", "payload": {"count": 3, "truncated": false}}
+//
+// before live delivery starts. At most WS_REPLAY_MAX_EVENTS (default 100) events
+// no older than WS_REPLAY_MAX_AGE (default 72h) are replayed; truncated tells the
+// client it missed more and should refetch. Events published while the client
+// connects can arrive twice, so clients drop events with a seq they have seen.
+// Events for books subscribed to with the subscribe command are not replayed,
+// only those for books in the user's library.
+//
+// The server pings every pingPeriod and closes connections which haven't
+// answered with a pong within pongWait. Messages bigger than maxMessageSize
+// close the connection.
+//
+// A user can keep up to WS_MAX_CONNECTIONS_PER_USER (default 5) connections
+// open to a replica, e.g. one per tab. Connections past the limit are closed
+// with 1008 (policy violation) right after the upgrade.
+//
+// Every connection buffers up to WS_SEND_BUFFER_SIZE (default 16) messages.
+// A connection that falls further behind is closed with 1013 (try again later),
+// or with WS_SLOW_CLIENT_POLICY=drop only misses the messages that didn't fit.
+// Typing events are never worth a disconnect and are always just dropped.
+//
+// Clients that can't open a websocket can stream the same events, without
+// commands, as server-sent events from /api/v1/events. Their data is the
+// envelope and their id its seq, so browsers resume with Last-Event-ID on their
+// own.
+
+const (
+	commandSubscribe            = "subscribe"
+	commandUnsubscribe          = "unsubscribe"
+	commandMarkNotificationRead = "mark_notification_read"
+	commandTyping               = "typing"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errNotSubscribed  = errors.New("not subscribed to book")
+)
+
+type clientMessage struct {
+	ID      string          `json:"id" validate:"required"`
+	Type    string          `json:"type" validate:"required"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type serverMessage struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id,omitempty"`
+	Event   *envelope `json:"event,omitempty"`
+	Payload any       `json:"payload,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type bookPayload struct {
+	BookId string `json:"bookId" validate:"required,uuid"`
+}
+
+type notificationPayload struct {
+	NotificationId string `json:"notificationId" validate:"required,uuid"`
+}
+
+func ackMessage(id string) []byte {
+	body, _ := json.Marshal(&serverMessage{Type: "ack", ID: id})
+	return body
+}
+
+func errorMessage(id string, err error) []byte {
+	body, _ := json.Marshal(&serverMessage{Type: "error", ID: id, Error: err.Error()})
+	return body
+}
+
+type replayPayload struct {
+	Count     int  `json:"count"`
+	Truncated bool `json:"truncated"`
+}
+
+func eventMessage(env *envelope) ([]byte, error) {
+	body, err := json.Marshal(&serverMessage{Type: "event", Event: env})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling event, %v", err)
+	}
+	return body, nil
+}
+
+func replayedMessage(count int, truncated bool) []byte {
+	body, _ := json.Marshal(&serverMessage{Type: "replayed", Payload: replayPayload{Count: count, Truncated: truncated}})
+	return body
+}
+
+func decodePayload(raw json.RawMessage, data any) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("%w, missing payload", errValidation)
+	}
+
+	if err := json.Unmarshal(raw, data); err != nil {
+		return fmt.Errorf("invalid payload, %v", err)
+	}
+
+	if err := validate.Struct(data); err != nil {
+		return fmt.Errorf("%w, %w", errValidation, err)
+	}
+
+	return nil
+}
+
+// handleCommand runs a single client command. The returned error is sent back
+// to the client, so internal errors are logged and replaced.
+func (s *server) handleCommand(ctx context.Context, c *client, msg *clientMessage) error {
+	switch msg.Type {
+	case commandSubscribe:
+		var payload bookPayload
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+
+		if err := s.checkIfBookExists(ctx, payload.BookId); err != nil {
+			if errors.Is(err, errBookNotFound) {
+				return err
+			}
+			s.logger.Error(err.Error())
+			return errors.New("internal server error")
+		}
+
+		s.hub.joinRoom <- &roomUser{roomID: payload.BookId, client: c}
+		c.rooms[payload.BookId] = true
+	case commandUnsubscribe:
+		var payload bookPayload
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+
+		if !c.rooms[payload.BookId] {
+			return errNotSubscribed
+		}
+
+		s.hub.disconnectRoomUser <- &roomUser{roomID: payload.BookId, client: c}
+		delete(c.rooms, payload.BookId)
+	case commandMarkNotificationRead:
+		var payload notificationPayload
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+
+		if err := s.markNotificationRead(ctx, c.id, payload.NotificationId); err != nil {
+			if errors.Is(err, errNotificationNotFound) {
+				return err
+			}
+			s.logger.Error(err.Error())
+			return errors.New("internal server error")
+		}
+	case commandTyping:
+		var payload bookPayload
+		if err := decodePayload(msg.Payload, &payload); err != nil {
+			return err
+		}
+
+		if !c.rooms[payload.BookId] {
+			return errNotSubscribed
+		}
+
+		if err := s.publishEvent(ctx, newEvent(typingEvent{BookId: payload.BookId, UserId: c.id, DisplayName: c.displayName})); err != nil {
+			s.logger.Error(err.Error())
+			return errors.New("internal server error")
+		}
+	default:
+		return errUnknownCommand
+	}
+
+	return nil
+}